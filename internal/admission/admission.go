@@ -0,0 +1,83 @@
+package admission
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-request/handle"
+)
+
+//Policy decides what happens to a job added to a full job queue
+type Policy int
+
+const (
+	//Block waits until the job queue has room for the job
+	Block Policy = iota
+	//Reject answers the new job with a busy Response
+	Reject
+	//DropOldest answers the oldest queued job with a busy Response to make room
+	DropOldest
+)
+
+//Policies maps the configuration name of each Policy
+var Policies = map[string]Policy{
+	"Block":      Block,
+	"Reject":     Reject,
+	"DropOldest": DropOldest,
+}
+
+/*Overflow describes how a request handler admits jobs once its queue is
+full. A queue is full when its queued jobs reach Threshold or its capacity,
+whichever is lower. A Threshold of 0 uses the capacity alone. RetryAfter is
+the hint sent to clients whose jobs are turned away*/
+type Overflow struct {
+	Policy     Policy
+	Threshold  int
+	RetryAfter time.Duration
+}
+
+/*Admit adds 'pair' to 'queue' following the overflow policy. Rejected
+jobs are answered and closed in the background so a slow client cannot
+stall the caller. An error is returned when 'pair' itself is rejected*/
+func Admit(queue chan handle.RequestPair, pair handle.RequestPair, overflow Overflow) error {
+	if overflow.Policy == Block {
+		queue <- pair
+		return nil
+	}
+
+	limit := cap(queue)
+	if overflow.Threshold > 0 && overflow.Threshold < limit {
+		limit = overflow.Threshold
+	}
+	for {
+		if len(queue) < limit || limit == 0 {
+			select {
+			case queue <- pair:
+				return nil
+			default:
+			}
+		}
+
+		if overflow.Policy == Reject || len(queue) == 0 {
+			go TurnAway(pair, overflow.RetryAfter)
+			return fmt.Errorf("Rejected job with %d of %d jobs queued in Admit()", len(queue), cap(queue))
+		}
+		select {
+		case oldest := <-queue:
+			go TurnAway(oldest, overflow.RetryAfter)
+		default:
+		}
+	}
+}
+
+/*TurnAway answers the job in 'pair' with a busy Response carrying the
+'retryAfter' hint and closes its connection*/
+func TurnAway(pair handle.RequestPair, retryAfter time.Duration) {
+	err := responder.Busy(pair.Conn, retryAfter)
+	if err != nil {
+		log.Printf("Failed to send busy response in Admit(): %v", err)
+	}
+	pair.Conn.Close()
+}
@@ -0,0 +1,137 @@
+package admission
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-request/handle"
+)
+
+func TestAdmit(t *testing.T) {
+	fmt.Printf("----------------------\nADMIT TEST\n----------------------\n")
+	responder.MarshalResponse = func(r responder.Response) ([]byte, error) {
+		return []byte(fmt.Sprintf("%d:%d", r.Status, r.RetryAfter.Milliseconds())), nil
+	}
+	retryAfter := time.Millisecond * 100
+
+	queue := make(chan handle.RequestPair, 2)
+	conns := newConns(3)
+	overflow := Overflow{Policy: Reject, RetryAfter: retryAfter}
+	for i, conn := range conns {
+		err := Admit(queue, handle.RequestPair{Request: i, Conn: conn}, overflow)
+		fmt.Printf("Reject job %d: %v\n", i, err)
+	}
+	expectTurnedAway(t, conns[2], retryAfter)
+	expectQueued(t, queue, 0, 1)
+
+	conns = newConns(3)
+	overflow = Overflow{Policy: DropOldest, RetryAfter: retryAfter}
+	for i, conn := range conns {
+		err := Admit(queue, handle.RequestPair{Request: i, Conn: conn}, overflow)
+		if err != nil {
+			t.Fatalf("DropOldest should admit every new job: %v", err)
+		}
+	}
+	expectTurnedAway(t, conns[0], retryAfter)
+	expectQueued(t, queue, 1, 2)
+
+	queue = make(chan handle.RequestPair, 4)
+	conns = newConns(3)
+	overflow = Overflow{Policy: Reject, Threshold: 2, RetryAfter: retryAfter}
+	for i, conn := range conns {
+		Admit(queue, handle.RequestPair{Request: i, Conn: conn}, overflow)
+	}
+	expectTurnedAway(t, conns[2], retryAfter)
+	expectQueued(t, queue, 0, 1)
+
+	unbuffered := make(chan handle.RequestPair)
+	conns = newConns(1)
+	err := Admit(unbuffered, handle.RequestPair{Request: 0, Conn: conns[0]}, Overflow{Policy: DropOldest})
+	fmt.Printf("Unbuffered queue without a worker: %v\n", err)
+	if err == nil {
+		t.Fatalf("Expected job to be rejected by an idle unbuffered queue")
+	}
+	expectTurnedAway(t, conns[0], 0)
+}
+
+func TestShutdown(t *testing.T) {
+	fmt.Printf("----------------------\nSHUTDOWN TEST\n----------------------\n")
+	responder.MarshalResponse = func(r responder.Response) ([]byte, error) {
+		return []byte(fmt.Sprintf("%d:%d", r.Status, r.RetryAfter.Milliseconds())), nil
+	}
+	retryAfter := time.Millisecond * 100
+
+	queue := make(chan handle.RequestPair, 3)
+	conns := newConns(3)
+	for i, conn := range conns {
+		queue <- handle.RequestPair{Request: i, Conn: conn}
+	}
+	close(queue)
+	flushed := Flush(queue, retryAfter)
+	fmt.Printf("Flushed %d queued jobs\n", flushed)
+	if flushed != len(conns) {
+		t.Fatalf("Expected %d jobs to be flushed, got %d", len(conns), flushed)
+	}
+	for _, conn := range conns {
+		expectTurnedAway(t, conn, retryAfter)
+	}
+
+	workers := &sync.WaitGroup{}
+	workers.Add(1)
+	err := Wait(workers, time.Millisecond*10)
+	fmt.Printf("Wait on a stuck worker: %v\n", err)
+	if err == nil {
+		t.Fatalf("Expected Wait to time out")
+	}
+	workers.Done()
+	if err = Wait(workers, time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectTurnedAway(t *testing.T, conn *FakeConn, retryAfter time.Duration) {
+	select {
+	case <-conn.closed:
+	case <-time.After(time.Second):
+		t.Fatalf("Turned away connection was never closed")
+	}
+	response := string(conn.Bytes()[4:])
+	fmt.Printf("Turned away with: %s\n", response)
+	if response != fmt.Sprintf("%d:%d", responder.StatusBusy, retryAfter.Milliseconds()) {
+		t.Fatalf("Unexpected busy response %s", response)
+	}
+}
+
+func expectQueued(t *testing.T, queue chan handle.RequestPair, jobs ...int) {
+	for _, job := range jobs {
+		pair := <-queue
+		if pair.Request.(int) != job {
+			t.Fatalf("Expected job %d to be queued, found %d", job, pair.Request.(int))
+		}
+	}
+	if len(queue) != 0 {
+		t.Fatalf("Unexpected jobs left in queue")
+	}
+}
+
+func newConns(n int) []*FakeConn {
+	conns := make([]*FakeConn, n)
+	for i := range conns {
+		conns[i] = &FakeConn{closed: make(chan struct{})}
+	}
+	return conns
+}
+
+type FakeConn struct {
+	bytes.Buffer
+	closed chan struct{}
+}
+
+func (fc *FakeConn) Close() error {
+	close(fc.closed)
+	return nil
+}
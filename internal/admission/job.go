@@ -0,0 +1,27 @@
+package admission
+
+import (
+	"context"
+	"time"
+)
+
+/*Job is a request stamped with the time it was admitted to a job queue
+so that its deadline also covers the time it spent queued*/
+type Job struct {
+	Request  interface{}
+	Admitted time.Time
+}
+
+//NewJob stamps 'request' with the current time
+func NewJob(request interface{}) Job {
+	return Job{Request: request, Admitted: time.Now()}
+}
+
+/*Context derives the context the job is handled under from 'parent'. It
+expires 'timeout' after the job was admitted. A timeout of 0 sets no deadline*/
+func (j Job) Context(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithDeadline(parent, j.Admitted.Add(timeout))
+}
@@ -0,0 +1,41 @@
+package admission
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arstevens/go-request/handle"
+)
+
+/*Flush turns away every job left in the closed 'queue' instead of letting
+it be handled. It returns the number of jobs that were turned away*/
+func Flush(queue <-chan handle.RequestPair, retryAfter time.Duration) int {
+	flushed := 0
+	for pair := range queue {
+		go TurnAway(pair, retryAfter)
+		flushed++
+	}
+	return flushed
+}
+
+/*Wait waits up to 'timeout' for the workers in 'workers' to finish. A
+timeout of 0 waits for as long as it takes*/
+func Wait(workers *sync.WaitGroup, timeout time.Duration) error {
+	finished := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(finished)
+	}()
+
+	if timeout <= 0 {
+		<-finished
+		return nil
+	}
+	select {
+	case <-finished:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("Timed out after %v waiting for jobs to finish in Wait()", timeout)
+	}
+}
@@ -13,19 +13,47 @@ type DataRequestAnalyzer struct {
 	dMutex         *sync.Mutex
 	sizeTracker    SwarmInfoTracker
 	sizeFinder     OptimalSizeFinder
+	layout         SwarmLayout
+	stop           chan struct{}
+	stopped        chan struct{}
 }
 
-func New(sizeTracker SwarmInfoTracker, sizeFinder OptimalSizeFinder) *DataRequestAnalyzer {
+/*MergeBelowSize is the optimal size under which a swarm is considered
+cold and merged with another cold swarm*/
+var MergeBelowSize = 3
+
+/*BisectAboveSize is the optimal size over which a swarm serving more
+than one dataspace is bisected*/
+var BisectAboveSize = 12
+
+func New(sizeTracker SwarmInfoTracker, sizeFinder OptimalSizeFinder, layout SwarmLayout) *DataRequestAnalyzer {
 	analyzer := &DataRequestAnalyzer{
 		matchDistances: swarmDistancesSlice(make([]*swarmDistanceInfo, 0)),
 		dMutex:         &sync.Mutex{},
 		sizeTracker:    sizeTracker,
 		sizeFinder:     sizeFinder,
+		layout:         layout,
+		stop:           make(chan struct{}),
+		stopped:        make(chan struct{}),
 	}
-	go pollForNewDistances(sizeTracker, sizeFinder, &analyzer.matchDistances, analyzer.dMutex)
+	go pollForNewDistances(sizeTracker, sizeFinder, layout, &analyzer.matchDistances, analyzer.dMutex,
+		analyzer.stop, analyzer.stopped)
 	return analyzer
 }
 
+/*Close stops the periodic distance calculation and returns
+once it has stopped*/
+func (da *DataRequestAnalyzer) Close() error {
+	select {
+	case <-da.stop:
+		return fmt.Errorf("Failed to close in DataRequestAnalyzer.Close(): Already closed")
+	default:
+	}
+	close(da.stop)
+	<-da.stopped
+	return nil
+}
+
 func (da *DataRequestAnalyzer) GetMostNeedy() (string, error) {
 	da.dMutex.Lock()
 	defer da.dMutex.Unlock()
@@ -64,6 +92,40 @@ func (da *DataRequestAnalyzer) CalculateCandidates() ([]transmuter.Candidate, er
 	return candidates, nil
 }
 
+/*CalculateRestructures pairs up cold swarms to be merged, keeping the
+larger swarm of each pair, and bisects hot swarms that serve more than
+one dataspace*/
+func (da *DataRequestAnalyzer) CalculateRestructures() ([]transmuter.Restructure, error) {
+	da.dMutex.Lock()
+	defer da.dMutex.Unlock()
+
+	restructures := make([]transmuter.Restructure, 0)
+	cold := make([]*swarmDistanceInfo, 0)
+	for _, info := range da.matchDistances {
+		if info.optimal < MergeBelowSize {
+			cold = append(cold, info)
+		} else if info.optimal > BisectAboveSize && len(da.layout.GetDataspacesOf(info.dataspace)) > 1 {
+			restructures = append(restructures, &Restructure{
+				kind:     transmuter.BisectKey,
+				swarmIDs: []string{info.dataspace},
+			})
+		}
+	}
+
+	sort.Slice(cold, func(i, j int) bool { return cold[i].optimal < cold[j].optimal })
+	for i := 0; i+1 < len(cold); i += 2 {
+		kept, merged := cold[i], cold[i+1]
+		if merged.size > kept.size {
+			kept, merged = merged, kept
+		}
+		restructures = append(restructures, &Restructure{
+			kind:     transmuter.MergeKey,
+			swarmIDs: []string{kept.dataspace, merged.dataspace},
+		})
+	}
+	return restructures, nil
+}
+
 func calculateTransferSize(neg *swarmDistanceInfo, pos *swarmDistanceInfo) int {
 	negAbsDistance := -1 * neg.distance
 	if negAbsDistance <= pos.distance {
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"testing"
 	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/transmuter"
 )
 
 func TestAnalyzer(t *testing.T) {
@@ -31,7 +33,8 @@ func TestAnalyzer(t *testing.T) {
 	DistancePollTime = time.Millisecond
 	tracker := &TestSwarmInfoTracker{sizes: sizes, loads: loads}
 	finder := &TestOptimalSizeFinder{sizes: dupSizes}
-	analyzer := New(tracker, finder)
+	layout := &TestSwarmLayout{}
+	analyzer := New(tracker, finder, layout)
 
 	time.Sleep(DistancePollTime * 2)
 	needyID, err := analyzer.GetMostNeedy()
@@ -50,12 +53,88 @@ func TestAnalyzer(t *testing.T) {
 			candidate.GetTransfererID(), candidate.GetTransfereeID(), candidate.GetTransferSize())
 	}
 
+	MergeBelowSize, BisectAboveSize = sizeLimit/2, sizeLimit/2
+	restructures, err := analyzer.CalculateRestructures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("\nRestructures\n------------\n")
+	for _, restructure := range restructures {
+		fmt.Printf("\tKind: %d Swarms: %v\n", restructure.GetKind(), restructure.GetSwarmIDs())
+		if restructure.GetKind() == transmuter.MergeKey && dupSizes[restructure.GetSwarmIDs()[1]] >= MergeBelowSize {
+			t.Fatalf("Only cold swarms should be merged")
+		}
+	}
+
 	fmt.Printf("\nFinal Distances\n---------------\n")
 	for _, distance := range analyzer.matchDistances {
 		fmt.Printf("\tDataspace: %s Distance: %d\n", distance.dataspace, distance.distance)
 	}
 }
 
+func TestReplicationFactor(t *testing.T) {
+	DistancePollTime = time.Millisecond
+	ReplicationFactor = 2
+	ReplicationFactors = map[string]int{"/dataspace/pinned/merged": 4}
+	defer func() {
+		ReplicationFactor = 1
+		ReplicationFactors = make(map[string]int)
+	}()
+
+	sizes := map[string]int{"/dataspace/popular": 10, "/dataspace/cold": 3, "/dataspace/pinned": 0}
+	optimal := map[string]int{"/dataspace/popular": 10, "/dataspace/cold": 0, "/dataspace/pinned": 0}
+	tracker := &TestSwarmInfoTracker{sizes: sizes}
+	finder := &TestOptimalSizeFinder{sizes: optimal}
+	analyzer := New(tracker, finder, &TestSwarmLayout{})
+	defer analyzer.Close()
+
+	time.Sleep(DistancePollTime * 5)
+	needyID, err := analyzer.GetMostNeedy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needyID != "/dataspace/pinned" {
+		t.Fatalf("Expected the swarm below its replication factor to be most needy, got %s", needyID)
+	}
+
+	candidates, err := analyzer.CalculateCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, candidate := range candidates {
+		fmt.Printf("Transferer: %s Transferee: %s Size: %d\n",
+			candidate.GetTransfererID(), candidate.GetTransfereeID(), candidate.GetTransferSize())
+		if candidate.GetTransfererID() == "/dataspace/cold" && candidate.GetTransferSize() > 1 {
+			t.Fatalf("Expected /dataspace/cold to keep %d endpoints", ReplicationFactor)
+		}
+	}
+}
+
+func TestWaitingDemand(t *testing.T) {
+	DistancePollTime = time.Millisecond
+	sizes := map[string]int{"/dataspace/empty": 0, "/dataspace/short": 1}
+	optimal := map[string]int{"/dataspace/empty": 0, "/dataspace/short": 3}
+	tracker := &TestSwarmInfoTracker{sizes: sizes, waiting: map[string]int{"/dataspace/empty": 5}}
+	analyzer := New(tracker, &TestOptimalSizeFinder{sizes: optimal}, &TestSwarmLayout{})
+	defer analyzer.Close()
+
+	time.Sleep(DistancePollTime * 5)
+	needyID, err := analyzer.GetMostNeedy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Most needy with waiting requesters: %s\n", needyID)
+	if needyID != "/dataspace/empty" {
+		t.Fatalf("Expected the swarm with waiting requesters to be most needy, got %s", needyID)
+	}
+}
+
+type TestSwarmLayout struct{}
+
+func (tl *TestSwarmLayout) GetDataspacesOf(id string) []string {
+	return []string{id, id + "/merged"}
+}
+
 type TestOptimalSizeFinder struct {
 	sizes map[string]int
 }
@@ -68,8 +147,13 @@ func (sf *TestOptimalSizeFinder) GetBestSize(id string) int {
 }
 
 type TestSwarmInfoTracker struct {
-	sizes map[string]int
-	loads map[string]int
+	sizes   map[string]int
+	loads   map[string]int
+	waiting map[string]int
+}
+
+func (tt *TestSwarmInfoTracker) GetWaiting(id string) int {
+	return tt.waiting[id]
 }
 
 func (tt *TestSwarmInfoTracker) GetSize(id string) int {
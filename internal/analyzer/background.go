@@ -8,10 +8,23 @@ import (
 
 var DistancePollTime = time.Minute
 
-func pollForNewDistances(tracker SwarmInfoTracker, optimalFinder OptimalSizeFinder,
-	distances *swarmDistancesSlice, mutex *sync.Mutex) {
+/*ReplicationFactor is the minimum size of a swarm serving dataspaces
+that have no replication factor of their own*/
+var ReplicationFactor = 1
+
+/*ReplicationFactors maps dataspaces to the minimum size of the swarm
+serving them. It overrides ReplicationFactor*/
+var ReplicationFactors = make(map[string]int)
+
+func pollForNewDistances(tracker SwarmInfoTracker, optimalFinder OptimalSizeFinder, layout SwarmLayout,
+	distances *swarmDistancesSlice, mutex *sync.Mutex, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
 	for {
-		time.Sleep(DistancePollTime)
+		select {
+		case <-time.After(DistancePollTime):
+		case <-stop:
+			return
+		}
 
 		dataspaces := tracker.GetDataspaces()
 		newDistances := make([]*swarmDistanceInfo, 0, len(dataspaces))
@@ -19,10 +32,19 @@ func pollForNewDistances(tracker SwarmInfoTracker, optimalFinder OptimalSizeFind
 			size := tracker.GetSize(dataspace)
 			optimalSize := optimalFinder.GetBestSize(dataspace)
 
+			/*Distances are taken from the minimum size if it is larger so
+			unpopular swarms are neither drained nor left empty*/
 			distance := size - optimalSize
+			if minimum := minimumSize(layout, dataspace); minimum > optimalSize {
+				distance = size - minimum
+			}
+			//Every waiting requester needs an endpoint on top of that
+			distance -= tracker.GetWaiting(dataspace)
 			newDistances = append(newDistances, &swarmDistanceInfo{
 				dataspace: dataspace,
 				distance:  distance,
+				size:      size,
+				optimal:   optimalSize,
 			})
 		}
 
@@ -34,3 +56,19 @@ func pollForNewDistances(tracker SwarmInfoTracker, optimalFinder OptimalSizeFind
 		mutex.Unlock()
 	}
 }
+
+/*minimumSize returns the largest replication factor of the
+dataspaces served by the swarm with id 'swarmID'*/
+func minimumSize(layout SwarmLayout, swarmID string) int {
+	minimum := 0
+	for _, dataspace := range layout.GetDataspacesOf(swarmID) {
+		factor, ok := ReplicationFactors[dataspace]
+		if !ok {
+			factor = ReplicationFactor
+		}
+		if factor > minimum {
+			minimum = factor
+		}
+	}
+	return minimum
+}
@@ -10,9 +10,21 @@ func (c *Candidate) GetTransfererID() string { return c.transfererID }
 func (c *Candidate) GetTransfereeID() string { return c.transfereeID }
 func (c *Candidate) GetTransferSize() int    { return c.transferSize }
 
+/*Restructure is a merge or bisect of swarms as
+described by transmuter.Restructure*/
+type Restructure struct {
+	kind     int
+	swarmIDs []string
+}
+
+func (r *Restructure) GetKind() int          { return r.kind }
+func (r *Restructure) GetSwarmIDs() []string { return r.swarmIDs }
+
 type swarmDistanceInfo struct {
 	dataspace string
 	distance  int
+	size      int
+	optimal   int
 }
 
 type swarmDistancesSlice []*swarmDistanceInfo
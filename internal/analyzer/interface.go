@@ -6,6 +6,8 @@ all known swarms as well as the load parameters
 of each swarm*/
 type SwarmInfoTracker interface {
 	GetSize(string) int
+	//Number of requesters waiting for an endpoint of the swarm or that gave up since one last joined
+	GetWaiting(string) int
 	GetDataspaces() []string
 }
 
@@ -14,3 +16,9 @@ finding the optimal size of a swarm*/
 type OptimalSizeFinder interface {
 	GetBestSize(string) int
 }
+
+/*SwarmLayout describes an object that knows
+which dataspaces each swarm serves*/
+type SwarmLayout interface {
+	GetDataspacesOf(string) []string
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"net"
 	"sync"
 	"time"
@@ -23,6 +24,8 @@ type ConnectionCache struct {
 	mutex           *sync.Mutex
 	connectCache    map[string]time.Time
 	disconnectCache map[string]time.Time
+	stop            chan struct{}
+	stopped         chan struct{}
 }
 
 //New creates a new ConnectionCache
@@ -31,14 +34,29 @@ func New() *ConnectionCache {
 		mutex:           &sync.Mutex{},
 		connectCache:    make(map[string]time.Time),
 		disconnectCache: make(map[string]time.Time),
+		stop:            make(chan struct{}),
+		stopped:         make(chan struct{}),
 	}
 	go pollForTimedOutRecords(cache.mutex, []map[string]time.Time{
 		cache.connectCache,
 		cache.disconnectCache,
-	})
+	}, cache.stop, cache.stopped)
 	return &cache
 }
 
+/*Close stops the garbage collection of old records and returns
+once it has stopped*/
+func (cc *ConnectionCache) Close() error {
+	select {
+	case <-cc.stop:
+		return fmt.Errorf("Failed to close in ConnectionCache.Close(): Already closed")
+	default:
+	}
+	close(cc.stop)
+	<-cc.stopped
+	return nil
+}
+
 //IsRecentlyDisconnected returns whether or not 'ip' recently disconnected
 func (cc *ConnectionCache) IsRecentlyDisconnected(ip net.IP) bool {
 	cc.mutex.Lock()
@@ -5,9 +5,15 @@ import (
 	"time"
 )
 
-func pollForTimedOutRecords(mutex *sync.Mutex, caches []map[string]time.Time) {
+func pollForTimedOutRecords(mutex *sync.Mutex, caches []map[string]time.Time,
+	stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
 	for {
-		time.Sleep(GarbageCollectionPeriod)
+		select {
+		case <-time.After(GarbageCollectionPeriod):
+		case <-stop:
+			return
+		}
 
 		mutex.Lock()
 		for _, cache := range caches {
@@ -0,0 +1,228 @@
+package configuration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/wrapper"
+	"github.com/arstevens/go-request/handle"
+)
+
+func TestTLSListener(t *testing.T) {
+	fmt.Printf("----------------------\nTLS LISTENER TEST\n----------------------\n")
+	dir := t.TempDir()
+	server, err := newAuthority("server", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCA, err := newAuthority("client CA", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCA, err := newAuthority("other CA", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newAuthority("client", clientCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rogue, err := newAuthority("rogue", otherCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { tlsCertificateFile, tlsKeyFile, tlsClientCAFile = "", "", "" }()
+	tlsCertificateFile = filepath.Join(dir, "server.pem")
+	tlsKeyFile = filepath.Join(dir, "server.key")
+	if err = server.write(tlsCertificateFile, tlsKeyFile); err != nil {
+		t.Fatal(err)
+	}
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(server.certificate)
+
+	fmt.Printf("\thandshaking without client certificates...")
+	config, err := loadTLSConfig()
+	if err != nil || config.ClientAuth != tls.NoClientCert {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected server config %v", err)
+	}
+	plain := serveTLS(t, config)
+	err = plain.dial(&tls.Config{RootCAs: serverCAs})
+	plain.listener.Close()
+	if err != nil {
+		fmt.Printf("failed\n")
+		t.Fatal(err)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\thandshaking with a client certificate of the client CA...")
+	tlsClientCAFile = filepath.Join(dir, "clientCA.pem")
+	if err = clientCA.write(tlsClientCAFile, filepath.Join(dir, "clientCA.key")); err != nil {
+		t.Fatal(err)
+	}
+	config, err = loadTLSConfig()
+	if err != nil || config.ClientAuth != tls.RequireAndVerifyClientCert {
+		fmt.Printf("failed\n")
+		t.Fatalf("Expected client certificates to be required %v", err)
+	}
+	mutual := serveTLS(t, config)
+	defer mutual.listener.Close()
+	err = mutual.dial(&tls.Config{RootCAs: serverCAs, Certificates: []tls.Certificate{client.keyPair()}})
+	if err != nil {
+		fmt.Printf("failed\n")
+		t.Fatal(err)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\trejecting a client certificate of another CA...")
+	//The certificate is sent even though it does not match the CAs the server asks for
+	err = mutual.dial(&tls.Config{RootCAs: serverCAs,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			keyPair := rogue.keyPair()
+			return &keyPair, nil
+		}})
+	if err == nil {
+		fmt.Printf("failed\n")
+		t.Fatalf("Expected a client certificate of another CA to be rejected")
+	}
+	err = mutual.dial(&tls.Config{RootCAs: serverCAs})
+	if err == nil {
+		fmt.Printf("failed\n")
+		t.Fatalf("Expected a client without a certificate to be rejected")
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tloading a client CA without certificates...")
+	tlsClientCAFile = tlsKeyFile
+	if _, err = loadTLSConfig(); err == nil {
+		fmt.Printf("failed\n")
+		t.Fatalf("Expected a client CA without certificates to be refused")
+	}
+	fmt.Printf("success\n")
+}
+
+//tlsServer accepts conns through a NetListener secured with TLS
+type tlsServer struct {
+	listener *wrapper.NetListener
+	address  string
+	accepted chan handle.Conn
+}
+
+func serveTLS(t *testing.T, config *tls.Config) *tlsServer {
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &tlsServer{
+		listener: wrapper.NewNetListener(tls.NewListener(netListener, config), "protobuf"),
+		address:  netListener.Addr().String(),
+		accepted: make(chan handle.Conn),
+	}
+	go func() {
+		for {
+			conn, err := server.listener.Accept()
+			if err != nil {
+				return
+			}
+			server.accepted <- conn
+		}
+	}()
+	return server
+}
+
+/*dial connects to 'ts' with 'config' and returns an error unless the conn
+was accepted and can carry data*/
+func (ts *tlsServer) dial(config *tls.Config) error {
+	conn, err := tls.Dial("tcp", ts.address, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	//Client certificates are verified after the client side of a TLS 1.3 handshake
+	if _, err = conn.Write([]byte{1}); err != nil {
+		return err
+	}
+	select {
+	case accepted := <-ts.accepted:
+		defer accepted.Close()
+		_, err = accepted.Read(make([]byte, 1))
+		return err
+	case <-time.After(time.Millisecond * 200):
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		return fmt.Errorf("Conn was never accepted: %v", err)
+	}
+}
+
+//authority is a certificate along with its key
+type authority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+/*newAuthority creates a certificate for 127.0.0.1 signed by 'parent' or
+self-signed if 'parent' is nil. Every certificate may sign others*/
+func newAuthority(name string, parent *authority) (*authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	signer := &authority{certificate: template, key: key}
+	if parent != nil {
+		signer = parent
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer.certificate, &key.PublicKey, signer.key)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &authority{certificate: certificate, key: key}, nil
+}
+
+func (a *authority) keyPair() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{a.certificate.Raw}, PrivateKey: a.key}
+}
+
+//write stores the certificate and key of 'a' as PEM files
+func (a *authority) write(certificateFile string, keyFile string) error {
+	rawKey, err := x509.MarshalECPrivateKey(a.key)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(certificateFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.certificate.Raw}), 0600)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0600)
+}
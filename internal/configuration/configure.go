@@ -2,18 +2,27 @@ package configuration
 
 import (
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/admission"
 	"github.com/arstevens/go-hive-signal/internal/analyzer"
 	"github.com/arstevens/go-hive-signal/internal/cache"
 	"github.com/arstevens/go-hive-signal/internal/comparator"
+	"github.com/arstevens/go-hive-signal/internal/connector"
 	"github.com/arstevens/go-hive-signal/internal/debriefer"
+	"github.com/arstevens/go-hive-signal/internal/gateway"
+	"github.com/arstevens/go-hive-signal/internal/localizer"
 	"github.com/arstevens/go-hive-signal/internal/manager"
 	"github.com/arstevens/go-hive-signal/internal/negotiator"
+	"github.com/arstevens/go-hive-signal/internal/protocol"
 	"github.com/arstevens/go-hive-signal/internal/register"
+	"github.com/arstevens/go-hive-signal/internal/registrator"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	"github.com/arstevens/go-hive-signal/internal/tracker"
 	"github.com/arstevens/go-hive-signal/internal/transmuter"
-	"github.com/arstevens/go-hive-signal/pkg/protomsg"
+	"github.com/arstevens/go-hive-signal/internal/vivaldi"
 )
 
 const (
@@ -22,12 +31,31 @@ const (
 )
 
 var requestQueueSizeKey = "RequestBufferSize"
+var workersKey = "Workers"
+var requestTimeoutKey = "RequestTimeout"
+var messageFormatKey = "MessageEncodingFormat"
 var (
 	connectorQueueSize   = 30
 	registratorQueueSize = 30
 	localizerQueueSize   = 30
 
-	gatewayActiveQueueSize = 0
+	connectorWorkers   = 1
+	registratorWorkers = 1
+	localizerWorkers   = 1
+
+	connectorOverflow   = admission.Overflow{Policy: admission.Block, RetryAfter: time.Second}
+	registratorOverflow = admission.Overflow{Policy: admission.Block, RetryAfter: time.Second}
+	localizerOverflow   = admission.Overflow{Policy: admission.Block, RetryAfter: time.Second}
+
+	gatewayActiveQueueSize                         = 0
+	gatewaySelectionPolicy gateway.SelectionPolicy = gateway.RoundRobin{}
+
+	negotiationStrategy manager.AgentNegotiator = negotiator.RoundtripLimitedNegotiate
+
+	drainQueuesOnShutdown = true
+
+	coordinatesEnabled  = true
+	coordinatesCapacity = 100000
 
 	trackerLoadHistorySize   = 0
 	debrieferLoadHistorySize = 0
@@ -37,13 +65,13 @@ var (
 	localizerRoutingCode   int32 = 0
 	registratorRoutingCode int32 = 1
 	connectorRoutingCode   int32 = 2
+	sessionRoutingCode     int32 = 3
 )
 
 var (
-	localizerUnpacker   = protomsg.UnpackLocalizeRequest
-	registratorUnpacker = protomsg.UnpackRegistrationRequest
-	connectorUnpacker   = protomsg.UnpackConnectionRequest
-	routeUnpacker       = protomsg.UnpackRouteWrapper
+	messageEncoding           = ProtobufEncoding
+	tcpListenerEncoding       = ""
+	websocketListenerEncoding = ""
 )
 
 var (
@@ -54,6 +82,7 @@ var (
 	CacheKey       = "Cache"
 	ComparatorKey  = "Comparator"
 	ConnectorKey   = "Connector"
+	CoordinatesKey = "Coordinates"
 	DebrieferKey   = "Debriefer"
 	GatewayKey     = "Gateway"
 	LocalizerKey   = "Localizer"
@@ -67,6 +96,14 @@ var (
 
 var (
 	listenerPort = 10000
+
+	tlsCertificateFile = ""
+	tlsKeyFile         = ""
+	tlsClientCAFile    = ""
+	tlsMinimumVersion  = "1.2"
+
+	websocketPort = 0
+	websocketPath = ""
 )
 
 var UnitOfTime = time.Millisecond
@@ -82,6 +119,7 @@ func GenerateConfiguratorMap() map[string]ComponentConfigurator {
 	confMap[CacheKey] = ConfigureCache
 	confMap[ComparatorKey] = ConfigureComparator
 	confMap[ConnectorKey] = ConfigureConnector
+	confMap[CoordinatesKey] = ConfigureCoordinates
 	confMap[DebrieferKey] = ConfigureDebriefer
 	confMap[GatewayKey] = ConfigureGateway
 	confMap[LocalizerKey] = ConfigureLocalizer
@@ -99,6 +137,10 @@ func ConfigureRouter(config map[string]interface{}) {
 	LocalizerRoutingKey := "LocalizerRoutingCode"
 	RegistratorRoutingKey := "RegistratorRoutingCode"
 	ConnectorRoutingKey := "ConnectorRoutingCode"
+	SessionRoutingKey := "SessionRoutingCode"
+	MinimumVersionKey := "MinimumProtocolVersion"
+	ShutdownDeadlineKey := "ShutdownDeadline"
+	DrainQueuesKey := "DrainQueuesOnShutdown"
 
 	if lr, ok := config[LocalizerRoutingKey]; ok {
 		localizerRoutingCode = int32(lr.(float64))
@@ -109,26 +151,104 @@ func ConfigureRouter(config map[string]interface{}) {
 	if cr, ok := config[ConnectorRoutingKey]; ok {
 		connectorRoutingCode = int32(cr.(float64))
 	}
+	if sr, ok := config[SessionRoutingKey]; ok {
+		sessionRoutingCode = int32(sr.(float64))
+	}
+	if mv, ok := config[MinimumVersionKey]; ok {
+		minimumVersion := int32(mv.(float64))
+		if minimumVersion < protocol.LegacyVersion || minimumVersion > protocol.CurrentVersion {
+			log.Fatalf(InvalidOptionError, strconv.Itoa(int(minimumVersion)), MinimumVersionKey)
+		}
+		protocol.MinimumVersion = minimumVersion
+	}
+	if sd, ok := config[ShutdownDeadlineKey]; ok {
+		TimeForTeardown = time.Duration(int64(sd.(float64)) * int64(UnitOfTime))
+	}
+	if dq, ok := config[DrainQueuesKey]; ok {
+		drainQueuesOnShutdown = dq.(bool)
+	}
 }
 
 func ConfigureListener(config map[string]interface{}) {
 	ListenerPortKey := "PortNumber"
+	TLSKey := "TLS"
+	WebSocketKey := "WebSocket"
 	if lp, ok := config[ListenerPortKey]; ok {
 		listenerPort = int(lp.(float64))
 	}
+	if mf, ok := config[messageFormatKey]; ok {
+		tcpListenerEncoding = parseEncoding(mf.(string), messageFormatKey)
+	}
+	if tc, ok := config[TLSKey]; ok {
+		configureTLS(tc.(map[string]interface{}))
+	}
+	if wc, ok := config[WebSocketKey]; ok {
+		configureWebSocket(wc.(map[string]interface{}))
+	}
 }
 
-func ConfigureMessaging(config map[string]interface{}) {
-	negotiator.UnmarshalMessage = protomsg.UnmarshalNegotiateMessage
-	MessageFormatKey := "MessageEncodingFormat"
+func configureWebSocket(config map[string]interface{}) {
+	PortKey := "PortNumber"
+	PathKey := "Path"
 
-	if mf, ok := config[MessageFormatKey]; ok {
-		ProtobufOption := "protobuf"
-		if mf.(string) == ProtobufOption {
-		} else {
-			log.Fatalf(InvalidOptionError, mf.(string), MessageFormatKey)
+	if wp, ok := config[PortKey]; ok {
+		websocketPort = int(wp.(float64))
+	} else {
+		log.Fatalf(MissingSettingError, PortKey)
+	}
+	if wp, ok := config[PathKey]; ok {
+		websocketPath = wp.(string)
+	}
+	if mf, ok := config[messageFormatKey]; ok {
+		websocketListenerEncoding = parseEncoding(mf.(string), messageFormatKey)
+	}
+}
+
+func configureTLS(config map[string]interface{}) {
+	CertificateFileKey := "CertificateFile"
+	KeyFileKey := "KeyFile"
+	ClientCAFileKey := "ClientCAFile"
+	MinimumVersionKey := "MinimumVersion"
+
+	if cf, ok := config[CertificateFileKey]; ok {
+		tlsCertificateFile = cf.(string)
+	} else {
+		log.Fatalf(MissingSettingError, CertificateFileKey)
+	}
+	if kf, ok := config[KeyFileKey]; ok {
+		tlsKeyFile = kf.(string)
+	} else {
+		log.Fatalf(MissingSettingError, KeyFileKey)
+	}
+	if ca, ok := config[ClientCAFileKey]; ok {
+		tlsClientCAFile = ca.(string)
+	}
+	if mv, ok := config[MinimumVersionKey]; ok {
+		minimumVersion := mv.(string)
+		if _, ok := tlsVersions[minimumVersion]; !ok {
+			log.Fatalf(InvalidOptionError, minimumVersion, MinimumVersionKey)
 		}
+		tlsMinimumVersion = minimumVersion
+	}
+}
+
+func ConfigureMessaging(config map[string]interface{}) {
+	if mf, ok := config[messageFormatKey]; ok {
+		messageEncoding = parseEncoding(mf.(string), messageFormatKey)
 	}
+
+	for encoding, codec := range messageCodecs {
+		negotiator.Unmarshalers[encoding] = codec.unmarshalNegotiate
+		negotiator.Marshalers[encoding] = codec.marshalNegotiate
+		negotiator.SignalMarshalers[encoding] = codec.marshalSignal
+		responder.Marshalers[encoding] = codec.marshalResponse
+		responder.NoticeMarshalers[encoding] = codec.marshalNotice
+		responder.NoticeUnmarshalers[encoding] = codec.unmarshalNotice
+	}
+	negotiator.UnmarshalMessage = messageCodecs[messageEncoding].unmarshalNegotiate
+	responder.MarshalResponse = messageCodecs[messageEncoding].marshalResponse
+	responder.MarshalNotice = messageCodecs[messageEncoding].marshalNotice
+	responder.UnmarshalNotice = messageCodecs[messageEncoding].unmarshalNotice
 }
 
 func ConfigureDebriefer(config map[string]interface{}) {
@@ -139,21 +259,81 @@ func ConfigureDebriefer(config map[string]interface{}) {
 }
 
 func ConfigureConnector(config map[string]interface{}) {
+	RequiredCapabilitiesKey := "RequiredCapabilities"
+
 	if rqs, ok := config[requestQueueSizeKey]; ok {
 		connectorQueueSize = int(rqs.(float64))
 	}
+	if w, ok := config[workersKey]; ok {
+		connectorWorkers = int(w.(float64))
+	}
+	configureOverflow(config, &connectorOverflow)
+	if rt, ok := config[requestTimeoutKey]; ok {
+		connector.RequestTimeout = time.Duration(int64(rt.(float64)) * int64(UnitOfTime))
+	}
+	if rc, ok := config[RequiredCapabilitiesKey]; ok {
+		names := make([]string, 0)
+		for _, name := range rc.([]interface{}) {
+			names = append(names, name.(string))
+		}
+		capabilities, err := protocol.ParseCapabilities(names)
+		if err != nil {
+			log.Fatalf(InvalidOptionError, strings.Join(names, ","), RequiredCapabilitiesKey)
+		}
+		connector.RequiredCapabilities = capabilities
+	}
 }
 
 func ConfigureRegistrator(config map[string]interface{}) {
 	if rqs, ok := config[requestQueueSizeKey]; ok {
 		registratorQueueSize = int(rqs.(float64))
 	}
+	if w, ok := config[workersKey]; ok {
+		registratorWorkers = int(w.(float64))
+	}
+	configureOverflow(config, &registratorOverflow)
+	if rt, ok := config[requestTimeoutKey]; ok {
+		registrator.RequestTimeout = time.Duration(int64(rt.(float64)) * int64(UnitOfTime))
+	}
 }
 
 func ConfigureLocalizer(config map[string]interface{}) {
 	if rqs, ok := config[requestQueueSizeKey]; ok {
 		localizerQueueSize = int(rqs.(float64))
 	}
+	if w, ok := config[workersKey]; ok {
+		localizerWorkers = int(w.(float64))
+	}
+	configureOverflow(config, &localizerOverflow)
+	if rt, ok := config[requestTimeoutKey]; ok {
+		localizer.RequestTimeout = time.Duration(int64(rt.(float64)) * int64(UnitOfTime))
+	}
+
+	MaxPeersKey := "MaxPeersPerRequest"
+	if mp, ok := config[MaxPeersKey]; ok {
+		localizer.MaxPeers = int(mp.(float64))
+	}
+}
+
+//configureOverflow reads the job queue overflow settings shared by every request handler
+func configureOverflow(config map[string]interface{}, overflow *admission.Overflow) {
+	OverflowPolicyKey := "OverflowPolicy"
+	OverflowThresholdKey := "OverflowThreshold"
+	RetryAfterKey := "RetryAfter"
+
+	if op, ok := config[OverflowPolicyKey]; ok {
+		policy, ok := admission.Policies[op.(string)]
+		if !ok {
+			log.Fatalf(InvalidOptionError, op.(string), OverflowPolicyKey)
+		}
+		overflow.Policy = policy
+	}
+	if ot, ok := config[OverflowThresholdKey]; ok {
+		overflow.Threshold = int(ot.(float64))
+	}
+	if ra, ok := config[RetryAfterKey]; ok {
+		overflow.RetryAfter = time.Duration(int64(ra.(float64)) * int64(UnitOfTime))
+	}
 }
 
 func ConfigureAnalyzer(config map[string]interface{}) {
@@ -162,6 +342,30 @@ func ConfigureAnalyzer(config map[string]interface{}) {
 		distancePollTime := time.Duration(int64(dpt.(float64)) * int64(UnitOfTime))
 		analyzer.DistancePollTime = distancePollTime
 	}
+
+	MergeBelowSizeKey := "MergeSwarmsBelowSize"
+	if mbs, ok := config[MergeBelowSizeKey]; ok {
+		analyzer.MergeBelowSize = int(mbs.(float64))
+	}
+
+	BisectAboveSizeKey := "BisectSwarmsAboveSize"
+	if bas, ok := config[BisectAboveSizeKey]; ok {
+		analyzer.BisectAboveSize = int(bas.(float64))
+	}
+
+	ReplicationFactorKey := "ReplicationFactor"
+	if rf, ok := config[ReplicationFactorKey]; ok {
+		analyzer.ReplicationFactor = int(rf.(float64))
+	}
+
+	ReplicationFactorsKey := "DataspaceReplicationFactors"
+	if rfs, ok := config[ReplicationFactorsKey]; ok {
+		factors := make(map[string]int)
+		for dataspace, rf := range rfs.(map[string]interface{}) {
+			factors[dataspace] = int(rf.(float64))
+		}
+		analyzer.ReplicationFactors = factors
+	}
 }
 
 func ConfigureCache(config map[string]interface{}) {
@@ -194,15 +398,78 @@ func ConfigureComparator(config map[string]interface{}) {
 
 func ConfigureGateway(config map[string]interface{}) {
 	DefaultQueueCapacityKey := "MaxActiveConnectionsOnStandby"
+	SelectionPolicyKey := "SelectionPolicy"
+	ActivityHalfLifeKey := "ActivityHalfLife"
+	HeartbeatIntervalKey := "HeartbeatInterval"
+	HeartbeatTimeoutKey := "HeartbeatTimeout"
+	MaxMissedHeartbeatsKey := "MaxMissedHeartbeats"
+	WaitingRoomSizeKey := "WaitingRoomSize"
+	WaitingTimeoutKey := "WaitingRoomTimeout"
 
 	if dq, ok := config[DefaultQueueCapacityKey]; ok {
 		gatewayActiveQueueSize = int(dq.(float64))
 	}
+	if sp, ok := config[SelectionPolicyKey]; ok {
+		policy, ok := gateway.Policies[sp.(string)]
+		if !ok {
+			log.Fatalf(InvalidOptionError, sp.(string), SelectionPolicyKey)
+		}
+		gatewaySelectionPolicy = policy
+	}
+	if hl, ok := config[ActivityHalfLifeKey]; ok {
+		gateway.ActivityHalfLife = time.Duration(int64(hl.(float64)) * int64(UnitOfTime))
+	}
+	if hi, ok := config[HeartbeatIntervalKey]; ok {
+		gateway.HeartbeatInterval = time.Duration(int64(hi.(float64)) * int64(UnitOfTime))
+	}
+	if ht, ok := config[HeartbeatTimeoutKey]; ok {
+		gateway.HeartbeatTimeout = time.Duration(int64(ht.(float64)) * int64(UnitOfTime))
+	}
+	if mm, ok := config[MaxMissedHeartbeatsKey]; ok {
+		gateway.MaxMissedHeartbeats = int(mm.(float64))
+	}
+	if wrs, ok := config[WaitingRoomSizeKey]; ok {
+		gateway.WaitingRoomSize = int(wrs.(float64))
+	}
+	if wt, ok := config[WaitingTimeoutKey]; ok {
+		gateway.WaitingTimeout = time.Duration(int64(wt.(float64)) * int64(UnitOfTime))
+	}
+}
+
+func ConfigureCoordinates(config map[string]interface{}) {
+	EnabledKey := "Enabled"
+	DimensionsKey := "Dimensions"
+	MaxNodesKey := "MaxNodes"
+	LatencyToleranceKey := "LatencyTolerance"
+	MaxRecentPeersKey := "MaxRecentPeers"
+	MaxSelectionCandidatesKey := "MaxSelectionCandidates"
+
+	if e, ok := config[EnabledKey]; ok {
+		coordinatesEnabled = e.(bool)
+	}
+	if d, ok := config[DimensionsKey]; ok {
+		vivaldi.Dimensions = int(d.(float64))
+	}
+	if mn, ok := config[MaxNodesKey]; ok {
+		coordinatesCapacity = int(mn.(float64))
+	}
+	if lt, ok := config[LatencyToleranceKey]; ok {
+		gateway.LatencyTolerance = time.Duration(int64(lt.(float64)) * int64(UnitOfTime))
+	}
+	if mrp, ok := config[MaxRecentPeersKey]; ok {
+		gateway.MaxRecentPeers = int(mrp.(float64))
+	}
+	if msc, ok := config[MaxSelectionCandidatesKey]; ok {
+		gateway.MaxSelectionCandidates = int(msc.(float64))
+	}
 }
 
 func ConfigureManager(config map[string]interface{}) {
 	ChangeTriggerLimitKey := "ChangesUntilSizeUpdate"
 	DebriefProcedureKey := "DebriefProcedure"
+	MigrationTimeoutKey := "MigrationTimeout"
+	PairingRetriesKey := "PairingRetries"
+	PairingRetryBudgetKey := "PairingRetryBudget"
 
 	if dp, ok := config[DebriefProcedureKey]; ok {
 		PreferredLoadOption := "PreferredLoad"
@@ -220,14 +487,43 @@ func ConfigureManager(config map[string]interface{}) {
 	if ctl, ok := config[ChangeTriggerLimitKey]; ok {
 		manager.ChangeTriggerLimit = int(ctl.(float64))
 	}
+	if mt, ok := config[MigrationTimeoutKey]; ok {
+		manager.MigrationTimeout = time.Duration(int64(mt.(float64)) * int64(UnitOfTime))
+	}
+	if pr, ok := config[PairingRetriesKey]; ok {
+		manager.PairingRetries = int(pr.(float64))
+	}
+	if prb, ok := config[PairingRetryBudgetKey]; ok {
+		manager.PairingRetryBudget = time.Duration(int64(prb.(float64)) * int64(UnitOfTime))
+	}
 }
 
 func ConfigureNegotiator(config map[string]interface{}) {
 	RoundtripLimitKey := "MaxRoundtripsDuringNegotiation"
+	StrategyKey := "Strategy"
+	NegotiationBudgetKey := "NegotiationBudget"
+	MessageIdleTimeoutKey := "MessageIdleTimeout"
+	TrickleTimeoutKey := "TrickleTimeout"
 
 	if rl, ok := config[RoundtripLimitKey]; ok {
 		negotiator.RoundtripLimit = int(rl.(float64))
 	}
+	if s, ok := config[StrategyKey]; ok {
+		strategy, ok := negotiator.Strategies[s.(string)]
+		if !ok {
+			log.Fatalf(InvalidOptionError, s.(string), StrategyKey)
+		}
+		negotiationStrategy = strategy
+	}
+	if nb, ok := config[NegotiationBudgetKey]; ok {
+		negotiator.NegotiationBudget = time.Duration(int64(nb.(float64)) * int64(UnitOfTime))
+	}
+	if mit, ok := config[MessageIdleTimeoutKey]; ok {
+		negotiator.MessageIdleTimeout = time.Duration(int64(mit.(float64)) * int64(UnitOfTime))
+	}
+	if tt, ok := config[TrickleTimeoutKey]; ok {
+		negotiator.TrickleTimeout = time.Duration(int64(tt.(float64)) * int64(UnitOfTime))
+	}
 }
 
 func ConfigureRegister(config map[string]interface{}) {
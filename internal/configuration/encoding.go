@@ -0,0 +1,156 @@
+package configuration
+
+import (
+	"log"
+
+	"github.com/arstevens/go-hive-signal/internal/negotiator"
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-hive-signal/pkg/jsonmsg"
+	"github.com/arstevens/go-hive-signal/pkg/protomsg"
+	"github.com/arstevens/go-request/handle"
+	"github.com/arstevens/go-request/route"
+)
+
+const (
+	ProtobufEncoding = "protobuf"
+	JSONEncoding     = "json"
+)
+
+/*messageCodec groups every function needed to speak
+one wire encoding with clients*/
+type messageCodec struct {
+	routeUnpacker       route.UnpackRouteRequest
+	localizerUnpacker   handle.UnpackRequest
+	registratorUnpacker handle.UnpackRequest
+	connectorUnpacker   handle.UnpackRequest
+	unmarshalNegotiate  negotiator.UnmarshalNegotiateMessage
+	marshalNegotiate    negotiator.MarshalNegotiateMessage
+	marshalSignal       negotiator.MarshalSignalMessage
+	marshalResponse     responder.MarshalResponseMessage
+	marshalNotice       responder.MarshalNoticeMessage
+	unmarshalNotice     responder.UnmarshalNoticeMessage
+}
+
+var messageCodecs = map[string]messageCodec{
+	ProtobufEncoding: messageCodec{
+		routeUnpacker:       protomsg.UnpackRouteWrapper,
+		localizerUnpacker:   protomsg.UnpackLocalizeRequest,
+		registratorUnpacker: protomsg.UnpackRegistrationRequest,
+		connectorUnpacker:   protomsg.UnpackConnectionRequest,
+		unmarshalNegotiate:  protomsg.UnmarshalNegotiateMessage,
+		marshalNegotiate:    protomsg.NewNegotiateMessage,
+		marshalSignal:       marshalProtobufSignal,
+		marshalResponse:     marshalProtobufResponse,
+		marshalNotice:       marshalProtobufNotice,
+		unmarshalNotice:     protomsg.UnpackNotice,
+	},
+	JSONEncoding: messageCodec{
+		routeUnpacker:       jsonmsg.UnpackRouteWrapper,
+		localizerUnpacker:   jsonmsg.UnpackLocalizeRequest,
+		registratorUnpacker: jsonmsg.UnpackRegistrationRequest,
+		connectorUnpacker:   jsonmsg.UnpackConnectionRequest,
+		unmarshalNegotiate:  jsonmsg.UnmarshalNegotiateMessage,
+		marshalNegotiate:    jsonmsg.NewNegotiateMessage,
+		marshalSignal:       marshalJSONSignal,
+		marshalResponse:     marshalJSONResponse,
+		marshalNotice:       marshalJSONNotice,
+		unmarshalNotice:     jsonmsg.UnpackNotice,
+	},
+}
+
+func marshalProtobufResponse(r responder.Response) ([]byte, error) {
+	return protomsg.MarshalResponse(&protomsg.Response{
+		Status:       protomsg.ResponseStatus(r.Status),
+		Category:     protomsg.ErrorCategory(r.Category),
+		Reason:       r.Reason,
+		Version:      r.Version,
+		Capabilities: r.Capabilities,
+		RetryAfter:   r.RetryAfter.Milliseconds(),
+		Peers:        r.Peers,
+		EndpointID:   r.EndpointID,
+	})
+}
+
+func marshalJSONResponse(r responder.Response) ([]byte, error) {
+	return jsonmsg.MarshalResponse(&jsonmsg.Response{
+		Status:       r.Status,
+		Category:     r.Category,
+		Reason:       r.Reason,
+		Version:      r.Version,
+		Capabilities: r.Capabilities,
+		RetryAfter:   r.RetryAfter.Milliseconds(),
+		Peers:        r.Peers,
+		EndpointID:   r.EndpointID,
+	})
+}
+
+func marshalProtobufSignal(s negotiator.Signal) ([]byte, error) {
+	var candidate *protomsg.IceCandidate
+	if s.Candidate != nil {
+		candidate = &protomsg.IceCandidate{
+			Candidate:        s.Candidate.Candidate,
+			SdpMid:           s.Candidate.SDPMid,
+			SdpMLineIndex:    s.Candidate.SDPMLineIndex,
+			UsernameFragment: s.Candidate.UsernameFragment,
+		}
+	}
+	return protomsg.NewSignalMessage(s.Type, s.SDP, candidate)
+}
+
+func marshalJSONSignal(s negotiator.Signal) ([]byte, error) {
+	var candidate *jsonmsg.IceCandidate
+	if s.Candidate != nil {
+		candidate = &jsonmsg.IceCandidate{
+			Candidate:        s.Candidate.Candidate,
+			SdpMid:           s.Candidate.SDPMid,
+			SdpMLineIndex:    s.Candidate.SDPMLineIndex,
+			UsernameFragment: s.Candidate.UsernameFragment,
+		}
+	}
+	return jsonmsg.NewSignalMessage(s.Type, s.SDP, candidate)
+}
+
+func marshalProtobufNotice(n responder.Notice) ([]byte, error) {
+	return protomsg.MarshalNotice(&protomsg.Notice{
+		Type:       protomsg.NoticeType(n.Type),
+		Reason:     n.Reason,
+		Peer:       n.Peer,
+		Rtt:        n.RTT.Microseconds(),
+		Dataspaces: n.Dataspaces,
+	})
+}
+
+func marshalJSONNotice(n responder.Notice) ([]byte, error) {
+	return jsonmsg.MarshalNotice(&jsonmsg.Notice{
+		Type:       n.Type,
+		Reason:     n.Reason,
+		Peer:       n.Peer,
+		Rtt:        n.RTT.Microseconds(),
+		Dataspaces: n.Dataspaces,
+	})
+}
+
+//unpackers maps the configured routing codes to the request unpackers of the codec
+func (mc messageCodec) unpackers() map[int32]handle.UnpackRequest {
+	return map[int32]handle.UnpackRequest{
+		localizerRoutingCode:   mc.localizerUnpacker,
+		registratorRoutingCode: mc.registratorUnpacker,
+		connectorRoutingCode:   mc.connectorUnpacker,
+	}
+}
+
+//parseEncoding exits if 'encoding' set under 'key' names no known codec
+func parseEncoding(encoding string, key string) string {
+	if _, ok := messageCodecs[encoding]; !ok {
+		log.Fatalf(InvalidOptionError, encoding, key)
+	}
+	return encoding
+}
+
+//listenerEncoding returns 'encoding' or the default encoding if it is unset
+func listenerEncoding(encoding string) string {
+	if encoding == "" {
+		return messageEncoding
+	}
+	return encoding
+}
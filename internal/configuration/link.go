@@ -2,13 +2,15 @@ package configuration
 
 import (
 	"fmt"
+	"io"
 	"log"
-	"net"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/admission"
 	"github.com/arstevens/go-hive-signal/internal/analyzer"
 	"github.com/arstevens/go-hive-signal/internal/cache"
 	"github.com/arstevens/go-hive-signal/internal/comparator"
@@ -18,19 +20,32 @@ import (
 	"github.com/arstevens/go-hive-signal/internal/localizer"
 	"github.com/arstevens/go-hive-signal/internal/manager"
 	"github.com/arstevens/go-hive-signal/internal/mapper"
-	"github.com/arstevens/go-hive-signal/internal/negotiator"
+	"github.com/arstevens/go-hive-signal/internal/protocol"
 	"github.com/arstevens/go-hive-signal/internal/register"
 	"github.com/arstevens/go-hive-signal/internal/registrator"
+	"github.com/arstevens/go-hive-signal/internal/session"
 	"github.com/arstevens/go-hive-signal/internal/tracker"
 	"github.com/arstevens/go-hive-signal/internal/transmuter"
 	"github.com/arstevens/go-hive-signal/internal/verifier"
+	"github.com/arstevens/go-hive-signal/internal/vivaldi"
 	"github.com/arstevens/go-hive-signal/internal/wrapper"
 	"github.com/arstevens/go-request/handle"
 	"github.com/arstevens/go-request/route"
 )
 
+/*TimeForTeardown is how long shutdown waits for the jobs being handled
+to finish before the swarms and their endpoints are closed*/
 var TimeForTeardown = time.Second * 5
 
+/*ShutdownReason is sent to endpoints that accept notices when the
+server shuts down*/
+var ShutdownReason = "Server shutting down"
+
+//jobHandler describes a request handler that can be shut down
+type jobHandler interface {
+	Shutdown(bool, time.Duration) error
+}
+
 func LinkProgram() (func(), error) {
 	endpointRegister, err := register.New()
 	if err != nil {
@@ -40,47 +55,84 @@ func LinkProgram() (func(), error) {
 	identityVerifier := verifier.New(endpointRegister, connectionCache)
 
 	lpseGenerator := debriefer.NewLPSEGenerator(debrieferLoadHistorySize)
-	gatewayGenerator := gateway.NewGenerator(gatewayActiveQueueSize)
+	var coordinates gateway.CoordinateRegistry
+	if coordinatesEnabled {
+		coordinates = vivaldi.NewRegistry(coordinatesCapacity)
+	}
+	gatewayGenerator := gateway.NewGenerator(gatewayActiveQueueSize, gatewaySelectionPolicy, coordinates)
 	infoTracker := tracker.New(lpseGenerator, trackerLoadHistorySize)
-	managerGenerator := manager.NewGenerator(gatewayGenerator, negotiator.RoundtripLimitedNegotiate,
+	managerGenerator := manager.NewGenerator(gatewayGenerator, negotiationStrategy,
 		infoTracker)
 	loadToSizeComparator := comparator.New(infoTracker)
-	dataAnalyzer := analyzer.New(infoTracker, loadToSizeComparator)
 	swarmMap := mapper.New(managerGenerator)
+	dataAnalyzer := analyzer.New(infoTracker, loadToSizeComparator, swarmMap)
 	swarmTransmuter := transmuter.New(swarmMap, dataAnalyzer)
 
-	requestLocalizer := localizer.New(localizerQueueSize, swarmMap, infoTracker)
-	registrationHandler := registrator.New(registratorQueueSize, swarmMap, endpointRegister)
-	connectionHandler := connector.New(connectorQueueSize, identityVerifier, swarmTransmuter)
+	requestLocalizer := localizer.New(localizerQueueSize, localizerWorkers, localizerOverflow, swarmMap, infoTracker)
+	registrationHandler := registrator.New(registratorQueueSize, registratorWorkers, registratorOverflow, swarmMap, endpointRegister)
+	connectionHandler := connector.New(connectorQueueSize, connectorWorkers, connectorOverflow, identityVerifier, swarmTransmuter)
 
 	routeMap := map[int32]handle.RequestHandler{
 		localizerRoutingCode:   requestLocalizer,
 		registratorRoutingCode: registrationHandler,
 		connectorRoutingCode:   connectionHandler,
 	}
-	unpackersMap := map[int32]handle.UnpackRequest{
-		localizerRoutingCode:   localizerUnpacker,
-		registratorRoutingCode: registratorUnpacker,
-		connectorRoutingCode:   connectorUnpacker,
-	}
-
-	listenAddr := fmt.Sprintf(":%d", listenerPort)
-	netListener, err := net.Listen("tcp", listenAddr)
+	routeListeners, err := createListeners()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to link program in configuration.LinkProgram(): %v", err)
 	}
-	routeListener := wrapper.NewNetListener(netListener)
 
 	return func() {
 		done := make(chan struct{})
-		go route.UnpackAndRoute(routeListener, done, routeMap, routeUnpacker, unpackersMap, route.ReadRequestFromNetConn)
+		for _, routeListener := range routeListeners {
+			codec := messageCodecs[routeListener.encoding]
+			readRequest := protocol.NewNegotiatingReader(wrapper.ReadRequest, codec.routeUnpacker)
+			sessionListener := session.NewListener(routeListener.listener, readRequest,
+				codec.routeUnpacker, sessionRoutingCode)
+			go route.UnpackAndRoute(sessionListener, done, routeMap, codec.routeUnpacker,
+				codec.unpackers(), readRequest)
+		}
 		log.Println("Hive Signal started successfully")
 
-		sigterm := make(chan os.Signal)
+		sigterm := make(chan os.Signal, 1)
 		signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
 
 		<-sigterm
+		log.Println("Hive Signal shutting down")
+		//Stop accepting requests and restructuring swarms
 		close(done)
-		time.Sleep(TimeForTeardown)
+		for _, poller := range []io.Closer{swarmTransmuter, dataAnalyzer, infoTracker, connectionCache} {
+			poller.Close()
+		}
+
+		shutdownHandlers([]jobHandler{requestLocalizer, registrationHandler, connectionHandler})
+
+		gateway.ClosingReason = ShutdownReason
+		swarmMap.Close()
+		err := endpointRegister.Close()
+		if err != nil {
+			log.Printf("Failed to close endpoint register: %v", err)
+		}
+		log.Println("Hive Signal stopped")
 	}, nil
 }
+
+/*shutdownHandlers shuts every handler down at once and waits up to
+TimeForTeardown for the jobs being handled to finish*/
+func shutdownHandlers(handlers []jobHandler) {
+	finished := &sync.WaitGroup{}
+	for _, handler := range handlers {
+		finished.Add(1)
+		go func(handler jobHandler) {
+			defer finished.Done()
+			err := handler.Shutdown(drainQueuesOnShutdown, TimeForTeardown)
+			if err != nil {
+				log.Println(err)
+			}
+		}(handler)
+	}
+	err := admission.Wait(finished, TimeForTeardown)
+	if err != nil {
+		log.Printf("Abandoning unfinished jobs: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/arstevens/go-hive-signal/internal/wrapper"
+	"github.com/arstevens/go-request/route"
+)
+
+//encodedListener pairs a listener with the wire encoding its clients speak
+type encodedListener struct {
+	listener route.Listener
+	encoding string
+}
+
+/*createListeners opens the plain TCP listener and, when configured, the
+WebSocket listener. Both are secured with TLS if it is enabled*/
+func createListeners() ([]encodedListener, error) {
+	var tlsConfig *tls.Config
+	if isTLSEnabled() {
+		var err error
+		tlsConfig, err = loadTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create listeners in createListeners(): %v", err)
+		}
+	}
+
+	netListener, err := listen(listenerPort, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create listeners in createListeners(): %v", err)
+	}
+	tcpEncoding := listenerEncoding(tcpListenerEncoding)
+	listeners := []encodedListener{
+		encodedListener{wrapper.NewNetListener(netListener, tcpEncoding), tcpEncoding},
+	}
+
+	if websocketPort != 0 {
+		wsListener, err := listen(websocketPort, tlsConfig)
+		if err != nil {
+			netListener.Close()
+			return nil, fmt.Errorf("Failed to create listeners in createListeners(): %v", err)
+		}
+		wsEncoding := listenerEncoding(websocketListenerEncoding)
+		wsRouteListener := wrapper.NewWebSocketListener(wsListener, websocketPath, wsEncoding)
+		listeners = append(listeners, encodedListener{wsRouteListener, wsEncoding})
+	}
+	return listeners, nil
+}
+
+func listen(port int, tlsConfig *tls.Config) (net.Listener, error) {
+	netListener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		netListener = tls.NewListener(netListener, tlsConfig)
+	}
+	return netListener, nil
+}
@@ -0,0 +1,47 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func isTLSEnabled() bool {
+	return tlsCertificateFile != "" && tlsKeyFile != ""
+}
+
+/*loadTLSConfig builds the server side tls.Config described by the
+Listener.TLS section of the configuration. Client certificates are
+required and verified only when a client CA is configured*/
+func loadTLSConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(tlsCertificateFile, tlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load key pair in loadTLSConfig(): %v", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tlsVersions[tlsMinimumVersion],
+	}
+	if tlsClientCAFile != "" {
+		rawCA, err := ioutil.ReadFile(tlsClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read client CA in loadTLSConfig(): %v", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(rawCA) {
+			return nil, fmt.Errorf("No certificates found in client CA %s in loadTLSConfig()", tlsClientCAFile)
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
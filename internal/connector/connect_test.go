@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
@@ -8,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/admission"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	"github.com/arstevens/go-request/handle"
 )
 
@@ -37,7 +40,7 @@ func TestConnector(t *testing.T) {
 	verifier := TestIdentityVerifier{}
 	connector := TestSwarmConnector{}
 
-	handler := New(queueSize, &verifier, &connector)
+	handler := New(queueSize, 1, admission.Overflow{}, &verifier, &connector)
 
 	for i := 0; i < totalRequests; i++ {
 		handler.AddJob(&requests[i], &conns[i])
@@ -46,6 +49,43 @@ func TestConnector(t *testing.T) {
 	time.Sleep(time.Second)
 }
 
+func TestRequiredCapabilities(t *testing.T) {
+	fmt.Printf("----------------------\nREQUIRED CAPABILITIES TEST\n----------------------\n")
+	RequiredCapabilities = 0x3
+	defer func() { RequiredCapabilities = 0 }()
+
+	request := TestConnectionRequest{origin: "/origin/0", logon: true}
+	verifier := TestIdentityVerifier{}
+	connector := TestSwarmConnector{}
+
+	lacking := CapableFakeConn{capabilities: 0x1}
+	err := handleConnectionRequest(context.Background(), &request, &lacking, &verifier, &connector)
+	fmt.Printf("Lacking capabilities: %v\n", err)
+	if responder.CategoryOf(err, responder.NoError) != responder.IncompatibleProtocol {
+		t.Fatalf("Expected endpoint lacking capabilities to be rejected")
+	}
+
+	capable := CapableFakeConn{capabilities: 0x7}
+	err = handleConnectionRequest(context.Background(), &request, &capable, &verifier, &connector)
+	if err != nil {
+		t.Fatalf("Expected capable endpoint to log on: %v", err)
+	}
+	fmt.Printf("Issued endpoint identity %s\n", capable.endpointID)
+	if len(capable.endpointID) != 32 {
+		t.Fatalf("Expected the endpoint to be issued an identity on log on")
+	}
+
+	request.logon = false
+	request.endpointID = capable.endpointID
+	err = handleConnectionRequest(context.Background(), &request, &lacking, &verifier, &connector)
+	if err != nil {
+		t.Fatalf("Log off should not require capabilities: %v", err)
+	}
+	if lacking.endpointID != capable.endpointID {
+		t.Fatalf("Expected log off to carry the identity it presented")
+	}
+}
+
 type TestIdentityVerifier struct{}
 
 func (tv *TestIdentityVerifier) Analyze(ip net.IP, orig string, logon bool) bool {
@@ -55,22 +95,31 @@ func (tv *TestIdentityVerifier) Analyze(ip net.IP, orig string, logon bool) bool
 
 type TestSwarmConnector struct{}
 
-func (tc *TestSwarmConnector) ProcessConnection(id string, connect bool, conn handle.Conn) error {
+func (tc *TestSwarmConnector) ProcessConnection(ctx context.Context, id string, connect bool, conn handle.Conn) error {
 	fmt.Printf("Adding conn with code(%t) to swarm\n", connect)
 	return nil
 }
+func (tc *TestSwarmConnector) PinConnection(ctx context.Context, id string, origin string, conn handle.Conn) error {
+	fmt.Printf("Pinning conn from origin %s to swarm %s\n", origin, id)
+	return nil
+}
 
 type TestConnectionRequest struct {
 	code    int
 	origin  string
 	logon   bool
 	swarmID string
+
+	endpointID string
 }
 
 func (tr *TestConnectionRequest) GetRequestCode() int { return tr.code }
 func (tr *TestConnectionRequest) GetOriginID() string { return tr.origin }
 func (tr *TestConnectionRequest) IsLogOn() bool       { return tr.logon }
 func (tr *TestConnectionRequest) GetSwarmID() string  { return tr.swarmID }
+func (tr *TestConnectionRequest) GetEndpointID() string {
+	return tr.endpointID
+}
 
 type FakeConn struct {
 	ip net.IP
@@ -80,3 +129,12 @@ func (fc *FakeConn) GetIP() net.IP             { return fc.ip }
 func (fc *FakeConn) Read([]byte) (int, error)  { return 0, nil }
 func (fc *FakeConn) Write([]byte) (int, error) { return 0, nil }
 func (fc *FakeConn) Close() error              { return nil }
+
+type CapableFakeConn struct {
+	FakeConn
+	capabilities uint64
+	endpointID   string
+}
+
+func (cc *CapableFakeConn) GetCapabilities() uint64 { return cc.capabilities }
+func (cc *CapableFakeConn) SetEndpointID(id string) { cc.endpointID = id }
@@ -1,35 +1,79 @@
 package connector
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/admission"
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	"github.com/arstevens/go-request/handle"
 )
 
+/*RequiredCapabilities holds the capability bits an endpoint must have
+negotiated to log on to a swarm*/
+var RequiredCapabilities uint64 = 0
+
+/*RequestTimeout bounds the time from admitting a connection request to
+adding its endpoint to a swarm. A RequestTimeout of 0 leaves requests unbounded*/
+var RequestTimeout time.Duration = 0
+
 /*ConnectionHandler verifies swarm connect requests and then
 passes them to a SwarmConnector*/
 type ConnectionHandler struct {
 	closed        bool
-	requestStream chan<- handle.RequestPair
+	ctx           context.Context
+	cancel        context.CancelFunc
+	mutex         *sync.RWMutex
+	workers       *sync.WaitGroup
+	overflow      admission.Overflow
+	requestStream chan handle.RequestPair
 }
 
-//New creates a new instance of ConnectionHandler
-func New(size int, verifier IdentityVerifier, connector SwarmConnector) *ConnectionHandler {
+/*New creates a new instance of ConnectionHandler that admits jobs following
+'overflow' once its job queue is full. The queue is drained by 'workers'
+concurrent goroutines, at least one*/
+func New(size int, workers int, overflow admission.Overflow, verifier IdentityVerifier, connector SwarmConnector) *ConnectionHandler {
 	requestStream := make(chan handle.RequestPair, size)
-	go processRequestStream(requestStream, verifier, connector)
+	ctx, cancel := context.WithCancel(context.Background())
+	workerGroup := &sync.WaitGroup{}
+	for i := 0; i < workers || i == 0; i++ {
+		workerGroup.Add(1)
+		go func() {
+			defer workerGroup.Done()
+			processRequestStream(ctx, requestStream, verifier, connector)
+		}()
+	}
 	return &ConnectionHandler{
 		closed:        false,
+		ctx:           ctx,
+		cancel:        cancel,
+		mutex:         &sync.RWMutex{},
+		workers:       workerGroup,
+		overflow:      overflow,
 		requestStream: requestStream,
 	}
 }
 
-//AddJob adds a request to the job queue. AddJob hangs if the job queue is full
+/*AddJob adds a request to the job queue. A full job queue is handled
+according to the overflow policy of the ConnectionHandler*/
 func (ch *ConnectionHandler) AddJob(request interface{}, conn handle.Conn) error {
+	pair := handle.RequestPair{Request: admission.NewJob(request), Conn: conn}
+	ch.mutex.RLock()
+	defer ch.mutex.RUnlock()
 	if ch.closed {
+		go admission.TurnAway(pair, ch.overflow.RetryAfter)
 		return fmt.Errorf("Cannot add a job on a closed ConnectionHandler")
 	}
-	ch.requestStream <- handle.RequestPair{Request: request, Conn: conn}
+	err := admission.Admit(ch.requestStream, pair, ch.overflow)
+	if err != nil {
+		return fmt.Errorf("Failed to add job to ConnectionHandler: %v", err)
+	}
 	return nil
 }
 
@@ -48,40 +92,124 @@ func (ch *ConnectionHandler) QueuedJobs() int {
 /*Close closes a DataspaceHandler for work and returns an error if the
 Handler is already closed*/
 func (ch *ConnectionHandler) Close() error {
-	if !ch.closed {
-		ch.closed = true
-		close(ch.requestStream)
-		return nil
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	if ch.closed {
+		return fmt.Errorf("Cannot close a closed DataspaceHandler")
 	}
-	return fmt.Errorf("Cannot close a closed DataspaceHandler")
+	ch.closed = true
+	close(ch.requestStream)
+	return nil
+}
+
+/*Shutdown closes the ConnectionHandler and handles the jobs left in its queue if
+'drain' is set or turns them away as busy otherwise. It then waits up to
+'timeout' for the jobs being handled to finish before cancelling them*/
+func (ch *ConnectionHandler) Shutdown(drain bool, timeout time.Duration) error {
+	err := ch.Close()
+	if err != nil {
+		return err
+	}
+	if !drain {
+		admission.Flush(ch.requestStream, ch.overflow.RetryAfter)
+	}
+	err = admission.Wait(ch.workers, timeout)
+	if err != nil {
+		//Abandon the jobs still being handled
+		ch.cancel()
+		return fmt.Errorf("Failed to shut down ConnectionHandler: %v", err)
+	}
+	ch.cancel()
+	return nil
 }
 
-func processRequestStream(requestStream <-chan handle.RequestPair,
+func processRequestStream(ctx context.Context, requestStream <-chan handle.RequestPair,
 	verifier IdentityVerifier, connector SwarmConnector) {
 	for {
 		requestPair, ok := <-requestStream
 		if !ok {
 			return
 		}
-		request := requestPair.Request.(ConnectionRequest)
+		job := requestPair.Request.(admission.Job)
+		request := job.Request.(ConnectionRequest)
 		conn := requestPair.Conn.(NetConn)
 
-		err := handleConnectionRequest(request, conn, verifier, connector)
+		jobCtx, cancel := job.Context(ctx, RequestTimeout)
+		err := handleConnectionRequest(jobCtx, request, conn, verifier, connector)
+		cancel()
 		if err != nil {
 			log.Println(err)
+			err = responder.Failure(conn, err, responder.InternalError)
+		} else if !request.IsLogOn() {
+			err = responder.Success(conn)
+		} else {
+			/*A successful log on hands the connection over to the
+			SwarmConnector which acknowledges it itself*/
+			continue
 		}
+		if err != nil {
+			log.Printf("Failed to respond to requester in ConnectionHandler: %v", err)
+		}
+		conn.Close()
 	}
 }
 
-func handleConnectionRequest(request ConnectionRequest, conn NetConn,
+func handleConnectionRequest(ctx context.Context, request ConnectionRequest, conn NetConn,
 	verifier IdentityVerifier, connector SwarmConnector) error {
 	if !verifier.Analyze(conn.GetIP(), request.GetOriginID(), request.IsLogOn()) {
-		return fmt.Errorf("Identity Verification failed in ConnectionHandler")
+		return responder.Errorf(responder.VerificationFailed, "Identity Verification failed in ConnectionHandler")
+	}
+	if request.IsLogOn() {
+		if missing := RequiredCapabilities &^ capabilitiesOf(conn); missing != 0 {
+			return responder.Errorf(responder.IncompatibleProtocol,
+				"Endpoint is missing required capabilities %#x in ConnectionHandler", missing)
+		}
+		//Keep the declared metadata with the conn so the swarm can match requesters to it
+		dRequest, isDescribed := request.(DescribedRequest)
+		dConn, isDescribable := conn.(metadata.DescribedConn)
+		if isDescribed && isDescribable {
+			dConn.SetMetadata(metadata.FromMessage(dRequest.GetMetadata()))
+		}
+		//Endpoints behind the same address are told apart by their identity
+		if iConn, ok := conn.(IdentifiedConn); ok {
+			id, err := newEndpointID()
+			if err != nil {
+				return fmt.Errorf("Failed to issue endpoint identity in ConnectionHandler: %v", err)
+			}
+			iConn.SetEndpointID(id)
+		}
+	} else if iRequest, ok := request.(IdentifiedRequest); ok {
+		//Only the endpoint holding the identity may log itself off
+		if iConn, ok := conn.(IdentifiedConn); ok {
+			iConn.SetEndpointID(iRequest.GetEndpointID())
+		}
 	}
 
-	err := connector.ProcessConnection(request.GetSwarmID(), request.IsLogOn(), conn)
+	var err error
+	if pRequest, ok := request.(PinnedRequest); ok && pRequest.IsPinned() && request.IsLogOn() {
+		err = connector.PinConnection(ctx, request.GetSwarmID(), request.GetOriginID(), conn)
+	} else {
+		err = connector.ProcessConnection(ctx, request.GetSwarmID(), request.IsLogOn(), conn)
+	}
 	if err != nil {
-		return fmt.Errorf("Failed to pass request to SwarmConnector in ConnectionHandler: %v", err)
+		return fmt.Errorf("Failed to pass request to SwarmConnector in ConnectionHandler: %w", err)
 	}
 	return nil
 }
+
+//newEndpointID returns a random endpoint identity that other clients cannot guess
+func newEndpointID() (string, error) {
+	raw := make([]byte, 16)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func capabilitiesOf(conn handle.Conn) uint64 {
+	if cConn, ok := conn.(CapableConn); ok {
+		return cConn.GetCapabilities()
+	}
+	return 0
+}
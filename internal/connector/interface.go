@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"context"
 	"net"
 
 	"github.com/arstevens/go-request/handle"
@@ -13,10 +14,14 @@ type IdentityVerifier interface {
 	Analyze(net.IP, string, bool) bool
 }
 
-//SwarmConnector connects a connection to a swarm
+/*SwarmConnector connects a connection to a swarm. A SwarmConnector
+takes ownership of the connection of every successful log on and is
+responsible for acknowledging it*/
 type SwarmConnector interface {
 	// SwarmID if exists, Connection code, connection object to requester
-	ProcessConnection(string, bool, handle.Conn) error
+	ProcessConnection(context.Context, string, bool, handle.Conn) error
+	// Dataspace to join, OriginID, connection object to requester
+	PinConnection(context.Context, string, string, handle.Conn) error
 }
 
 /*ConnectionRequest is the request type that a
@@ -27,9 +32,40 @@ type ConnectionRequest interface {
 	IsLogOn() bool
 }
 
+/*DescribedRequest is a ConnectionRequest in which a logging on endpoint
+may declare its metadata. GetMetadata returns nil if none was declared*/
+type DescribedRequest interface {
+	GetMetadata() interface{}
+}
+
+/*PinnedRequest is a ConnectionRequest with which a logging on endpoint may
+pin itself to the swarm of the dataspace named by GetSwarmID. Only endpoints
+of the origin owning the dataspace may pin themselves to it*/
+type PinnedRequest interface {
+	IsPinned() bool
+}
+
+/*IdentifiedRequest is a ConnectionRequest with which a logging off endpoint
+presents the identity it was issued when it logged on*/
+type IdentifiedRequest interface {
+	GetEndpointID() string
+}
+
 /*NetConn is a type of handle.Conn that has an additional
 method GetIP() since it represents a network connection*/
 type NetConn interface {
 	handle.Conn
 	GetIP() net.IP
 }
+
+/*IdentifiedConn is a type of connection that can be issued the
+identity of the endpoint behind it*/
+type IdentifiedConn interface {
+	SetEndpointID(string)
+}
+
+/*CapableConn is a type of connection that knows the
+capabilities negotiated with its client*/
+type CapableConn interface {
+	GetCapabilities() uint64
+}
@@ -1,58 +1,593 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"sync"
+	"time"
 
 	"github.com/arstevens/go-hive-signal/internal/manager"
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-hive-signal/internal/vivaldi"
 )
 
+/*ClosingReason is sent to endpoints that accept notices when the
+gateway holding them is closed*/
+var ClosingReason = "Swarm closed"
+
+/*WaitingRoomSize bounds the number of requesters held by a swarm without
+endpoints until one joins. A WaitingRoomSize of 0 turns them away at once*/
+var WaitingRoomSize = 16
+
+/*WaitingTimeout bounds the time a requester is held in the waiting room*/
+var WaitingTimeout = time.Second * 10
+
+/*SwarmGateway hands out the endpoints of a swarm for exclusive use. An
+endpoint that has been checked out with GetEndpoint is not handed out
+again until it is released so that concurrent negotiations never share
+a connection. Which idle endpoint is handed out is decided by a
+SelectionPolicy among the endpoints predicted closest to the requester by
+the CoordinateRegistry. When HeartbeatInterval is set idle endpoints are
+pinged in the background and evicted once they stop answering. Requesters
+arriving while the swarm has no endpoints are held in a bounded waiting
+room until one joins*/
 type SwarmGateway struct {
 	activeQueue *activeConnectionQueue
+	checkedOut  map[manager.Conn]bool
+	suspects    map[manager.Conn]bool
+	departing   map[manager.Conn]bool
+	stats       map[manager.Conn]*EndpointStats
+	policy      SelectionPolicy
+	coordinates CoordinateRegistry
+	onEviction  func()
+	onWaiting   func(int)
+	waiting     int
+	unmet       int
 	aqMutex     *sync.Mutex
+	available   *sync.Cond
+	closed      bool
+	stop        chan struct{}
+	stopped     chan struct{}
 }
 
-func New(activeSize int) *SwarmGateway {
-	return &SwarmGateway{
+/*New creates a SwarmGateway that checks out endpoints following 'policy'.
+A nil 'policy' checks them out round robin. A nil 'coordinates' ignores
+the latency between endpoints and requesters*/
+func New(activeSize int, policy SelectionPolicy, coordinates CoordinateRegistry) *SwarmGateway {
+	if policy == nil {
+		policy = RoundRobin{}
+	}
+	aqMutex := &sync.Mutex{}
+	sg := &SwarmGateway{
 		activeQueue: newActiveConnectionQueue(activeSize),
-		aqMutex:     &sync.Mutex{},
+		checkedOut:  make(map[manager.Conn]bool),
+		suspects:    make(map[manager.Conn]bool),
+		departing:   make(map[manager.Conn]bool),
+		stats:       make(map[manager.Conn]*EndpointStats),
+		policy:      policy,
+		coordinates: coordinates,
+		aqMutex:     aqMutex,
+		available:   sync.NewCond(aqMutex),
+		closed:      false,
+	}
+	if HeartbeatInterval > 0 {
+		sg.stop = make(chan struct{})
+		sg.stopped = make(chan struct{})
+		go sg.heartbeat(sg.stop, sg.stopped)
 	}
+	return sg
 }
 
 func (sg *SwarmGateway) PushEndpoint(c manager.Conn) error {
+	return sg.push(manager.Restricted{Conn: c})
+}
+
+/*PushPinnedEndpoint adds an endpoint to the swarm that is pinned to
+'dataspace'. It is only checked out for requesters of that dataspace
+and never for a transfer to another swarm*/
+func (sg *SwarmGateway) PushPinnedEndpoint(c manager.Conn, dataspace string) error {
+	return sg.push(manager.Restricted{Conn: c, Dataspaces: []string{dataspace}, Pinned: true})
+}
+
+func (sg *SwarmGateway) push(endpoint manager.Restricted) error {
+	c := endpoint.Conn
 	sg.aqMutex.Lock()
-	sg.activeQueue.Push(c.(Conn))
-	sg.aqMutex.Unlock()
+	defer sg.aqMutex.Unlock()
+	if sg.closed {
+		c.Close()
+		return fmt.Errorf("Failed to push endpoint in SwarmGateway.PushEndpoint(): Gateway closed")
+	}
+	conn := c.(Conn)
+	err := sg.activeQueue.Push(conn)
+	if err != nil {
+		return fmt.Errorf("Failed to push endpoint in SwarmGateway.PushEndpoint(): %v", err)
+	}
+	sg.stats[c] = newRestrictedStats(endpoint)
+	sg.clearUnmet()
+	//Every waiter is woken since the endpoint may only fit some of them
+	sg.available.Broadcast()
 	return nil
 }
 
-func (sg *SwarmGateway) GetEndpoint() (manager.Conn, error) {
+/*GetEndpoint checks out an endpoint fitting 'selection' for exclusive use.
+It waits while every such endpoint is checked out and fails once none are
+left or 'ctx' is done. If the swarm has no endpoints at all it waits in the
+waiting room for up to WaitingTimeout instead, unless the room is full or
+'selection' may not wait. Only requesters should wait in the room*/
+func (sg *SwarmGateway) GetEndpoint(ctx context.Context, selection manager.Selection) (manager.Conn, error) {
 	sg.aqMutex.Lock()
-	conn := sg.activeQueue.Pop()
-	for conn != nil && conn.IsClosed() {
-		conn = sg.activeQueue.Pop()
+	defer sg.aqMutex.Unlock()
+
+	//Wake the waiting loop below when 'ctx' is done
+	if ctx.Done() != nil {
+		returned := make(chan struct{})
+		defer close(returned)
+		go func() {
+			select {
+			case <-ctx.Done():
+				sg.aqMutex.Lock()
+				sg.available.Broadcast()
+				sg.aqMutex.Unlock()
+			case <-returned:
+			}
+		}()
 	}
-	if conn == nil {
-		sg.aqMutex.Unlock()
-		return nil, fmt.Errorf("No active endpoints in SwarmGateway.GetEndpoint()")
+
+	var room *time.Timer
+	timedOut, served := false, false
+	defer func() {
+		if room != nil {
+			room.Stop()
+			if !served {
+				sg.unmet++
+			}
+			sg.setWaiting(sg.waiting - 1)
+		}
+	}()
+
+	for {
+		conn := sg.selectEndpoint(selection)
+		if conn != nil {
+			served = true
+			sg.checkedOut[conn] = true
+			sg.stats[conn].recordPeer(selection.Requester)
+			return conn, nil
+		}
+		empty := sg.activeQueue.GetSize() == 0 && len(sg.checkedOut) == 0
+		if sg.closed || (len(sg.checkedOut) == 0 && !empty) {
+			return nil, fmt.Errorf("No active endpoints in SwarmGateway.GetEndpoint()")
+		}
+		if empty && selection.NoWait {
+			return nil, fmt.Errorf("No active endpoints in SwarmGateway.GetEndpoint()")
+		}
+		if empty && room == nil {
+			if sg.waiting >= WaitingRoomSize {
+				sg.unmet++
+				sg.setWaiting(sg.waiting)
+				return nil, fmt.Errorf("No active endpoints and waiting room full in SwarmGateway.GetEndpoint()")
+			}
+			sg.setWaiting(sg.waiting + 1)
+			room = time.AfterFunc(WaitingTimeout, func() {
+				sg.aqMutex.Lock()
+				timedOut = true
+				sg.available.Broadcast()
+				sg.aqMutex.Unlock()
+			})
+		}
+		if empty && timedOut {
+			return nil, fmt.Errorf("No endpoint joined in time in SwarmGateway.GetEndpoint()")
+		}
+		if !empty && !sg.anyCheckedOutFits(selection) {
+			return nil, fmt.Errorf("No endpoints meet the requirements in SwarmGateway.GetEndpoint()")
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Gave up waiting for an endpoint in SwarmGateway.GetEndpoint(): %v", ctx.Err())
+		}
+		sg.available.Wait()
+	}
+}
+
+/*ReleaseEndpoint returns an endpoint checked out with GetEndpoint to the
+swarm. Endpoints that closed or logged off while checked out are dropped.
+An endpoint that cannot be returned to the queue is closed and evicted*/
+func (sg *SwarmGateway) ReleaseEndpoint(c manager.Conn) error {
+	evicted, err := sg.release(c)
+	if evicted {
+		sg.notifyEviction()
+	}
+	return err
+}
+
+/*release returns 'c' to the swarm and reports whether or not it had
+to be evicted instead*/
+func (sg *SwarmGateway) release(c manager.Conn) (bool, error) {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	if !sg.checkedOut[c] {
+		return false, fmt.Errorf("Endpoint %s is not checked out in SwarmGateway.ReleaseEndpoint()", c.GetAddress())
+	}
+	delete(sg.checkedOut, c)
+	/*Wake every waiter so that they notice when the last
+	endpoint is gone instead of waiting forever*/
+	defer sg.available.Broadcast()
+
+	conn := c.(Conn)
+	if sg.closed {
+		delete(sg.stats, c)
+		delete(sg.departing, c)
+		return false, closeEndpoint(conn)
+	}
+	if sg.departing[c] {
+		delete(sg.stats, c)
+		delete(sg.departing, c)
+		return false, conn.Close()
+	}
+	if conn.IsClosed() {
+		delete(sg.stats, c)
+		return false, nil
+	}
+	sg.stats[c].recordNegotiation(time.Now())
+	err := sg.activeQueue.Push(conn)
+	if err != nil {
+		delete(sg.stats, c)
+		closeEndpoint(conn)
+		return true, fmt.Errorf("Failed to release endpoint in SwarmGateway.ReleaseEndpoint(): %v", err)
+	}
+	return false, nil
+}
+
+/*DetachEndpoints removes 'n' distinct idle endpoints from the swarm without
+closing them so that they can be handed to another swarm. Pinned endpoints
+and endpoints that cannot be told about a migration are never detached
+since they would serve dataspaces they know nothing of. Either all 'n'
+endpoints are detached or none are. Every endpoint that can be detached
+is if 'n' is negative*/
+func (sg *SwarmGateway) DetachEndpoints(n int) ([]manager.Conn, error) {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	if sg.closed {
+		return nil, fmt.Errorf("Failed to detach endpoints in SwarmGateway.DetachEndpoints(): Gateway closed")
+	}
+
+	positions := make([]int, 0, sg.activeQueue.GetSize())
+	for i := 0; i < sg.activeQueue.GetSize() && (n < 0 || len(positions) < n); i++ {
+		conn := sg.activeQueue.At(i)
+		if sg.isMovable(conn) {
+			positions = append(positions, i)
+		}
+	}
+	if n < 0 {
+		n = len(positions)
+	} else if len(positions) < n {
+		return nil, fmt.Errorf("Only %d of %d endpoints can be detached in SwarmGateway.DetachEndpoints()", len(positions), n)
+	}
+
+	//Remove from the back so that the positions ahead stay valid
+	detached := make([]manager.Conn, n)
+	for i := n - 1; i >= 0; i-- {
+		conn := sg.activeQueue.RemoveAt(positions[i])
+		delete(sg.stats, conn)
+		detached[i] = conn
+	}
+	return detached, nil
+}
+
+/*RestrictEndpoints limits every endpoint that serves all dataspaces of the
+swarm to 'dataspaces', checked out or not. It is called before the swarm
+gains dataspaces the endpoints do not hold the state of*/
+func (sg *SwarmGateway) RestrictEndpoints(dataspaces []string) {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	for _, stats := range sg.stats {
+		if len(stats.Dataspaces) == 0 {
+			stats.Dataspaces = append([]string{}, dataspaces...)
+		}
+	}
+}
+
+/*DetachRestricted removes every idle endpoint that DetachEndpoints never
+detaches and that only serves dataspaces among 'dataspaces' so that it can
+follow them to another swarm without a migration. Such endpoints that are
+checked out are closed once they are released since the swarm no longer
+serves their dataspaces*/
+func (sg *SwarmGateway) DetachRestricted(dataspaces []string) ([]manager.Restricted, error) {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	if sg.closed {
+		return nil, fmt.Errorf("Failed to detach endpoints in SwarmGateway.DetachRestricted(): Gateway closed")
+	}
+
+	follows := func(conn Conn) bool {
+		stats, ok := sg.stats[conn]
+		return ok && len(stats.Dataspaces) > 0 && (stats.Pinned || !acceptsMigration(conn)) &&
+			isSubset(stats.Dataspaces, dataspaces)
+	}
+	for c := range sg.checkedOut {
+		if follows(c.(Conn)) {
+			sg.departing[c] = true
+		}
+	}
+	detached := make([]manager.Restricted, 0)
+	//Remove from the back so that the positions ahead stay valid
+	for i := sg.activeQueue.GetSize() - 1; i >= 0; i-- {
+		conn := sg.activeQueue.At(i)
+		if conn.IsClosed() || !follows(conn) {
+			continue
+		}
+		stats := sg.stats[conn]
+		detached = append(detached, manager.Restricted{Conn: conn, Dataspaces: stats.Dataspaces, Pinned: stats.Pinned})
+		sg.activeQueue.RemoveAt(i)
+		delete(sg.stats, conn)
+	}
+	return detached, nil
+}
+
+/*AdoptEndpoints adds endpoints detached from another swarm. Either all of
+them are added or none are. They are left open when they cannot be added.
+The endpoints serve every dataspace of the swarm*/
+func (sg *SwarmGateway) AdoptEndpoints(conns []manager.Conn) error {
+	endpoints := make([]manager.Restricted, len(conns))
+	for i, conn := range conns {
+		endpoints[i] = manager.Restricted{Conn: conn}
+	}
+	return sg.adopt(endpoints)
+}
+
+/*AdoptRestricted adds restricted endpoints detached from another swarm
+like AdoptEndpoints. They keep serving the same dataspaces and stay pinned
+if they were*/
+func (sg *SwarmGateway) AdoptRestricted(endpoints []manager.Restricted) error {
+	return sg.adopt(endpoints)
+}
+
+func (sg *SwarmGateway) adopt(endpoints []manager.Restricted) error {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	if sg.closed {
+		return fmt.Errorf("Failed to adopt endpoints in SwarmGateway.AdoptEndpoints(): Gateway closed")
+	}
+	for pushed, endpoint := range endpoints {
+		err := sg.activeQueue.Push(endpoint.Conn.(Conn))
+		if err != nil {
+			//Undo the pushes that went through
+			for ; pushed > 0; pushed-- {
+				sg.activeQueue.RemoveAt(sg.activeQueue.GetSize() - 1)
+			}
+			return fmt.Errorf("Failed to adopt endpoints in SwarmGateway.AdoptEndpoints(): %v", err)
+		}
+	}
+	for _, endpoint := range endpoints {
+		sg.stats[endpoint.Conn] = newRestrictedStats(endpoint)
+	}
+	if len(endpoints) > 0 {
+		sg.clearUnmet()
+	}
+	sg.available.Broadcast()
+	return nil
+}
+
+/*RemoveEndpoint removes the endpoint that was issued the identity 'id'
+from the swarm and closes it. An endpoint that is checked out is closed
+once it is released instead*/
+func (sg *SwarmGateway) RemoveEndpoint(id string) error {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	if sg.closed {
+		return fmt.Errorf("Failed to remove endpoint in SwarmGateway.RemoveEndpoint(): Gateway closed")
+	}
+	if id == "" {
+		return fmt.Errorf("Failed to remove endpoint in SwarmGateway.RemoveEndpoint(): No identity given")
+	}
+
+	for i := 0; i < sg.activeQueue.GetSize(); i++ {
+		conn := sg.activeQueue.At(i)
+		if vivaldi.IdentityOf(conn) == id {
+			sg.activeQueue.RemoveAt(i)
+			delete(sg.stats, conn)
+			sg.available.Broadcast()
+			return conn.Close()
+		}
+	}
+	for c := range sg.checkedOut {
+		if !sg.departing[c] && vivaldi.IdentityOf(c) == id {
+			sg.departing[c] = true
+			return nil
+		}
+	}
+	return fmt.Errorf("No endpoint with identity %s in SwarmGateway.RemoveEndpoint()", id)
+}
+
+/*RecordDebrief keeps the preferred load reported by an endpoint in its
+debrief so that it can be weighed by the SelectionPolicy*/
+func (sg *SwarmGateway) RecordDebrief(c manager.Conn, debrief interface{}) {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	if preferredLoad, ok := debrief.(int); ok {
+		if stats, ok := sg.stats[c]; ok {
+			stats.PreferredLoad = preferredLoad
+		}
+	}
+}
+
+/*OnEviction registers 'evicted' to be called after the heartbeat
+evicted unresponsive endpoints from the gateway*/
+func (sg *SwarmGateway) OnEviction(evicted func()) {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	sg.onEviction = evicted
+}
+
+/*OnWaiting registers 'waiting' to be called with the number of requesters
+in the waiting room whenever it changes. Requesters that left the room
+without an endpoint or found it full keep being counted until an endpoint
+joins so that the demand is still seen by analyses that run long after
+they gave up. It is called while the gateway is locked and must not call
+back into it*/
+func (sg *SwarmGateway) OnWaiting(waiting func(int)) {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	sg.onWaiting = waiting
+}
+
+/*setWaiting records the size of the waiting room and reports it along
+with the unmet requesters. The caller must hold aqMutex*/
+func (sg *SwarmGateway) setWaiting(waiting int) {
+	sg.waiting = waiting
+	if sg.onWaiting != nil {
+		sg.onWaiting(waiting + sg.unmet)
+	}
+}
+
+//clearUnmet forgets the unmet requesters once an endpoint joined. The caller must hold aqMutex
+func (sg *SwarmGateway) clearUnmet() {
+	if sg.unmet > 0 {
+		sg.unmet = 0
+		sg.setWaiting(sg.waiting)
 	}
-	sg.activeQueue.Push(conn)
-	sg.aqMutex.Unlock()
-	return conn, nil
 }
 
+//GetMovableEndpoints returns the number of idle endpoints DetachEndpoints can detach
+func (sg *SwarmGateway) GetMovableEndpoints() int {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	movable := 0
+	for i := 0; i < sg.activeQueue.GetSize(); i++ {
+		if sg.isMovable(sg.activeQueue.At(i)) {
+			movable++
+		}
+	}
+	return movable
+}
+
+//isMovable returns whether or not the idle 'conn' may be detached. Requires aqMutex
+func (sg *SwarmGateway) isMovable(conn Conn) bool {
+	return !sg.stats[conn].Pinned && acceptsMigration(conn) && !conn.IsClosed()
+}
+
+/*GetTotalEndpoints returns the number of endpoints including those checked
+out. Endpoints that logged off while checked out are not counted*/
 func (sg *SwarmGateway) GetTotalEndpoints() int {
 	sg.aqMutex.Lock()
 	defer sg.aqMutex.Unlock()
-	return sg.activeQueue.GetSize()
+	return sg.activeQueue.GetSize() + len(sg.checkedOut) - len(sg.departing)
 }
 
+/*Close closes every idle endpoint and stops the heartbeat. Endpoints that
+are checked out are closed when they are released*/
 func (sg *SwarmGateway) Close() error {
 	sg.aqMutex.Lock()
+	if sg.closed {
+		sg.aqMutex.Unlock()
+		return nil
+	}
+	idle := make([]Conn, 0, sg.activeQueue.GetSize())
 	for !sg.activeQueue.IsEmpty() {
 		conn := sg.activeQueue.Pop()
-		conn.Close()
+		delete(sg.stats, conn)
+		idle = append(idle, conn)
+	}
+	sg.closed = true
+	sg.available.Broadcast()
+	sg.aqMutex.Unlock()
+
+	if sg.stop != nil {
+		close(sg.stop)
+		<-sg.stopped
 	}
+	//Suspects are held by the heartbeat which has now returned
+	sg.aqMutex.Lock()
+	for c := range sg.suspects {
+		delete(sg.checkedOut, c)
+		delete(sg.stats, c)
+		delete(sg.departing, c)
+		idle = append(idle, c.(Conn))
+	}
+	sg.suspects = make(map[manager.Conn]bool)
 	sg.aqMutex.Unlock()
+
+	for _, conn := range idle {
+		closeEndpoint(conn)
+	}
 	return nil
 }
+
+/*selectEndpoint removes the idle endpoint fitting 'selection' chosen by
+the SelectionPolicy from the queue. Only the first MaxSelectionCandidates
+fitting endpoints of the queue are weighed. Closed endpoints it comes
+across are dropped. The caller must hold aqMutex*/
+func (sg *SwarmGateway) selectEndpoint(selection manager.Selection) Conn {
+	for {
+		idle := make([]*EndpointStats, 0, sg.activeQueue.GetSize())
+		positions := make([]int, 0, sg.activeQueue.GetSize())
+		for i := 0; i < sg.activeQueue.GetSize(); i++ {
+			if MaxSelectionCandidates > 0 && len(idle) >= MaxSelectionCandidates {
+				break
+			}
+			conn := sg.activeQueue.At(i)
+			stats := sg.stats[conn]
+			if fits(selection, conn, stats) {
+				idle = append(idle, stats)
+				positions = append(positions, i)
+			}
+		}
+		if len(idle) == 0 {
+			return nil
+		}
+		idle, positions = sg.nearest(selection.Requester, idle, positions)
+
+		conn := sg.activeQueue.RemoveAt(positions[sg.policy.Select(idle)])
+		if !conn.IsClosed() {
+			return conn
+		}
+		delete(sg.stats, conn)
+	}
+}
+
+//anyCheckedOutFits returns whether or not a checked out endpoint fits 'selection'
+func (sg *SwarmGateway) anyCheckedOutFits(selection manager.Selection) bool {
+	for conn := range sg.checkedOut {
+		if stats, ok := sg.stats[conn]; ok && fits(selection, conn, stats) {
+			return true
+		}
+	}
+	return false
+}
+
+//fits returns whether or not 'conn' described by 'stats' may be checked out for 'selection'
+func fits(selection manager.Selection, conn manager.Conn, stats *EndpointStats) bool {
+	return !selection.Excludes(conn) && selection.Requirements.IsMetBy(stats.Metadata) &&
+		(len(stats.Dataspaces) == 0 || isSubset(selection.Dataspaces, stats.Dataspaces))
+}
+
+//isSubset returns whether or not every dataspace of 'dataspaces' is among 'of'
+func isSubset(dataspaces []string, of []string) bool {
+	for _, dataspace := range dataspaces {
+		found := false
+		for _, other := range of {
+			if dataspace == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+/*closeEndpoint tells endpoints that accept notices why they are
+being disconnected before closing them*/
+func closeEndpoint(conn Conn) error {
+	if conn.IsClosed() {
+		return nil
+	}
+	if acceptsNotices(conn) {
+		err := responder.Notify(conn, responder.Notice{Type: responder.NoticeClosing, Reason: ClosingReason})
+		if err != nil {
+			log.Printf("Failed to notify endpoint %s in SwarmGateway: %v", conn.GetAddress(), err)
+		}
+	}
+	return conn.Close()
+}
@@ -1,17 +1,29 @@
 package gateway
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"math/rand"
+	"net"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/manager"
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+	"github.com/arstevens/go-hive-signal/internal/protocol"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 )
 
 func TestSwarmGateway(t *testing.T) {
 	fmt.Printf("---------------------------\n    SWARM GATEWAY TEST\n---------------------------\n")
 
 	activeSize := 11
-	gateway := New(activeSize-1)
+	gateway := New(activeSize-1, RoundRobin{}, nil)
 
 	fmt.Printf("Populating gateway with %d connections...\n", activeSize)
 	for i := 0; i < activeSize; i++ {
@@ -26,7 +38,7 @@ func TestSwarmGateway(t *testing.T) {
 	removals := make([]*FakeConn, activeSize)
 	fmt.Printf("Getting %d endpoints...\n", activeSize)
 	for i := 0; i < activeSize; i++ {
-		conn, err := gateway.GetEndpoint()
+		conn, err := gateway.GetEndpoint(context.Background(), manager.Selection{})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -35,6 +47,397 @@ func TestSwarmGateway(t *testing.T) {
 		fmt.Printf("\tFetched endpoint at %s\n", c.GetAddress())
 	}
 	fmt.Printf("\tTotal Connections: active=%d\n", gateway.activeQueue.GetSize())
+	if gateway.GetTotalEndpoints() != activeSize {
+		t.Fatalf("Checked out endpoints should still be counted, got %d", gateway.GetTotalEndpoints())
+	}
+
+	fmt.Printf("Releasing %d endpoints...\n", activeSize)
+	removals[0].closed = true
+	for _, c := range removals {
+		err := gateway.ReleaseEndpoint(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fmt.Printf("\tTotal Connections: active=%d\n", gateway.activeQueue.GetSize())
+	if gateway.GetTotalEndpoints() != activeSize-1 {
+		t.Fatalf("Closed endpoint should be dropped on release, got %d", gateway.GetTotalEndpoints())
+	}
+	if gateway.ReleaseEndpoint(removals[1]) == nil {
+		t.Fatalf("Released an endpoint that was not checked out")
+	}
+}
+
+func TestConcurrentCheckout(t *testing.T) {
+	fmt.Printf("---------------------------\n  CONCURRENT CHECKOUT TEST\n---------------------------\n")
+	endpoints := 3
+	gateway := New(0, RoundRobin{}, nil)
+	for i := 0; i < endpoints; i++ {
+		gateway.PushEndpoint(&FakeConn{addr: "/address/" + strconv.Itoa(i)})
+	}
+
+	workers := 20
+	inUse := make(map[manager.Conn]bool)
+	useMutex := &sync.Mutex{}
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			conn, err := gateway.GetEndpoint(context.Background(), manager.Selection{})
+			if err != nil {
+				errs <- err
+				return
+			}
+			useMutex.Lock()
+			if inUse[conn] {
+				useMutex.Unlock()
+				errs <- fmt.Errorf("Endpoint %s checked out twice", conn.GetAddress())
+				return
+			}
+			inUse[conn] = true
+			useMutex.Unlock()
+
+			time.Sleep(time.Millisecond)
+			useMutex.Lock()
+			delete(inUse, conn)
+			useMutex.Unlock()
+			errs <- gateway.ReleaseEndpoint(conn)
+		}()
+	}
+	for i := 0; i < workers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	fmt.Printf("\t%d workers shared %d endpoints\n", workers, endpoints)
+	if gateway.GetTotalEndpoints() != endpoints {
+		t.Fatalf("Expected %d endpoints after checkouts, got %d", endpoints, gateway.GetTotalEndpoints())
+	}
+
+	for i := 0; i < endpoints; i++ {
+		gateway.GetEndpoint(context.Background(), manager.Selection{})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	_, err := gateway.GetEndpoint(ctx, manager.Selection{})
+	fmt.Printf("\tWaiting on a fully checked out swarm: %v\n", err)
+	if err == nil {
+		t.Fatalf("Expected GetEndpoint to give up once its context expired")
+	}
+}
+
+func TestSelectionPolicies(t *testing.T) {
+	fmt.Printf("---------------------------\n  SELECTION POLICY TEST\n---------------------------\n")
+	now := time.Now()
+	idle := []*EndpointStats{
+		{Conn: &FakeConn{addr: "/address/0"}, LastPaired: now, PreferredLoad: 0},
+		{Conn: &FakeConn{addr: "/address/1"}, LastPaired: now.Add(-time.Minute), PreferredLoad: 10},
+		{Conn: &FakeConn{addr: "/address/2"}, activity: 5, activitySince: now},
+	}
+	idle[0].activity, idle[0].activitySince = 2, now
+	idle[1].activity, idle[1].activitySince = 1, now
+
+	expected := map[string]int{"RoundRobin": 0, "LeastRecentlyPaired": 2, "LeastActive": 1}
+	for name, index := range expected {
+		selected := Policies[name].Select(idle)
+		fmt.Printf("\t%s selected %s\n", name, idle[selected].Conn.GetAddress())
+		if selected != index {
+			t.Fatalf("Expected %s to select %d, got %d", name, index, selected)
+		}
+	}
+	for _, name := range []string{"Random", "WeightedLoad"} {
+		for i := 0; i < 100; i++ {
+			if selected := Policies[name].Select(idle); selected < 0 || selected >= len(idle) {
+				t.Fatalf("%s selected out of range index %d", name, selected)
+			}
+		}
+	}
+
+	gateway := New(0, LeastActive{}, nil)
+	for i := 0; i < 2; i++ {
+		gateway.PushEndpoint(&FakeConn{addr: "/address/" + strconv.Itoa(i)})
+	}
+	first, _ := gateway.GetEndpoint(context.Background(), manager.Selection{})
+	gateway.ReleaseEndpoint(first)
+	second, _ := gateway.GetEndpoint(context.Background(), manager.Selection{})
+	fmt.Printf("\tLeast active gateway handed out %s then %s\n", first.GetAddress(), second.GetAddress())
+	if first == second {
+		t.Fatalf("Expected the least active endpoint to be handed out after a negotiation")
+	}
+}
+
+func TestEndpointRequirements(t *testing.T) {
+	fmt.Printf("---------------------------\n  ENDPOINT REQUIREMENTS TEST\n---------------------------\n")
+	gateway := New(0, RoundRobin{}, nil)
+	mobile := &DescribedConn{FakeConn: FakeConn{addr: "/address/mobile"}}
+	mobile.SetMetadata(metadata.Metadata{Transports: []string{"webrtc"}, MaxUploadBandwidth: 500})
+	desktop := &DescribedConn{FakeConn: FakeConn{addr: "/address/desktop"}}
+	desktop.SetMetadata(metadata.Metadata{Transports: []string{"webrtc", "tcp"}, MaxUploadBandwidth: 10000})
+	gateway.PushEndpoint(mobile)
+	gateway.PushEndpoint(desktop)
+
+	selection := manager.Selection{Requirements: metadata.Requirements{MinUploadBandwidth: 1000}}
+	conn, err := gateway.GetEndpoint(context.Background(), selection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("\tEndpoint meeting the requirements: %s\n", conn.GetAddress())
+	if conn != desktop {
+		t.Fatalf("Expected the desktop endpoint, got %s", conn.GetAddress())
+	}
+
+	/*The only compatible endpoint is checked out so the next request
+	waits for it instead of settling for the mobile endpoint*/
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if _, err = gateway.GetEndpoint(ctx, selection); err == nil {
+		t.Fatalf("Expected GetEndpoint to wait for the checked out compatible endpoint")
+	}
+	gateway.ReleaseEndpoint(conn)
+
+	//An excluded endpoint is skipped even if it is the only one meeting the requirements
+	selection.Exclude = []manager.Conn{desktop}
+	if _, err = gateway.GetEndpoint(context.Background(), selection); err == nil {
+		t.Fatalf("Expected the excluded desktop endpoint to be skipped")
+	}
+
+	_, err = gateway.GetEndpoint(context.Background(),
+		manager.Selection{Requirements: metadata.Requirements{Transports: []string{"quic"}}})
+	fmt.Printf("\tRequiring an unsupported transport: %v\n", err)
+	if err == nil {
+		t.Fatalf("Expected no endpoint to meet the requirements")
+	}
+}
+
+func TestNearestEndpoint(t *testing.T) {
+	fmt.Printf("---------------------------\n   NEAREST ENDPOINT TEST\n---------------------------\n")
+	coordinates := &FakeRegistry{rtts: map[string]time.Duration{
+		"/address/tokyo":     time.Millisecond * 200,
+		"/address/paris":     time.Millisecond * 10,
+		"/address/frankfurt": time.Millisecond * 25,
+	}}
+	gateway := New(0, RoundRobin{}, coordinates)
+	for _, city := range []string{"tokyo", "paris", "frankfurt", "unknown"} {
+		gateway.PushEndpoint(&FakeConn{addr: "/address/" + city})
+	}
+
+	LatencyTolerance = 0
+	conn, _ := gateway.GetEndpoint(context.Background(), manager.Selection{Requester: "requester"})
+	fmt.Printf("\tNearest endpoint: %s\n", conn.GetAddress())
+	if conn.GetAddress() != "/address/paris" {
+		t.Fatalf("Expected the nearest endpoint, got %s", conn.GetAddress())
+	}
+	gateway.ReleaseEndpoint(conn)
+
+	LatencyTolerance = time.Millisecond * 20
+	defer func() { LatencyTolerance = time.Millisecond * 20 }()
+	conn, _ = gateway.GetEndpoint(context.Background(), manager.Selection{Requester: "requester"})
+	fmt.Printf("\tRound robin among endpoints within tolerance: %s\n", conn.GetAddress())
+	if conn.GetAddress() != "/address/frankfurt" {
+		t.Fatalf("Expected the longest idle endpoint within tolerance, got %s", conn.GetAddress())
+	}
+	if !gateway.wasPairedWith(conn.(Conn), "requester") || gateway.wasPairedWith(conn.(Conn), "stranger") {
+		t.Fatalf("Latency reports should only be accepted about requesters the endpoint was paired with")
+	}
+	gateway.ReleaseEndpoint(conn)
+
+	//Only the first candidates of the queue are weighed
+	MaxSelectionCandidates = 1
+	defer func() { MaxSelectionCandidates = 64 }()
+	conn, _ = gateway.GetEndpoint(context.Background(), manager.Selection{Requester: "requester"})
+	fmt.Printf("\tNearest among the first candidate: %s\n", conn.GetAddress())
+	if conn.GetAddress() != "/address/tokyo" {
+		t.Fatalf("Expected the head of the queue to be the only candidate, got %s", conn.GetAddress())
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	fmt.Printf("---------------------------\n      HEARTBEAT TEST\n---------------------------\n")
+	responder.MarshalNotice = func(n responder.Notice) ([]byte, error) {
+		return []byte(fmt.Sprintf("%d:%s", n.Type, n.Reason)), nil
+	}
+	responder.UnmarshalNotice = func(raw []byte) (interface{}, error) {
+		parts := strings.SplitN(string(raw), ":", 2)
+		noticeType, err := strconv.Atoi(parts[0])
+		return &FakeNotice{noticeType: int32(noticeType), reason: parts[1]}, err
+	}
+	HeartbeatInterval, HeartbeatTimeout, MaxMissedHeartbeats = time.Millisecond*10, time.Millisecond*10, 2
+	defer func() { HeartbeatInterval = 0 }()
+
+	gateway := New(0, RoundRobin{}, nil)
+	defer gateway.Close()
+	evicted := make(chan struct{}, 1)
+	gateway.OnEviction(func() { evicted <- struct{}{} })
+
+	alive, alivePeer := net.Pipe()
+	go answerPings(alivePeer)
+	dead, _ := net.Pipe()
+	gateway.PushEndpoint(&PipeConn{Conn: alive, addr: "/address/alive"})
+	gateway.PushEndpoint(&PipeConn{Conn: dead, addr: "/address/dead"})
+
+	select {
+	case <-evicted:
+	case <-time.After(time.Second):
+		t.Fatalf("Unresponsive endpoint was never evicted")
+	}
+	fmt.Printf("\tEndpoints left after eviction: %d\n", gateway.GetTotalEndpoints())
+	if gateway.GetTotalEndpoints() != 1 {
+		t.Fatalf("Expected only the responsive endpoint to remain, got %d", gateway.GetTotalEndpoints())
+	}
+	conn, err := gateway.GetEndpoint(context.Background(), manager.Selection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.GetAddress() != "/address/alive" {
+		t.Fatalf("Expected the responsive endpoint, got %s", conn.GetAddress())
+	}
+	gateway.ReleaseEndpoint(conn)
+}
+
+func TestWaitingRoom(t *testing.T) {
+	fmt.Printf("---------------------------\n     WAITING ROOM TEST\n---------------------------\n")
+	WaitingRoomSize, WaitingTimeout = 1, time.Millisecond*50
+	defer func() { WaitingRoomSize, WaitingTimeout = 16, time.Second*10 }()
+	gateway := New(0, RoundRobin{}, nil)
+	waiting := make(chan int, 16)
+	gateway.OnWaiting(func(n int) { waiting <- n })
+
+	//The first requester waits in the room while the second finds it full
+	checkedOut := make(chan manager.Conn)
+	go func() {
+		conn, _ := gateway.GetEndpoint(context.Background(), manager.Selection{})
+		checkedOut <- conn
+	}()
+	if n := <-waiting; n != 1 {
+		t.Fatalf("Expected one waiting requester, got %d", n)
+	}
+	if _, err := gateway.GetEndpoint(context.Background(), manager.Selection{}); err == nil {
+		t.Fatalf("Expected a full waiting room to turn requesters away")
+	}
+	if n := <-waiting; n != 2 {
+		t.Fatalf("Expected the turned away requester to be counted, got %d", n)
+	}
+
+	//The turned away requester is forgotten once an endpoint joins
+	endpoint := &FakeConn{addr: "/address/late"}
+	gateway.PushEndpoint(endpoint)
+	if conn := <-checkedOut; conn != endpoint || <-waiting != 1 || <-waiting != 0 {
+		t.Fatalf("Expected the waiting requester to be handed the endpoint that joined")
+	}
+	fmt.Printf("\tWaiting requester paired with %s\n", endpoint.GetAddress())
+
+	gateway.RemoveEndpoint("/address/late")
+	gateway.ReleaseEndpoint(endpoint)
+	start := time.Now()
+	_, err := gateway.GetEndpoint(context.Background(), manager.Selection{})
+	fmt.Printf("\tGave up after %v: %v\n", time.Since(start), err)
+	if err == nil || time.Since(start) < WaitingTimeout {
+		t.Fatalf("Expected the requester to wait for WaitingTimeout before giving up")
+	}
+	if <-waiting != 1 || <-waiting != 1 {
+		t.Fatalf("Expected the requester that gave up to still be counted")
+	}
+
+	start, queued := time.Now(), len(waiting)
+	_, err = gateway.GetEndpoint(context.Background(), manager.Selection{NoWait: true})
+	if err == nil || time.Since(start) >= WaitingTimeout || len(waiting) != queued {
+		t.Fatalf("Expected a lookup that may not wait to fail at once without entering the waiting room")
+	}
+}
+
+func TestRemoveEndpoint(t *testing.T) {
+	fmt.Printf("---------------------------\n    REMOVE ENDPOINT TEST\n---------------------------\n")
+	gateway := New(0, RoundRobin{}, nil)
+	idle := &FakeConn{addr: "/address/idle"}
+	busy := &FakeConn{addr: "/address/busy"}
+	gateway.PushEndpoint(busy)
+	gateway.PushEndpoint(idle)
+	conn, _ := gateway.GetEndpoint(context.Background(), manager.Selection{})
+
+	if err := gateway.RemoveEndpoint("/address/idle"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.RemoveEndpoint("/address/busy"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("\tEndpoints left after both logged off: %d\n", gateway.GetTotalEndpoints())
+	if !idle.closed || busy.closed || gateway.GetTotalEndpoints() != 0 {
+		t.Fatalf("Expected the idle endpoint closed and the checked out one kept until released")
+	}
+	gateway.ReleaseEndpoint(conn)
+	if !busy.closed {
+		t.Fatalf("Expected the checked out endpoint to be closed once released")
+	}
+	if err := gateway.RemoveEndpoint("/address/unknown"); err == nil {
+		t.Fatalf("Expected removing an unknown endpoint to fail")
+	}
+}
+
+func TestPinnedEndpoint(t *testing.T) {
+	fmt.Printf("---------------------------\n    PINNED ENDPOINT TEST\n---------------------------\n")
+	gateway := New(0, RoundRobin{}, nil)
+	gateway.PushPinnedEndpoint(&MigratableConn{FakeConn: FakeConn{addr: "/address/pinned"}}, "/dataspace/0")
+	gateway.PushEndpoint(&FakeConn{addr: "/address/legacy"})
+	gateway.PushEndpoint(&MigratableConn{FakeConn: FakeConn{addr: "/address/free"}})
+
+	if gateway.GetMovableEndpoints() != 1 {
+		t.Fatalf("Expected only the endpoint that can migrate to be movable, got %d", gateway.GetMovableEndpoints())
+	}
+	if _, err := gateway.DetachEndpoints(2); err == nil || gateway.GetTotalEndpoints() != 3 {
+		t.Fatalf("Expected detaching pinned or legacy endpoints to detach nothing")
+	}
+	detached, err := gateway.DetachEndpoints(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("\tEndpoint detached for a transfer: %s\n", detached[0].GetAddress())
+	if detached[0].GetAddress() != "/address/free" {
+		t.Fatalf("Expected the endpoint that can migrate, got %s", detached[0].GetAddress())
+	}
+
+	other := New(0, RoundRobin{}, nil)
+	if err = other.AdoptEndpoints(detached); err != nil || other.GetTotalEndpoints() != 1 {
+		t.Fatalf("Expected the detached endpoint to be adopted by another swarm")
+	}
+	conn, err := gateway.GetEndpoint(context.Background(), manager.Selection{Dataspaces: []string{"/dataspace/0"}})
+	if err != nil || conn.GetAddress() != "/address/pinned" {
+		t.Fatalf("Expected the pinned endpoint to still be paired with requesters of its dataspace")
+	}
+	gateway.ReleaseEndpoint(conn)
+
+	//The swarm gains a dataspace the legacy endpoint cannot be told about
+	gateway.RestrictEndpoints([]string{"/dataspace/0"})
+	selection := manager.Selection{Dataspaces: []string{"/dataspace/1"}, NoWait: true}
+	if conn, err = gateway.GetEndpoint(context.Background(), selection); err == nil {
+		t.Fatalf("Expected restricted endpoints to not be paired with requesters of other dataspaces, got %s", conn.GetAddress())
+	}
+	if restricted, _ := gateway.DetachRestricted([]string{"/dataspace/1"}); len(restricted) != 0 {
+		t.Fatalf("Expected endpoints of other dataspaces to stay, got %v", restricted)
+	}
+
+	restricted, err := gateway.DetachRestricted([]string{"/dataspace/0"})
+	fmt.Printf("\tEndpoints detached with their dataspace: %d\n", len(restricted))
+	if err != nil || len(restricted) != 2 || gateway.GetTotalEndpoints() != 0 {
+		t.Fatalf("Expected the pinned and legacy endpoints to follow their dataspace, got %v", restricted)
+	}
+	if err = other.AdoptRestricted(restricted); err != nil || other.GetTotalEndpoints() != 3 {
+		t.Fatalf("Expected the restricted endpoints to be adopted by another swarm")
+	}
+	if detached, _ = other.DetachEndpoints(-1); len(detached) != 1 || detached[0].GetAddress() != "/address/free" {
+		t.Fatalf("Expected the adopted endpoints to stay pinned or legacy")
+	}
+	if _, err = other.GetEndpoint(context.Background(), selection); err == nil {
+		t.Fatalf("Expected the adopted endpoints to stay restricted to their dataspace")
+	}
+
+	//A pinned endpoint checked out while its dataspace moves is closed once released
+	conn, _ = other.GetEndpoint(context.Background(), manager.Selection{})
+	if restricted, _ = other.DetachRestricted([]string{"/dataspace/0"}); len(restricted) != 1 {
+		t.Fatalf("Expected the idle restricted endpoint to be detached, got %v", restricted)
+	}
+	other.ReleaseEndpoint(conn)
+	if !conn.(Conn).IsClosed() || other.GetTotalEndpoints() != 0 {
+		t.Fatalf("Expected the checked out endpoint to be closed once released")
+	}
 }
 
 func TestActiveConnectionQueue(t *testing.T) {
@@ -118,3 +521,77 @@ func (fc *FakeConn) Write([]byte) (int, error) { return 0, nil }
 func (fc *FakeConn) Close() error              { fc.closed = true; return nil }
 func (fc *FakeConn) IsClosed() bool            { return fc.closed }
 func (fc *FakeConn) GetAddress() string        { return fc.addr }
+func (fc *FakeConn) GetEndpointID() string     { return fc.addr }
+
+type DescribedConn struct {
+	FakeConn
+	metadata metadata.Metadata
+}
+
+func (dc *DescribedConn) SetMetadata(m metadata.Metadata) { dc.metadata = m }
+func (dc *DescribedConn) GetMetadata() metadata.Metadata  { return dc.metadata }
+
+//FakeRegistry predicts the round trip time from any requester to each endpoint
+type FakeRegistry struct {
+	rtts map[string]time.Duration
+}
+
+func (fr *FakeRegistry) Observe(string, string, time.Duration) {}
+func (fr *FakeRegistry) Estimate(requester string, endpoint string) (time.Duration, bool) {
+	rtt, ok := fr.rtts[endpoint]
+	return rtt, ok
+}
+
+//MigratableConn is a FakeConn that negotiated the Migration capability
+type MigratableConn struct {
+	FakeConn
+}
+
+func (mc *MigratableConn) GetProtocolVersion() int32 { return protocol.CurrentVersion }
+func (mc *MigratableConn) GetCapabilities() uint64 {
+	return protocol.CapabilityNotices | protocol.CapabilityMigration
+}
+
+type PipeConn struct {
+	net.Conn
+	addr   string
+	closed bool
+}
+
+func (pc *PipeConn) Close() error              { pc.closed = true; return pc.Conn.Close() }
+func (pc *PipeConn) IsClosed() bool            { return pc.closed }
+func (pc *PipeConn) GetAddress() string        { return pc.addr }
+func (pc *PipeConn) GetProtocolVersion() int32 { return protocol.CurrentVersion }
+func (pc *PipeConn) GetCapabilities() uint64 {
+	return protocol.CapabilityNotices | protocol.CapabilityHeartbeat
+}
+
+type FakeNotice struct {
+	noticeType int32
+	reason     string
+}
+
+func (fn *FakeNotice) GetType() int32          { return fn.noticeType }
+func (fn *FakeNotice) GetReason() string       { return fn.reason }
+func (fn *FakeNotice) GetPeer() string         { return "" }
+func (fn *FakeNotice) GetRtt() int64           { return 0 }
+func (fn *FakeNotice) GetDataspaces() []string { return nil }
+
+//answerPings plays an endpoint that answers every ping it receives
+func answerPings(conn net.Conn) {
+	for {
+		var size int32
+		if binary.Read(conn, binary.BigEndian, &size) != nil {
+			return
+		}
+		raw := make([]byte, size)
+		if _, err := io.ReadFull(conn, raw); err != nil {
+			return
+		}
+		reason := strings.SplitN(string(raw), ":", 2)[1]
+		err := responder.Notify(conn, responder.Notice{Type: responder.NoticePong, Reason: reason})
+		if err != nil {
+			return
+		}
+	}
+}
@@ -4,17 +4,19 @@ import "github.com/arstevens/go-hive-signal/internal/manager"
 
 /*SwarmGatewayGenerator implements manager.SwarmGatewayGenerator. It
 defines an object that can create new SwarmGateways with the provided
-activeSize and inactiveSize*/
+activeSize, SelectionPolicy and CoordinateRegistry*/
 type SwarmGatewayGenerator struct {
-	activeSize int
+	activeSize  int
+	policy      SelectionPolicy
+	coordinates CoordinateRegistry
 }
 
 //NewGenerator creates a new SwarmGatewayGenerator instance
-func NewGenerator(activeSize int) *SwarmGatewayGenerator {
-	return &SwarmGatewayGenerator{activeSize: activeSize}
+func NewGenerator(activeSize int, policy SelectionPolicy, coordinates CoordinateRegistry) *SwarmGatewayGenerator {
+	return &SwarmGatewayGenerator{activeSize: activeSize, policy: policy, coordinates: coordinates}
 }
 
 //New creates a new SwarmGateway instance
 func (sg *SwarmGatewayGenerator) New() manager.SwarmGateway {
-	return New(sg.activeSize)
+	return New(sg.activeSize, sg.policy, sg.coordinates)
 }
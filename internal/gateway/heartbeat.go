@@ -0,0 +1,238 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/manager"
+	"github.com/arstevens/go-hive-signal/internal/protocol"
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-hive-signal/internal/vivaldi"
+)
+
+/*HeartbeatInterval is the time between two pings of the idle endpoints
+of a gateway. Heartbeats are disabled when it is zero*/
+var HeartbeatInterval time.Duration = 0
+
+//HeartbeatTimeout is the time an endpoint is given to answer a ping
+var HeartbeatTimeout = time.Second * 5
+
+/*MaxMissedHeartbeats is the number of pings in a row an endpoint may
+leave unanswered before it is evicted from the swarm*/
+var MaxMissedHeartbeats = 3
+
+/*heartbeat pings the idle endpoints of the gateway every HeartbeatInterval
+until 'stop' is closed. 'stopped' is closed once it has returned*/
+func (sg *SwarmGateway) heartbeat(stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sg.pulse(ctx)
+		}
+	}
+}
+
+/*pulse pings every idle endpoint that accepts heartbeats along with the
+suspects that missed their last ping. Idle endpoints that do not accept
+heartbeats are only checked for a closed connection. Endpoints are checked
+out while they are pinged so that they are never paired mid ping*/
+func (sg *SwarmGateway) pulse(ctx context.Context) {
+	sg.aqMutex.Lock()
+	if sg.closed {
+		sg.aqMutex.Unlock()
+		return
+	}
+	pinged := make([]Conn, 0)
+	evicted := 0
+	for i, idle := 0, sg.activeQueue.GetSize(); i < idle; i++ {
+		conn := sg.activeQueue.Pop()
+		if conn.IsClosed() {
+			delete(sg.stats, conn)
+			evicted++
+		} else if acceptsHeartbeat(conn) {
+			sg.checkedOut[conn] = true
+			pinged = append(pinged, conn)
+		} else {
+			sg.activeQueue.Push(conn)
+		}
+	}
+	for conn := range sg.suspects {
+		pinged = append(pinged, conn.(Conn))
+	}
+	sg.aqMutex.Unlock()
+
+	results := make(chan bool, len(pinged))
+	wg := &sync.WaitGroup{}
+	for _, conn := range pinged {
+		wg.Add(1)
+		go func(conn Conn) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, HeartbeatTimeout)
+			defer cancel()
+			results <- sg.settle(conn, sg.ping(pingCtx, conn))
+		}(conn)
+	}
+	wg.Wait()
+	close(results)
+	for wasEvicted := range results {
+		if wasEvicted {
+			evicted++
+		}
+	}
+
+	if evicted > 0 {
+		log.Printf("Evicted %d unresponsive endpoints in SwarmGateway", evicted)
+		sg.notifyEviction()
+	}
+}
+
+/*settle returns a pinged endpoint to the swarm or records the missed ping.
+Endpoints that missed MaxMissedHeartbeats pings in a row are closed and
+removed from the swarm. It returns whether or not 'conn' was evicted*/
+func (sg *SwarmGateway) settle(conn Conn, pingErr error) bool {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	defer sg.available.Broadcast()
+
+	stats := sg.stats[conn]
+	if sg.closed {
+		delete(sg.checkedOut, conn)
+		delete(sg.suspects, conn)
+		delete(sg.stats, conn)
+		delete(sg.departing, conn)
+		closeEndpoint(conn)
+		return false
+	}
+	if sg.departing[conn] {
+		//The endpoint logged off while it was being pinged
+		delete(sg.checkedOut, conn)
+		delete(sg.suspects, conn)
+		delete(sg.stats, conn)
+		delete(sg.departing, conn)
+		conn.Close()
+		return false
+	}
+
+	if pingErr == nil {
+		stats.MissedHeartbeats = 0
+		delete(sg.checkedOut, conn)
+		delete(sg.suspects, conn)
+		err := sg.activeQueue.Push(conn)
+		if err != nil {
+			log.Printf("Failed to return endpoint %s in SwarmGateway: %v", conn.GetAddress(), err)
+		}
+		return false
+	}
+
+	stats.MissedHeartbeats++
+	if stats.MissedHeartbeats < MaxMissedHeartbeats && !conn.IsClosed() {
+		/*Suspects stay checked out because a late pong
+		would otherwise be read during a negotiation*/
+		sg.suspects[conn] = true
+		return false
+	}
+	delete(sg.checkedOut, conn)
+	delete(sg.suspects, conn)
+	delete(sg.stats, conn)
+	if !conn.IsClosed() {
+		conn.Close()
+	}
+	return true
+}
+
+//notifyEviction tells the owner of the gateway that endpoints were evicted
+func (sg *SwarmGateway) notifyEviction() {
+	sg.aqMutex.Lock()
+	onEviction := sg.onEviction
+	sg.aqMutex.Unlock()
+	if onEviction != nil {
+		onEviction()
+	}
+}
+
+/*ping sends a ping Notice to 'conn' and waits for the pong answering it.
+Pongs answering earlier pings are skipped. The round trip time of the ping
+and the latency reports the endpoint sends before its pong are recorded
+in the CoordinateRegistry if they are about requesters it was paired with*/
+func (sg *SwarmGateway) ping(ctx context.Context, conn Conn) error {
+	unbind := manager.BindContext(ctx, conn)
+	defer unbind()
+
+	nonce := strconv.FormatUint(rand.Uint64(), 36)
+	sent := time.Now()
+	err := responder.Notify(conn, responder.Notice{Type: responder.NoticePing, Reason: nonce})
+	if err != nil {
+		return fmt.Errorf("Failed to send ping in SwarmGateway.ping(): %v", err)
+	}
+	key := vivaldi.KeyOf(conn)
+	for {
+		notice, err := responder.ReadNotice(conn)
+		if err != nil {
+			return fmt.Errorf("Failed to read pong in SwarmGateway.ping(): %v", err)
+		}
+		if notice.Type == responder.NoticePong && notice.Reason == nonce {
+			if sg.coordinates != nil {
+				sg.coordinates.Observe(vivaldi.Server, key, time.Since(sent))
+			}
+			return nil
+		}
+		if notice.Type == responder.NoticeLatencyReport && sg.coordinates != nil && reportsLatency(conn) &&
+			sg.wasPairedWith(conn, notice.Peer) {
+			sg.coordinates.Observe(key, notice.Peer, notice.RTT)
+		}
+	}
+}
+
+//wasPairedWith returns whether or not 'conn' was recently paired with the requester keyed 'peer'
+func (sg *SwarmGateway) wasPairedWith(conn Conn, peer string) bool {
+	sg.aqMutex.Lock()
+	defer sg.aqMutex.Unlock()
+	stats, ok := sg.stats[conn]
+	return ok && stats.pairedWith(peer)
+}
+
+//acceptsNotices returns whether or not 'conn' negotiated the Notices capability
+func acceptsNotices(conn Conn) bool {
+	return hasCapability(conn, protocol.CapabilityNotices)
+}
+
+//acceptsHeartbeat returns whether or not 'conn' negotiated the Notices and Heartbeat capabilities
+func acceptsHeartbeat(conn Conn) bool {
+	return hasCapability(conn, protocol.CapabilityNotices|protocol.CapabilityHeartbeat)
+}
+
+//reportsLatency returns whether or not 'conn' negotiated the Notices and LatencyReports capabilities
+func reportsLatency(conn Conn) bool {
+	return hasCapability(conn, protocol.CapabilityNotices|protocol.CapabilityLatencyReports)
+}
+
+//acceptsMigration returns whether or not 'conn' negotiated the Notices and Migration capabilities
+func acceptsMigration(conn Conn) bool {
+	return hasCapability(conn, protocol.CapabilityNotices|protocol.CapabilityMigration)
+}
+
+//hasCapability returns whether or not 'conn' negotiated every bit of 'capabilities'
+func hasCapability(conn Conn, capabilities uint64) bool {
+	pConn, ok := conn.(responder.ProtocolConn)
+	return ok && pConn.GetCapabilities()&capabilities == capabilities
+}
@@ -1,8 +1,19 @@
 package gateway
 
-import "github.com/arstevens/go-hive-signal/internal/manager"
+import (
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/manager"
+)
 
 type Conn interface {
 	manager.Conn
 	IsClosed() bool
 }
+
+/*CoordinateRegistry predicts the round trip time between two nodes from
+the round trip times observed between nodes so far*/
+type CoordinateRegistry interface {
+	Observe(string, string, time.Duration)
+	Estimate(string, string) (time.Duration, bool)
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import "time"
+
+/*LatencyTolerance is how much further than the nearest endpoint another
+endpoint may be predicted to be from a requester and still be handed to
+the SelectionPolicy. It keeps the load spread among nearby endpoints*/
+var LatencyTolerance = time.Millisecond * 20
+
+/*MaxRecentPeers is the number of requesters remembered for each endpoint.
+Latency reports are only accepted about requesters an endpoint was
+recently paired with so it cannot move the Coordinates of other nodes*/
+var MaxRecentPeers = 32
+
+/*MaxSelectionCandidates bounds the number of idle endpoints fitting a
+Selection that are weighed each time an endpoint is checked out. Zero
+weighs every endpoint*/
+var MaxSelectionCandidates = 64
+
+/*nearest narrows 'idle' and the queue 'positions' of its endpoints down to
+those predicted within LatencyTolerance of the nearest one to 'requester'.
+Endpoints without a prediction are only kept when none has one*/
+func (sg *SwarmGateway) nearest(requester string, idle []*EndpointStats, positions []int) ([]*EndpointStats, []int) {
+	if sg.coordinates == nil || requester == "" {
+		return idle, positions
+	}
+
+	estimates := make([]time.Duration, len(idle))
+	known := make([]bool, len(idle))
+	best := time.Duration(-1)
+	for i, endpoint := range idle {
+		estimates[i], known[i] = sg.coordinates.Estimate(requester, endpoint.key)
+		if known[i] && (best < 0 || estimates[i] < best) {
+			best = estimates[i]
+		}
+	}
+	if best < 0 {
+		return idle, positions
+	}
+
+	nearIdle := make([]*EndpointStats, 0, len(idle))
+	nearPositions := make([]int, 0, len(idle))
+	for i, endpoint := range idle {
+		if known[i] && estimates[i] <= best+LatencyTolerance {
+			nearIdle = append(nearIdle, endpoint)
+			nearPositions = append(nearPositions, positions[i])
+		}
+	}
+	return nearIdle, nearPositions
+}
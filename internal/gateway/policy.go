@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/manager"
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+	"github.com/arstevens/go-hive-signal/internal/vivaldi"
+)
+
+/*ActivityHalfLife is how long it takes the recorded negotiation
+activity of an endpoint to decay to half its value*/
+var ActivityHalfLife = time.Minute
+
+/*EndpointStats holds what a SelectionPolicy knows about an idle
+endpoint of the swarm*/
+type EndpointStats struct {
+	Conn Conn
+	//Declared by the endpoint when it logged on
+	Metadata metadata.Metadata
+	//When the endpoint joined the swarm
+	Added time.Time
+	//When the endpoint was last released after a negotiation. Zero if never
+	LastPaired time.Time
+	//Number of negotiations the endpoint took part in
+	Negotiations int
+	//Preferred load reported in the latest debrief. 0 if unknown
+	PreferredLoad int
+	//Number of heartbeats in a row the endpoint left unanswered
+	MissedHeartbeats int
+	//Pinned endpoints are never checked out for a transfer to another swarm
+	Pinned bool
+	//Dataspaces the endpoint is restricted to. It serves every dataspace of the swarm if there are none
+	Dataspaces []string
+
+	//Key of the endpoint in the CoordinateRegistry
+	key string
+	//Keys of the requesters the endpoint was recently paired with
+	peers         []string
+	activity      float64
+	activitySince time.Time
+}
+
+func newEndpointStats(conn Conn) *EndpointStats {
+	now := time.Now()
+	return &EndpointStats{Conn: conn, Metadata: metadata.Of(conn), Added: now,
+		key: vivaldi.KeyOf(conn), activitySince: now}
+}
+
+//newRestrictedStats returns the stats of an endpoint joining with the restrictions of 'endpoint'
+func newRestrictedStats(endpoint manager.Restricted) *EndpointStats {
+	stats := newEndpointStats(endpoint.Conn.(Conn))
+	stats.Pinned = endpoint.Pinned
+	stats.Dataspaces = endpoint.Dataspaces
+	return stats
+}
+
+/*GetActivity returns the number of recent negotiations of the endpoint
+with older negotiations decayed according to ActivityHalfLife*/
+func (es *EndpointStats) GetActivity(now time.Time) float64 {
+	elapsed := now.Sub(es.activitySince)
+	return es.activity * math.Pow(0.5, float64(elapsed)/float64(ActivityHalfLife))
+}
+
+/*recordPeer remembers that the endpoint was paired with the requester
+keyed 'peer'. Only the latest MaxRecentPeers requesters are kept*/
+func (es *EndpointStats) recordPeer(peer string) {
+	if peer == "" {
+		return
+	}
+	es.peers = append(es.peers, peer)
+	if len(es.peers) > MaxRecentPeers {
+		es.peers = es.peers[len(es.peers)-MaxRecentPeers:]
+	}
+}
+
+//pairedWith returns whether or not the endpoint was recently paired with 'peer'
+func (es *EndpointStats) pairedWith(peer string) bool {
+	for _, recent := range es.peers {
+		if recent == peer {
+			return true
+		}
+	}
+	return false
+}
+
+//recordNegotiation marks that the endpoint just finished a negotiation
+func (es *EndpointStats) recordNegotiation(now time.Time) {
+	es.activity = es.GetActivity(now) + 1
+	es.activitySince = now
+	es.LastPaired = now
+	es.Negotiations++
+}
+
+/*SelectionPolicy decides which idle endpoint is checked out next. Select
+is given the idle endpoints from the longest idle to the most recently
+released and returns the index of the one to check out*/
+type SelectionPolicy interface {
+	Select(idle []*EndpointStats) int
+}
+
+//Policies maps the configuration name of each SelectionPolicy
+var Policies = map[string]SelectionPolicy{
+	"RoundRobin":          RoundRobin{},
+	"Random":              Random{},
+	"LeastRecentlyPaired": LeastRecentlyPaired{},
+	"LeastActive":         LeastActive{},
+	"WeightedLoad":        WeightedLoad{},
+}
+
+//RoundRobin checks out the endpoint that has been idle the longest
+type RoundRobin struct{}
+
+func (RoundRobin) Select(idle []*EndpointStats) int {
+	return 0
+}
+
+//Random checks out an idle endpoint uniformly at random
+type Random struct{}
+
+func (Random) Select(idle []*EndpointStats) int {
+	return rand.Intn(len(idle))
+}
+
+/*LeastRecentlyPaired checks out the endpoint whose last negotiation
+ended the longest ago. Endpoints that were never paired come first*/
+type LeastRecentlyPaired struct{}
+
+func (LeastRecentlyPaired) Select(idle []*EndpointStats) int {
+	selected := 0
+	for i, endpoint := range idle {
+		if endpoint.LastPaired.Before(idle[selected].LastPaired) {
+			selected = i
+		}
+	}
+	return selected
+}
+
+/*LeastActive checks out the endpoint that took part in the fewest
+negotiations recently. See EndpointStats.GetActivity*/
+type LeastActive struct{}
+
+func (LeastActive) Select(idle []*EndpointStats) int {
+	now := time.Now()
+	selected := 0
+	lowest := idle[0].GetActivity(now)
+	for i, endpoint := range idle {
+		if activity := endpoint.GetActivity(now); activity < lowest {
+			selected, lowest = i, activity
+		}
+	}
+	return selected
+}
+
+/*WeightedLoad checks out endpoints at random, weighted by the preferred
+load they reported in their latest debrief. Endpoints that never reported
+a preferred load are weighted as the average of those that did*/
+type WeightedLoad struct{}
+
+func (WeightedLoad) Select(idle []*EndpointStats) int {
+	total, reported := 0, 0
+	for _, endpoint := range idle {
+		if endpoint.PreferredLoad > 0 {
+			total += endpoint.PreferredLoad
+			reported++
+		}
+	}
+	if reported == 0 {
+		return rand.Intn(len(idle))
+	}
+	average := total / reported
+	if average == 0 {
+		average = 1
+	}
+	total += average * (len(idle) - reported)
+
+	target := rand.Intn(total)
+	for i, endpoint := range idle {
+		weight := endpoint.PreferredLoad
+		if weight <= 0 {
+			weight = average
+		}
+		if target < weight {
+			return i
+		}
+		target -= weight
+	}
+	return len(idle) - 1
+}
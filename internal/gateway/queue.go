@@ -87,3 +87,24 @@ func (aq *activeConnectionQueue) Pop() Conn {
 
 	return c
 }
+
+//At returns the entry 'i' places from the head of the queue
+func (aq *activeConnectionQueue) At(i int) Conn {
+	return aq.queue[(aq.head+i)%len(aq.queue)]
+}
+
+/*RemoveAt removes and returns the entry 'i' places from the head of the
+queue. Entries ahead of it keep their order*/
+func (aq *activeConnectionQueue) RemoveAt(i int) Conn {
+	if i < 0 || i >= aq.size {
+		return nil
+	}
+	c := aq.At(i)
+	for j := i; j > 0; j-- {
+		aq.queue[(aq.head+j)%len(aq.queue)] = aq.queue[(aq.head+j-1)%len(aq.queue)]
+	}
+	aq.queue[aq.head] = nil
+	aq.head = (aq.head + 1) % len(aq.queue)
+	aq.size--
+	return c
+}
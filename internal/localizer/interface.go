@@ -1,9 +1,16 @@
 package localizer
 
+import (
+	"context"
+
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+)
+
 /*SwarmManager defines an object that can process a request given a dataspace
 and a connection the the requester*/
 type SwarmManager interface {
-	AttemptToPair(conn interface{}) error
+	//Pairs with up to 'peers' distinct endpoints of the dataspace and returns how many were paired
+	AttemptToPairMany(ctx context.Context, conn interface{}, dataspace string, requirements metadata.Requirements, peers int) (int, error)
 	GetID() string
 }
 
@@ -14,9 +21,9 @@ type SwarmMap interface {
 }
 
 /*FrequencyTracker defines an object that needs to be informed when a new request
-comes in in order to keep track of the frequency of requests per dataspace and swarm*/
+comes in in order to keep track of the frequency of requests per swarm*/
 type FrequencyTracker interface {
-	IncrementFrequencyCounter(dataspace string)
+	IncrementFrequencyCounter(swarmID string)
 }
 
 /*LocalizeRequest defines an object that holds the information needed for the localizer
@@ -24,3 +31,15 @@ to properly identify where to sent the request for processing*/
 type LocalizeRequest interface {
 	GetDataspace() string
 }
+
+/*ConstrainedRequest is a LocalizeRequest that restricts the endpoints it may be
+paired with. GetRequirements returns nil if the request has no requirements*/
+type ConstrainedRequest interface {
+	GetRequirements() interface{}
+}
+
+/*FanOutRequest is a LocalizeRequest that asks to be paired with several
+endpoints at once. GetPeers returns 0 or 1 for a single endpoint*/
+type FanOutRequest interface {
+	GetPeers() int32
+}
@@ -1,34 +1,76 @@
 package localizer
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/admission"
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	"github.com/arstevens/go-request/handle"
 )
 
+/*RequestTimeout bounds the time from admitting a request to pairing
+its requester. A RequestTimeout of 0 leaves requests unbounded*/
+var RequestTimeout time.Duration = 0
+
+/*MaxPeers bounds the number of endpoints a single requester may be
+paired with. Requests asking for more are paired with MaxPeers*/
+var MaxPeers = 5
+
 //RequestLocalizer routes requests to their right swarm
 type RequestLocalizer struct {
 	closed        bool
-	requestStream chan<- handle.RequestPair
+	ctx           context.Context
+	cancel        context.CancelFunc
+	mutex         *sync.RWMutex
+	workers       *sync.WaitGroup
+	overflow      admission.Overflow
+	requestStream chan handle.RequestPair
 }
 
-//New creates a new instance of RequestLocalizer with a job queue of capacity 'size'
-func New(size int, managers SwarmMap, tracker FrequencyTracker) *RequestLocalizer {
+/*New creates a new instance of RequestLocalizer with a job queue of capacity 'size'
+that admits jobs following 'overflow' once it is full. The queue is drained by
+'workers' concurrent goroutines, at least one*/
+func New(size int, workers int, overflow admission.Overflow, managers SwarmMap, tracker FrequencyTracker) *RequestLocalizer {
 	requestStream := make(chan handle.RequestPair, size)
-	go processRequestStream(requestStream, managers, tracker)
+	ctx, cancel := context.WithCancel(context.Background())
+	workerGroup := &sync.WaitGroup{}
+	for i := 0; i < workers || i == 0; i++ {
+		workerGroup.Add(1)
+		go func() {
+			defer workerGroup.Done()
+			processRequestStream(ctx, requestStream, managers, tracker)
+		}()
+	}
 	return &RequestLocalizer{
 		closed:        false,
+		ctx:           ctx,
+		cancel:        cancel,
+		mutex:         &sync.RWMutex{},
+		workers:       workerGroup,
+		overflow:      overflow,
 		requestStream: requestStream,
 	}
 }
 
-//AddJob adds a request and connection to the queue for processing
+/*AddJob adds a request and connection to the queue for processing. A full
+queue is handled according to the overflow policy of the RequestLocalizer*/
 func (rl *RequestLocalizer) AddJob(request interface{}, conn handle.Conn) error {
+	pair := handle.RequestPair{Request: admission.NewJob(request), Conn: conn}
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
 	if rl.closed {
+		go admission.TurnAway(pair, rl.overflow.RetryAfter)
 		return fmt.Errorf("Cannot add a job on a closed RequestLocalizer")
 	}
-	rl.requestStream <- handle.RequestPair{Request: request, Conn: conn}
+	err := admission.Admit(rl.requestStream, pair, rl.overflow)
+	if err != nil {
+		return fmt.Errorf("Failed to add job to RequestLocalizer: %v", err)
+	}
 	return nil
 }
 
@@ -44,39 +86,91 @@ func (rl *RequestLocalizer) QueuedJobs() int {
 
 //Close closes the RequestLocalizer
 func (rl *RequestLocalizer) Close() error {
-	if !rl.closed {
-		close(rl.requestStream)
-		rl.closed = true
-		return nil
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	if rl.closed {
+		return fmt.Errorf("Cannot close a closed RequestLocalizer")
 	}
-	return fmt.Errorf("Cannot close a closed RequestLocalizer")
+	rl.closed = true
+	close(rl.requestStream)
+	return nil
 }
 
-func processRequestStream(requestStream <-chan handle.RequestPair, managers SwarmMap, tracker FrequencyTracker) {
+/*Shutdown closes the RequestLocalizer and handles the jobs left in its queue if
+'drain' is set or turns them away as busy otherwise. It then waits up to
+'timeout' for the jobs being handled to finish before cancelling them*/
+func (rl *RequestLocalizer) Shutdown(drain bool, timeout time.Duration) error {
+	err := rl.Close()
+	if err != nil {
+		return err
+	}
+	if !drain {
+		admission.Flush(rl.requestStream, rl.overflow.RetryAfter)
+	}
+	err = admission.Wait(rl.workers, timeout)
+	if err != nil {
+		//Abandon the jobs still being handled
+		rl.cancel()
+		return fmt.Errorf("Failed to shut down RequestLocalizer: %v", err)
+	}
+	rl.cancel()
+	return nil
+}
+
+func processRequestStream(ctx context.Context, requestStream <-chan handle.RequestPair, managers SwarmMap, tracker FrequencyTracker) {
 	for {
 		requestPair, ok := <-requestStream
 		if !ok {
 			return
 		}
-		localizeRequest := requestPair.Request.(LocalizeRequest)
+		job := requestPair.Request.(admission.Job)
+		localizeRequest := job.Request.(LocalizeRequest)
 
-		err := handleLocalizeRequest(localizeRequest.GetDataspace(), requestPair.Conn, managers, tracker)
+		jobCtx, cancel := job.Context(ctx, RequestTimeout)
+		paired, err := handleLocalizeRequest(jobCtx, localizeRequest, requestPair.Conn, managers, tracker)
+		cancel()
 		if err != nil {
 			log.Println(err)
+			err = responder.Failure(requestPair.Conn, err, responder.InternalError)
+		} else {
+			err = responder.Paired(requestPair.Conn, int32(paired))
 		}
+		if err != nil {
+			log.Printf("Failed to respond to requester in RequestLocalizer: %v", err)
+		}
+		requestPair.Conn.Close()
 	}
 }
 
-func handleLocalizeRequest(dataspace string, conn handle.Conn, managers SwarmMap, tracker FrequencyTracker) error {
+/*handleLocalizeRequest pairs the requester on 'conn' with the swarm of the
+requested dataspace and returns the number of endpoints it was paired with*/
+func handleLocalizeRequest(ctx context.Context, request LocalizeRequest, conn handle.Conn, managers SwarmMap, tracker FrequencyTracker) (int, error) {
+	dataspace := request.GetDataspace()
+	requirements := metadata.Requirements{}
+	if cRequest, ok := request.(ConstrainedRequest); ok {
+		requirements = metadata.RequirementsFromMessage(cRequest.GetRequirements())
+	}
+	peers := 1
+	if fRequest, ok := request.(FanOutRequest); ok && fRequest.GetPeers() > 1 {
+		peers = int(fRequest.GetPeers())
+		if peers > MaxPeers {
+			peers = MaxPeers
+		}
+	}
+
 	swarmManagerObj, err := managers.GetSwarm(dataspace)
 	if err != nil {
-		return fmt.Errorf("Failed to get SwarmManager from SwarmMap in RequestLocalizer: %v", err)
+		return 0, fmt.Errorf("Failed to get SwarmManager from SwarmMap in RequestLocalizer: %w", err)
 	}
+	/*Count the request before pairing so that demand is still recorded
+	when the swarm has no endpoints to offer. Load is counted per swarm
+	since a swarm may serve several dataspaces*/
 	swarmManager := swarmManagerObj.(SwarmManager)
-	err = swarmManager.AttemptToPair(conn)
+	tracker.IncrementFrequencyCounter(swarmManager.GetID())
+
+	paired, err := swarmManager.AttemptToPairMany(ctx, conn, dataspace, requirements, peers)
 	if err != nil {
-		return fmt.Errorf("Failed to pair to swarm in RequestLocalizer: %v", err)
+		return 0, fmt.Errorf("Failed to pair to swarm in RequestLocalizer: %w", err)
 	}
-	tracker.IncrementFrequencyCounter(dataspace)
-	return nil
+	return paired, nil
 }
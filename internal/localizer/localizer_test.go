@@ -1,10 +1,14 @@
 package localizer
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
 	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/admission"
+	"github.com/arstevens/go-hive-signal/internal/metadata"
 )
 
 func TestLocalizer(t *testing.T) {
@@ -26,13 +30,46 @@ func TestLocalizer(t *testing.T) {
 	fconn := FakeConn{}
 
 	queueSize := 3
-	rlocalizer := New(queueSize, &smap, &ftrack)
+	rlocalizer := New(queueSize, 1, admission.Overflow{}, &smap, &ftrack)
 	for _, request := range requests {
 		rlocalizer.AddJob(&LocalizeRequestTest{
 			d: request.GetDataspace(),
 		}, &fconn)
 	}
 	time.Sleep(time.Second)
+
+	err := rlocalizer.Shutdown(true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rlocalizer.AddJob(&LocalizeRequestTest{d: "/dataspace/0"}, &fconn) == nil {
+		t.Fatalf("Shut down RequestLocalizer should refuse new jobs")
+	}
+	if rlocalizer.Shutdown(true, time.Second) == nil {
+		t.Fatalf("Shutting down twice should fail")
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	smap := SwarmMapTest{smap: map[string]SwarmManager{"/dataspace/0": &SwarmManagerTest{id: "/dataspace/0"}}}
+	ftrack := FrequencyTrackerTest{fmap: make(map[string]int)}
+
+	for _, peers := range []int32{0, 3, int32(MaxPeers) + 4} {
+		request := &LocalizeRequestTest{d: "/dataspace/0", peers: peers}
+		paired, err := handleLocalizeRequest(context.Background(), request, &FakeConn{}, &smap, &ftrack)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := int(peers)
+		if expected < 1 {
+			expected = 1
+		} else if expected > MaxPeers {
+			expected = MaxPeers
+		}
+		if paired != expected {
+			t.Fatalf("Expected a request for %d peers to be paired with %d, got %d", peers, expected, paired)
+		}
+	}
 }
 
 type FakeConn struct{}
@@ -58,9 +95,9 @@ type SwarmManagerTest struct {
 	id string
 }
 
-func (sm *SwarmManagerTest) AttemptToPair(conn interface{}) error {
-	fmt.Printf("%s: Attempting to pair\n", sm.id)
-	return nil
+func (sm *SwarmManagerTest) AttemptToPairMany(ctx context.Context, conn interface{}, dataspace string, requirements metadata.Requirements, peers int) (int, error) {
+	fmt.Printf("%s: Attempting to pair with %d peers of %s\n", sm.id, peers, dataspace)
+	return peers, nil
 }
 
 func (sm *SwarmManagerTest) GetID() string {
@@ -71,13 +108,15 @@ type FrequencyTrackerTest struct {
 	fmap map[string]int
 }
 
-func (ft *FrequencyTrackerTest) IncrementFrequencyCounter(dataspace string) {
-	ft.fmap[dataspace]++
-	fmt.Printf("Swarm %s incremented to %d\n", dataspace, ft.fmap[dataspace])
+func (ft *FrequencyTrackerTest) IncrementFrequencyCounter(swarmID string) {
+	ft.fmap[swarmID]++
+	fmt.Printf("Swarm %s incremented to %d\n", swarmID, ft.fmap[swarmID])
 }
 
 type LocalizeRequestTest struct {
-	d string
+	d     string
+	peers int32
 }
 
 func (lt *LocalizeRequestTest) GetDataspace() string { return lt.d }
+func (lt *LocalizeRequestTest) GetPeers() int32      { return lt.peers }
@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*binding is the deadline a context bound to a conn. Bindings without a
+deadline leave the deadline of the binding they are nested in in place*/
+type binding struct {
+	deadline    time.Time
+	hasDeadline bool
+}
+
+/*bindings holds the bindings of every conn bound by BindContext, the
+innermost last, so that unbinding restores the deadline of the outer one*/
+var bindings = make(map[DeadlineConn][]*binding)
+var bindingsMutex = &sync.Mutex{}
+
+/*BindContext ties the I/O on 'conns' to 'ctx'. Blocked reads and writes
+fail once the deadline of 'ctx' passes or it is cancelled. Conns that
+cannot take deadlines are closed instead when 'ctx' is done. The returned
+function unbinds the conns and must be called once the I/O is finished.
+Bindings may be nested. Unbinding restores the deadline of the binding
+the conns were bound by before*/
+func BindContext(ctx context.Context, conns ...Conn) func() {
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline && ctx.Done() == nil {
+		return func() {}
+	}
+	b := &binding{deadline: deadline, hasDeadline: hasDeadline}
+	pushBinding(conns, b)
+	if ctx.Done() == nil {
+		return func() { popBinding(conns, b) }
+	}
+
+	unbound := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		select {
+		case <-ctx.Done():
+			expireBinding(conns, b)
+		case <-unbound:
+		}
+	}()
+	return func() {
+		close(unbound)
+		<-finished
+		popBinding(conns, b)
+	}
+}
+
+func pushBinding(conns []Conn, b *binding) {
+	bindingsMutex.Lock()
+	defer bindingsMutex.Unlock()
+	for _, conn := range conns {
+		if dConn, ok := conn.(DeadlineConn); ok {
+			bindings[dConn] = append(bindings[dConn], b)
+			if b.hasDeadline {
+				dConn.SetDeadline(b.deadline)
+			}
+		}
+	}
+}
+
+//expireBinding fails the blocked I/O on 'conns' once the context of 'b' is done
+func expireBinding(conns []Conn, b *binding) {
+	bindingsMutex.Lock()
+	defer bindingsMutex.Unlock()
+	b.deadline, b.hasDeadline = time.Now(), true
+	for _, conn := range conns {
+		if dConn, ok := conn.(DeadlineConn); ok {
+			dConn.SetDeadline(b.deadline)
+		} else {
+			conn.Close()
+		}
+	}
+}
+
+func popBinding(conns []Conn, b *binding) {
+	bindingsMutex.Lock()
+	defer bindingsMutex.Unlock()
+	for _, conn := range conns {
+		dConn, ok := conn.(DeadlineConn)
+		if !ok {
+			continue
+		}
+		stack := bindings[dConn]
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i] == b {
+				stack = append(stack[:i], stack[i+1:]...)
+				break
+			}
+		}
+		if len(stack) == 0 {
+			delete(bindings, dConn)
+		} else {
+			bindings[dConn] = stack
+		}
+		dConn.SetDeadline(deadlineOf(stack))
+	}
+}
+
+//deadlineOf returns the deadline of the innermost binding in 'stack' that has one
+func deadlineOf(stack []*binding) time.Time {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].hasDeadline {
+			return stack[i].deadline
+		}
+	}
+	return time.Time{}
+}
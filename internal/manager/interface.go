@@ -1,17 +1,81 @@
 package manager
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+)
 
 /*SwarmGateway returns a connection object to an endpoint
 in a designated swarm*/
 type SwarmGateway interface {
 	PushEndpoint(Conn) error
-	//Returns the connection, a debrief object, and an error
-	GetEndpoint() (Conn, error)
+	//Pushes an endpoint that only serves the dataspace it is pinned to and is never checked out for a transfer
+	PushPinnedEndpoint(Conn, string) error
+	//Checks out an endpoint fitting the Selection until it is released or detached
+	GetEndpoint(context.Context, Selection) (Conn, error)
+	ReleaseEndpoint(Conn) error
+	//Removes exactly n idle endpoints that can migrate or none at all. Every such endpoint if n is negative
+	DetachEndpoints(int) ([]Conn, error)
+	//Returns the number of idle endpoints DetachEndpoints can remove
+	GetMovableEndpoints() int
+	//Limits every endpoint that serves all dataspaces of the swarm to the dataspaces given
+	RestrictEndpoints([]string)
+	//Removes every idle endpoint that cannot migrate and only serves dataspaces among those given
+	DetachRestricted([]string) ([]Restricted, error)
+	//Adds all endpoints detached from another swarm or none at all. They serve every dataspace of the swarm
+	AdoptEndpoints([]Conn) error
+	//Adds all restricted endpoints detached from another swarm or none at all. They keep their restrictions
+	AdoptRestricted([]Restricted) error
+	//Removes and closes the endpoint that was issued the identity when it logged on
+	RemoveEndpoint(string) error
+	//Records the debrief an endpoint sent before its latest negotiation
+	RecordDebrief(Conn, interface{})
+	//Registers a function called after unresponsive endpoints were evicted
+	OnEviction(func())
+	//Registers a function called with the number of requesters waiting for an endpoint or that gave up since one last joined
+	OnWaiting(func(int))
 	GetTotalEndpoints() int
 	io.Closer
 }
 
+/*Selection narrows down the endpoints a SwarmGateway may check out. The
+zero value lets it check out any endpoint*/
+type Selection struct {
+	Requirements metadata.Requirements
+	//Key of the requester the endpoint is for. Endpoints closer to it are preferred
+	Requester string
+	//Endpoints that already failed the requester and must not be checked out
+	Exclude []Conn
+	//Fail at once if the swarm has no endpoints instead of waiting for one to join
+	NoWait bool
+	//Dataspaces the endpoint must hold the state of. Any endpoint fits if there are none
+	Dataspaces []string
+}
+
+//Excludes returns whether or not 'conn' may not be checked out for the Selection
+func (s Selection) Excludes(conn Conn) bool {
+	for _, excluded := range s.Exclude {
+		if excluded == conn {
+			return true
+		}
+	}
+	return false
+}
+
+/*Restricted is an endpoint that only serves some of the dataspaces of its
+swarm. Endpoints are restricted when they are pinned to a dataspace or when
+their swarm gains dataspaces they cannot be told about*/
+type Restricted struct {
+	Conn Conn
+	//Dataspaces the endpoint holds the state of
+	Dataspaces []string
+	//Pinned endpoints are never transferred to another swarm
+	Pinned bool
+}
+
 //An object that can create new SwarmGateways
 type SwarmGatewayGenerator interface {
 	New() SwarmGateway
@@ -21,16 +85,33 @@ type SwarmGatewayGenerator interface {
 type SwarmInfoTracker interface {
 	AddDebriefDatapoint(string, interface{})
 	SetSize(string, int)
+	//Records the number of requesters waiting for an endpoint of the swarm
+	SetWaiting(string, int)
 	Delete(string)
 }
 
 /*AgentNegotiator takes in two connection objects(the offerer
   and the acceptor) and passes session descriptions between them
-  until the acceptor accepts the session*/
-type AgentNegotiator func(Conn, Conn) error
+  until the acceptor accepts the session or the context is done*/
+type AgentNegotiator func(context.Context, Conn, Conn) error
+
+/*OffererError is returned by an AgentNegotiator when the negotiation
+failed because of the offerer. The acceptor may then be paired with
+another offerer*/
+type OffererError struct {
+	Err error
+}
+
+func (e *OffererError) Error() string { return e.Err.Error() }
+func (e *OffererError) Unwrap() error { return e.Err }
 
 //Conn represents a connection to an endpoint
 type Conn interface {
 	GetAddress() string
 	io.ReadWriteCloser
 }
+
+//DeadlineConn describes a connection whose blocked I/O can be timed out
+type DeadlineConn interface {
+	SetDeadline(time.Time) error
+}
@@ -1,141 +1,521 @@
 package manager
 
 import (
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+	"github.com/arstevens/go-hive-signal/internal/protocol"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	"github.com/arstevens/go-hive-signal/internal/transmuter"
+	"github.com/arstevens/go-hive-signal/internal/vivaldi"
 )
 
 var ChangeTriggerLimit int = 20
-var OperationSuccess byte = 1
 var DebriefProcedure func(io.Reader) interface{} = nil
 
+/*MigrationTimeout bounds the time an endpoint moved to another swarm is
+given to acknowledge the move and retrieve the state of its new swarm*/
+var MigrationTimeout = time.Second * 10
+
+/*PairingRetries is the number of other offerers a requester is
+paired with after its offerer failed during negotiation*/
+var PairingRetries = 2
+
+/*PairingRetryBudget bounds the total time spent pairing a requester
+once its first offerer failed*/
+var PairingRetryBudget = time.Second * 5
+
+//ResetReason is sent to requesters along with the NoticeReset preceding a retry
+var ResetReason = "Endpoint failed during negotiation"
+
 /*SwarmManager is an object that can be used to connect new requesters
-to a peer-to-peer swarm*/
+to a peer-to-peer swarm. It is safe for concurrent use*/
 type SwarmManager struct {
-	gateway   SwarmGateway
-	negotiate AgentNegotiator
-	tracker   SwarmInfoTracker
-	closed    bool
-	id        string
-	changes   int
+	gateway    SwarmGateway
+	negotiate  AgentNegotiator
+	tracker    SwarmInfoTracker
+	mutex      *sync.Mutex
+	closed     bool
+	id         string
+	dataspaces []string
+	changes    int
 }
 
 //New creates a new SwarmManager
 func New(swarmID string, gateway SwarmGateway, negotiate AgentNegotiator, tracker SwarmInfoTracker) *SwarmManager {
 	tracker.SetSize(swarmID, gateway.GetTotalEndpoints())
-	return &SwarmManager{
-		gateway:   gateway,
-		negotiate: negotiate,
-		tracker:   tracker,
-		closed:    false,
-		id:        swarmID,
-		changes:   0,
+	sm := &SwarmManager{
+		gateway:    gateway,
+		negotiate:  negotiate,
+		tracker:    tracker,
+		mutex:      &sync.Mutex{},
+		closed:     false,
+		id:         swarmID,
+		dataspaces: []string{swarmID},
+		changes:    0,
 	}
+	gateway.OnEviction(sm.updateSize)
+	gateway.OnWaiting(func(waiting int) { tracker.SetWaiting(swarmID, waiting) })
+	return sm
 }
 
-//AttemptToPair attempts to pair 'conn' with someone from the swarm
-func (sm *SwarmManager) AttemptToPair(conn interface{}) error {
-	if sm.closed {
-		return fmt.Errorf("Failed to attempt pair in SwarmManager.AttemptToPair(). Object closed")
+/*AttemptToPair attempts to pair 'conn' with someone from the swarm meeting
+'requirements'. The attempt is abandoned once 'ctx' is done. The caller
+remains responsible for closing 'conn'*/
+func (sm *SwarmManager) AttemptToPair(ctx context.Context, conn interface{}, requirements metadata.Requirements) error {
+	_, err := sm.AttemptToPairMany(ctx, conn, "", requirements, 1)
+	return err
+}
+
+/*AttemptToPairMany pairs 'conn' with up to 'peers' distinct endpoints
+holding the state of 'dataspace' and meeting 'requirements' one after the
+other. An empty 'dataspace' pairs with any endpoint. If more than one peer is
+asked for each negotiation is preceded by a NoticePeer carrying the
+index of the peer so the requester can tell the negotiations apart.
+Requesters that did not negotiate the Notices capability are paired with
+a single peer. Pairing stops at the first peer that cannot be paired. The number of
+peers paired is returned and an error only if none were*/
+func (sm *SwarmManager) AttemptToPairMany(ctx context.Context, conn interface{}, dataspace string, requirements metadata.Requirements, peers int) (int, error) {
+	if sm.isClosed() {
+		return 0, fmt.Errorf("Failed to attempt pair in SwarmManager.AttemptToPair(). Object closed")
 	}
 
 	acceptorConn, ok := conn.(Conn)
 	if !ok {
-		return fmt.Errorf("Failed to pair in SwarmManager.AttemptToPair(). 'conn' does not conform to Conn interface")
+		return 0, fmt.Errorf("Failed to pair in SwarmManager.AttemptToPair(). 'conn' does not conform to Conn interface")
 	}
-	defer acceptorConn.Close()
 
-	offerer, err := sm.gateway.GetEndpoint()
-	if err != nil {
-		log.Printf("Failed to pair in SwarmManager.AttemptToPair(): %v", err)
-		return nil
+	if !acceptsNotices(acceptorConn) {
+		peers = 1
 	}
-
-	debrief := DebriefProcedure(offerer)
-	if debrief != nil {
-		sm.tracker.AddDebriefDatapoint(sm.id, debrief)
+	selection := Selection{Requirements: requirements, Requester: vivaldi.KeyOf(acceptorConn)}
+	if dataspace != "" {
+		selection.Dataspaces = []string{dataspace}
 	}
+	paired := 0
+	for ; paired < peers || paired == 0; paired++ {
+		var err error
+		if peers > 1 {
+			err = responder.Notify(acceptorConn, responder.Notice{Type: responder.NoticePeer, Reason: strconv.Itoa(paired)})
+		}
+		var offerer Conn
+		if err == nil {
+			offerer, err = sm.pairWithRetries(ctx, acceptorConn, &selection)
+		}
+		if err != nil && paired == 0 {
+			return 0, err
+		} else if err != nil {
+			log.Printf("Paired %d of %d peers in SwarmManager.AttemptToPairMany(): %v", paired, peers, err)
+			return paired, nil
+		}
+		//Every peer of a requester is a distinct endpoint
+		selection.Exclude = append(selection.Exclude, offerer)
+	}
+	return paired, nil
+}
 
-	offererConn, ok := offerer.(Conn)
-	if !ok {
-		return fmt.Errorf("Failed to pair in SwarmManager.AttemptToPair(). offerer 'conn' does not conform to Conn interface")
+/*pairWithRetries pairs 'acceptor' with one offerer. Offerers that fail
+during negotiation are added to the exclusions of 'selection' and another
+one is tried until PairingRetries or PairingRetryBudget run out. Each retry
+is preceded by a NoticeReset so that the requester drops whatever the
+failed offerer already sent it. Requesters that did not negotiate the
+Notices capability cannot be reset and are never retried*/
+func (sm *SwarmManager) pairWithRetries(ctx context.Context, acceptor Conn, selection *Selection) (Conn, error) {
+	budget, cancel := context.WithTimeout(ctx, PairingRetryBudget)
+	defer cancel()
+	attemptCtx := ctx
+	for attempt := 0; ; attempt++ {
+		offerer, err := sm.pair(attemptCtx, acceptor, *selection)
+		var offererErr *OffererError
+		if err == nil || !errors.As(err, &offererErr) || attempt == PairingRetries || budget.Err() != nil ||
+			!acceptsNotices(acceptor) {
+			return offerer, err
+		}
+		log.Printf("Retrying pairing without endpoint %s in SwarmManager.AttemptToPair(): %v", offerer.GetAddress(), err)
+		nErr := responder.Notify(acceptor, responder.Notice{Type: responder.NoticeReset, Reason: ResetReason})
+		if nErr != nil {
+			return offerer, fmt.Errorf("Failed to reset requester in SwarmManager.AttemptToPair(): %v (%w)", nErr, err)
+		}
+		selection.Exclude = append(selection.Exclude, offerer)
+		attemptCtx = budget
 	}
-	err = sm.gateway.PushEndpoint(offererConn)
+}
+
+//acceptsNotices returns whether or not 'conn' negotiated the Notices capability
+func acceptsNotices(conn Conn) bool {
+	pConn, ok := conn.(responder.ProtocolConn)
+	return ok && pConn.GetCapabilities()&protocol.CapabilityNotices != 0
+}
+
+/*pair negotiates between 'acceptor' and an offerer fitting 'selection'.
+The offerer is returned so that it can be excluded if it failed*/
+func (sm *SwarmManager) pair(ctx context.Context, acceptor Conn, selection Selection) (Conn, error) {
+	offerer, err := sm.gateway.GetEndpoint(ctx, selection)
 	if err != nil {
-		log.Printf("Failed to push endpoint back to queue in SwarmManager.AttemptToPair(): %v", err)
+		return nil, responder.Errorf(responder.NoEndpointsAvailable, "Failed to pair in SwarmManager.AttemptToPair(): %v", err)
 	}
+	defer sm.releaseEndpoint(offerer)
 
-	err = sm.negotiate(offererConn, acceptorConn)
+	sm.debrief(ctx, offerer)
+	err = sm.negotiate(ctx, offerer, acceptor)
 	if err != nil {
-		return fmt.Errorf("Failed to pair in SwarmManager.AttemptToPair(): %v", err)
+		return offerer, responder.Errorf(responder.NegotiationFailed, "Failed to pair in SwarmManager.AttemptToPair(): %w", err)
 	}
-	return nil
+	return offerer, nil
+}
+
+/*AddEndpoint Adds the provided connection to the swarm. The addition is
+abandoned if 'ctx' is done before the endpoint retrieved the swarm state*/
+func (sm *SwarmManager) AddEndpoint(ctx context.Context, c interface{}) error {
+	return sm.addEndpoint(ctx, c, "")
 }
 
-//AddEndpoint Adds the provided connection to the swarm
-func (sm *SwarmManager) AddEndpoint(c interface{}) error {
+/*AddPinnedEndpoint adds the provided connection to the swarm like AddEndpoint
+but the endpoint only serves 'dataspace' and is never transferred to another
+swarm unless 'dataspace' moves there*/
+func (sm *SwarmManager) AddPinnedEndpoint(ctx context.Context, c interface{}, dataspace string) error {
+	return sm.addEndpoint(ctx, c, dataspace)
+}
+
+func (sm *SwarmManager) addEndpoint(ctx context.Context, c interface{}, pinnedTo string) error {
 	//Connect new endpoint with old endpoint so that state can be copied over
 	conn, ok := c.(Conn)
 	if !ok {
 		return fmt.Errorf("Failed to add endpoint in SwarmManager.AddEndpoint(): parameter of wrong type")
 	}
-	err := sm.connectForContextRetrieval(conn)
+	dataspaces := sm.GetDataspaces()
+	if pinnedTo != "" {
+		dataspaces = []string{pinnedTo}
+	}
+	err := sm.connectForContextRetrieval(ctx, conn, dataspaces)
 	if err != nil {
 		return err
 	}
 
+	/*Acknowledge before the endpoint becomes visible to the gateway
+	so that the response never interleaves with a negotiation. The
+	endpoint needs the identity it was issued to log off*/
+	err = responder.LoggedOn(conn, vivaldi.IdentityOf(conn))
+	if err != nil {
+		return fmt.Errorf("Failed to communicate endpoint addition in SwarmManager.AddEndpoint(): %v", err)
+	}
+
 	//Add new endpoint to the gateway structure
-	err = sm.gateway.PushEndpoint(conn)
+	if pinnedTo != "" {
+		err = sm.gateway.PushPinnedEndpoint(conn, pinnedTo)
+	} else {
+		err = sm.gateway.PushEndpoint(conn)
+	}
 	if err != nil {
 		return fmt.Errorf("Failed to add endpoint in SwarmManager.AddEndpoint(): %v", err)
 	}
 	sm.incrementChanges()
+	return nil
+}
 
-	err = binary.Write(conn, binary.BigEndian, OperationSuccess)
+/*RemoveEndpoint removes the endpoint that was issued the identity 'id'
+from the swarm after it logged off. The new size of the swarm is reported
+right away*/
+func (sm *SwarmManager) RemoveEndpoint(id string) error {
+	if sm.isClosed() {
+		return fmt.Errorf("Failed to remove endpoint in SwarmManager.RemoveEndpoint(). Object closed")
+	}
+	err := sm.gateway.RemoveEndpoint(id)
 	if err != nil {
-		return fmt.Errorf("Failed to communicate endpoint addition in SwarmManager.AddEndpoint(): %v", err)
+		return fmt.Errorf("Failed to remove endpoint in SwarmManager.RemoveEndpoint(): %v", err)
 	}
+	sm.updateSize()
 	return nil
 }
 
+/*Transfer moves 'tsize' distinct idle endpoints to the swarm of 'm' or every
+idle endpoint that can be moved if 'tsize' is transmuter.TransferAll. Each
+endpoint is told about its new dataspace and retrieves the state of its new
+swarm before it joins it. Pinned endpoints and endpoints that cannot be told
+about a migration are never moved. The endpoints migrate concurrently so a
+transfer takes about as long as its slowest migration. Either every endpoint
+joins the swarm of 'm' or all of them are returned to this swarm, migrating
+back if they were already told about the move. Endpoints are only closed if
+returning them fails as well. The new sizes of both swarms are reported
+right away*/
 func (sm *SwarmManager) Transfer(tsize int, m transmuter.SwarmManager) error {
 	smallManager := m.(*SwarmManager)
-	for i := 0; i < tsize; i++ {
-		conn, err := sm.gateway.GetEndpoint()
-		if err != nil {
-			return fmt.Errorf("Failed to transfer endpoints in SwarmManager.Transfer(): %v", err)
+	if sm.isClosed() || smallManager.isClosed() {
+		return fmt.Errorf("Failed to transfer endpoints in SwarmManager.Transfer(): Object closed")
+	}
+	conns, err := sm.gateway.DetachEndpoints(tsize)
+	if err != nil {
+		return fmt.Errorf("Failed to transfer endpoints in SwarmManager.Transfer(): %v", err)
+	}
+	defer sm.updateSize()
+
+	results := smallManager.migrateAll(conns, smallManager.GetDataspaces())
+	migrated := make([]Conn, 0, len(conns))
+	for _, result := range results {
+		if result.err != nil {
+			log.Printf("Failed to migrate endpoint %s in SwarmManager.Transfer(): %v", result.conn.GetAddress(), result.err)
+			continue
 		}
-		err = smallManager.gateway.PushEndpoint(conn)
-		if err != nil {
-			return fmt.Errorf("Failed to transfer endpoints in SwarmManager.Transfer(): %v", err)
+		migrated = append(migrated, result.conn)
+	}
+	if len(migrated) < len(conns) {
+		sm.reclaim(results)
+		return fmt.Errorf("Failed to transfer endpoints in SwarmManager.Transfer(): Only %d of %d endpoints migrated",
+			len(migrated), len(conns))
+	}
+	err = smallManager.gateway.AdoptEndpoints(migrated)
+	if err != nil {
+		sm.reclaim(results)
+		return fmt.Errorf("Failed to transfer endpoints in SwarmManager.Transfer(): %v", err)
+	}
+	smallManager.updateSize()
+	return nil
+}
+
+/*Absorb moves every idle endpoint of the swarm of 'm' into this swarm once
+'m' was merged into it. The endpoints of 'm' that can migrate do so first
+and retrieve the state of this swarm from the endpoints it already holds.
+The endpoints this swarm held are then migrated in place and retrieve the
+state of the dataspaces it gained from the absorbed ones. Endpoints that
+cannot be told about the merge keep serving only the dataspaces they served
+before so that they are never paired with requesters of other dataspaces.
+Endpoints that fail to migrate are closed*/
+func (sm *SwarmManager) Absorb(m transmuter.SwarmManager) error {
+	merged := m.(*SwarmManager)
+	if sm.isClosed() || merged.isClosed() {
+		return fmt.Errorf("Failed to absorb endpoints in SwarmManager.Absorb(): Object closed")
+	}
+	defer sm.updateSize()
+	defer merged.updateSize()
+	mergedDataspaces := merged.GetDataspaces()
+	ownDataspaces := without(sm.GetDataspaces(), mergedDataspaces)
+	sm.gateway.RestrictEndpoints(ownDataspaces)
+	merged.gateway.RestrictEndpoints(mergedDataspaces)
+
+	absorbed, err := merged.gateway.DetachEndpoints(transmuter.TransferAll)
+	if err != nil {
+		return fmt.Errorf("Failed to absorb endpoints in SwarmManager.Absorb(): %v", err)
+	}
+	absorbed = sm.migrated(absorbed, ownDataspaces)
+	own, err := sm.gateway.DetachEndpoints(transmuter.TransferAll)
+	if err == nil {
+		err = sm.gateway.AdoptEndpoints(absorbed)
+	}
+	if err != nil {
+		closeAll(absorbed)
+		closeAll(own)
+		return fmt.Errorf("Failed to absorb endpoints in SwarmManager.Absorb(): %v", err)
+	}
+	own = sm.migrated(own, mergedDataspaces)
+	err = sm.gateway.AdoptEndpoints(own)
+	if err != nil {
+		closeAll(own)
+		return fmt.Errorf("Failed to absorb endpoints in SwarmManager.Absorb(): %v", err)
+	}
+	return merged.TransferRestricted(sm)
+}
+
+/*TransferRestricted moves every idle endpoint that cannot migrate and only
+serves dataspaces of the swarm of 'm', such as the endpoints pinned to them,
+to that swarm. They keep serving the same dataspaces there. Such endpoints
+that are checked out are closed once they are released. Endpoints that
+cannot be moved are returned to this swarm and only closed if that fails*/
+func (sm *SwarmManager) TransferRestricted(m transmuter.SwarmManager) error {
+	other := m.(*SwarmManager)
+	if sm.isClosed() || other.isClosed() {
+		return fmt.Errorf("Failed to transfer endpoints in SwarmManager.TransferRestricted(): Object closed")
+	}
+	restricted, err := sm.gateway.DetachRestricted(other.GetDataspaces())
+	if err != nil {
+		return fmt.Errorf("Failed to transfer endpoints in SwarmManager.TransferRestricted(): %v", err)
+	}
+	defer sm.updateSize()
+	err = other.gateway.AdoptRestricted(restricted)
+	if err != nil {
+		if rErr := sm.gateway.AdoptRestricted(restricted); rErr != nil {
+			for _, endpoint := range restricted {
+				endpoint.Conn.Close()
+			}
 		}
+		return fmt.Errorf("Failed to transfer endpoints in SwarmManager.TransferRestricted(): %v", err)
 	}
+	other.updateSize()
 	return nil
 }
 
-func (sm *SwarmManager) connectForContextRetrieval(conn Conn) error {
-	offerer, err := sm.gateway.GetEndpoint()
+/*migrated migrates 'conns' to this swarm retrieving the state of 'dataspaces'
+and returns the conns that migrated. The others are closed*/
+func (sm *SwarmManager) migrated(conns []Conn, dataspaces []string) []Conn {
+	succeeded := make([]Conn, 0, len(conns))
+	for _, result := range sm.migrateAll(conns, dataspaces) {
+		if result.err != nil {
+			log.Printf("Failed to migrate endpoint %s in SwarmManager.Absorb(): %v", result.conn.GetAddress(), result.err)
+			result.conn.Close()
+			continue
+		}
+		succeeded = append(succeeded, result.conn)
+	}
+	return succeeded
+}
+
+func closeAll(conns []Conn) {
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+//without returns the dataspaces of 'dataspaces' that are not in 'excluded'
+func without(dataspaces []string, excluded []string) []string {
+	kept := make([]string, 0, len(dataspaces))
+	for _, dataspace := range dataspaces {
+		found := false
+		for _, other := range excluded {
+			if dataspace == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, dataspace)
+		}
+	}
+	return kept
+}
+
+/*migration is the outcome of migrating an endpoint. 'notified' is set once
+the endpoint was told about the move and may already serve the dataspaces
+of its new swarm*/
+type migration struct {
+	conn     Conn
+	notified bool
+	err      error
+}
+
+/*migrateAll migrates every conn in 'conns' to this swarm concurrently. The
+conns retrieve the state of 'dataspaces' from the endpoints of this swarm*/
+func (sm *SwarmManager) migrateAll(conns []Conn, dataspaces []string) []migration {
+	results := make([]migration, len(conns))
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn Conn) {
+			defer wg.Done()
+			notified, err := sm.migrate(conn, dataspaces)
+			results[i] = migration{conn: conn, notified: notified, err: err}
+		}(i, conn)
+	}
+	wg.Wait()
+	return results
+}
+
+/*reclaim adopts endpoints detached from this swarm back into it. Endpoints
+that may serve other dataspaces are migrated to the dataspaces of this swarm
+first. Endpoints that cannot be adopted are closed*/
+func (sm *SwarmManager) reclaim(detached []migration) {
+	if len(detached) == 0 {
+		return
+	}
+	returned := make([]Conn, 0, len(detached))
+	remigrate := make([]Conn, 0, len(detached))
+	for _, result := range detached {
+		if result.notified {
+			remigrate = append(remigrate, result.conn)
+		} else {
+			returned = append(returned, result.conn)
+		}
+	}
+	for _, result := range sm.migrateAll(remigrate, sm.GetDataspaces()) {
+		if result.err != nil {
+			log.Printf("Failed to return endpoint %s in SwarmManager.Transfer(): %v", result.conn.GetAddress(), result.err)
+			result.conn.Close()
+			continue
+		}
+		returned = append(returned, result.conn)
+	}
+	err := sm.gateway.AdoptEndpoints(returned)
+	if err != nil {
+		log.Printf("Failed to return endpoints in SwarmManager.Transfer(): %v", err)
+		for _, conn := range returned {
+			conn.Close()
+		}
+	}
+}
+
+/*migrate tells 'conn' that it now serves the dataspaces of this swarm and
+waits for its acknowledgement. The endpoint then retrieves the state of
+'dataspaces' from this swarm and is told once it may join it. Whether the
+endpoint was told about the move is returned along with any error*/
+func (sm *SwarmManager) migrate(conn Conn, dataspaces []string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), MigrationTimeout)
+	defer cancel()
+
+	unbind := BindContext(ctx, conn)
+	err := responder.Notify(conn, responder.Notice{Type: responder.NoticeMigrate, Reason: sm.id,
+		Dataspaces: sm.GetDataspaces()})
+	if err != nil {
+		unbind()
+		return false, fmt.Errorf("Failed to notify endpoint in SwarmManager.migrate(): %v", err)
+	}
+	err = awaitMigrated(conn, sm.id)
+	unbind()
+	if err != nil {
+		return true, fmt.Errorf("Failed to notify endpoint in SwarmManager.migrate(): %v", err)
+	}
+
+	err = sm.connectForContextRetrieval(ctx, conn, dataspaces)
+	if err != nil {
+		//The endpoint waits for an answer before it may be migrated back
+		responder.Failure(conn, err, responder.SwarmUnavailable)
+		return true, err
+	}
+	err = responder.Success(conn)
 	if err != nil {
+		return true, fmt.Errorf("Failed to communicate migration in SwarmManager.migrate(): %v", err)
+	}
+	return true, nil
+}
+
+/*awaitMigrated reads the notices sent by 'conn' until it acknowledges the
+migration to 'dataspace'. Notices left over from earlier exchanges are skipped*/
+func awaitMigrated(conn Conn, dataspace string) error {
+	for {
+		notice, err := responder.ReadNotice(conn)
+		if err != nil {
+			return err
+		}
+		if notice.Type == responder.NoticeMigrated && notice.Reason == dataspace {
+			return nil
+		}
+	}
+}
+
+/*connectForContextRetrieval negotiates between 'conn' and an endpoint of the
+swarm holding the state of 'dataspaces' so that it can copy that state. The
+endpoint joining an empty swarm has no state to copy and is not left waiting
+for another one*/
+func (sm *SwarmManager) connectForContextRetrieval(ctx context.Context, conn Conn, dataspaces []string) error {
+	selection := Selection{Requester: vivaldi.KeyOf(conn), NoWait: true, Dataspaces: dataspaces}
+	offerer, err := sm.gateway.GetEndpoint(ctx, selection)
+	if ctx.Err() != nil {
+		return fmt.Errorf("Failed to retrieve swarm state in SwarmManager.AddEndpoint(): %v", ctx.Err())
+	} else if err != nil {
 		/*If there was an error getting an endpoint thats because the swarm is
 		empty and therefore there is no context to retrieve*/
 		return nil
 	}
+	defer sm.releaseEndpoint(offerer)
 
-	debrief := DebriefProcedure(offerer)
-	if debrief != nil {
-		sm.tracker.AddDebriefDatapoint(sm.id, debrief)
-	}
-	offererConn, ok := offerer.(Conn)
-	if !ok {
-		return fmt.Errorf("Failed to negotiate in SwarmManager.AddEndpoint(): Connection of wrong type")
-	}
-	err = sm.negotiate(offererConn, conn)
+	sm.debrief(ctx, offerer)
+	err = sm.negotiate(ctx, offerer, conn)
 	if err != nil {
-		return fmt.Errorf("Failed to negotiate in SwarmManager.AddEndpoint(): %v", err)
+		return responder.Errorf(responder.NegotiationFailed, "Failed to negotiate in SwarmManager.AddEndpoint(): %v", err)
 	}
 	return nil
 }
@@ -145,22 +525,85 @@ func (sm *SwarmManager) GetID() string {
 	return sm.id
 }
 
+/*SetDataspaces replaces the dataspaces served by the swarm. Endpoints
+migrating to the swarm are told to serve every one of them*/
+func (sm *SwarmManager) SetDataspaces(dataspaces []string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.dataspaces = append([]string{}, dataspaces...)
+}
+
+//GetDataspaces returns every dataspace served by the swarm
+func (sm *SwarmManager) GetDataspaces() []string {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	return append([]string{}, sm.dataspaces...)
+}
+
 func (sm *SwarmManager) GetSize() int {
 	return sm.gateway.GetTotalEndpoints()
 }
 
+//GetMovableSize returns the number of idle endpoints Transfer can move
+func (sm *SwarmManager) GetMovableSize() int {
+	return sm.gateway.GetMovableEndpoints()
+}
+
 //Close closes the SwarmManager for use
 func (sm *SwarmManager) Close() error {
+	sm.mutex.Lock()
 	if sm.closed {
+		sm.mutex.Unlock()
 		return fmt.Errorf("Failed to close in SwarmManager.Close() Already closed")
 	}
-	sm.tracker.Delete(sm.id)
-	sm.gateway.Close()
 	sm.closed = true
+	sm.mutex.Unlock()
+
+	/*The gateway waits for its heartbeat which may be reporting an
+	eviction so it must be closed without holding the mutex*/
+	sm.gateway.Close()
+	sm.tracker.Delete(sm.id)
 	return nil
 }
 
+func (sm *SwarmManager) isClosed() bool {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	return sm.closed
+}
+
+//debrief reads the debrief 'offerer' sends before each negotiation
+func (sm *SwarmManager) debrief(ctx context.Context, offerer Conn) {
+	unbind := BindContext(ctx, offerer)
+	debrief := DebriefProcedure(offerer)
+	unbind()
+	if debrief != nil {
+		sm.tracker.AddDebriefDatapoint(sm.id, debrief)
+		sm.gateway.RecordDebrief(offerer, debrief)
+	}
+}
+
+func (sm *SwarmManager) releaseEndpoint(conn Conn) {
+	err := sm.gateway.ReleaseEndpoint(conn)
+	if err != nil {
+		log.Printf("Failed to release endpoint in SwarmManager: %v", err)
+	}
+}
+
+//updateSize reports the size of the swarm to the tracker right away
+func (sm *SwarmManager) updateSize() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.closed {
+		return
+	}
+	sm.tracker.SetSize(sm.id, sm.gateway.GetTotalEndpoints())
+	sm.changes = 0
+}
+
 func (sm *SwarmManager) incrementChanges() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
 	sm.changes++
 	if sm.changes > ChangeTriggerLimit {
 		sm.tracker.SetSize(sm.id, sm.gateway.GetTotalEndpoints())
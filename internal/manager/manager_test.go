@@ -1,15 +1,24 @@
 package manager
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+	"github.com/arstevens/go-hive-signal/internal/protocol"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 )
 
 func TestManager(t *testing.T) {
 	DebriefProcedure = func(io.Reader) interface{} { return rand.Intn(95) + 5 }
+	responder.MarshalResponse = func(responder.Response) ([]byte, error) { return []byte{}, nil }
 	tracker := &testSwarmTracker{m: make(map[string]int)}
 	totalSwarms := 10
 	swarms := make([]*SwarmManager, totalSwarms)
@@ -24,15 +33,153 @@ func TestManager(t *testing.T) {
 	for i := 0; i < totalSwarms; i++ {
 		fmt.Printf("\tID: %s\n", swarms[i].GetID())
 		conn := &FakeConn{}
-		err := swarms[i].AttemptToPair(conn)
+		err := swarms[i].AttemptToPair(context.Background(), conn, metadata.Requirements{})
 		if err != nil {
 			t.Fatalf("Failed to run pair: %v\n", err)
 		}
-		err = swarms[i].AddEndpoint(&FakeConn{})
+		err = swarms[i].AddEndpoint(context.Background(), &FakeConn{})
 		if err != nil {
 			panic(err)
 		}
 	}
+
+	fmt.Printf("[RUNNING TRANSFER TESTS]\n")
+	responder.MarshalNotice = marshalNotice
+	responder.UnmarshalNotice = unmarshalNotice
+	from := New("/dataspace/from", &testSwarmGateway{conn: &FakeConn{}, totalEndpoints: 10}, negotiate, tracker)
+	toGateway := &testSwarmGateway{conn: &FakeConn{}, totalEndpoints: 2}
+	to := New("/dataspace/to", toGateway, negotiate, tracker)
+	err := from.Transfer(4, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("\tSizes after transfer: %d -> %d\n", tracker.m["/dataspace/from"], tracker.m["/dataspace/to"])
+	if tracker.m["/dataspace/from"] != 6 || tracker.m["/dataspace/to"] != 6 {
+		t.Fatalf("Expected both swarm sizes to be reported after a transfer")
+	}
+	if from.Transfer(7, to) == nil || from.GetSize() != 6 {
+		t.Fatalf("Expected a transfer larger than the swarm to move nothing")
+	}
+
+	closed := New("/dataspace/closed", &testSwarmGateway{conn: &FakeConn{}, closed: true}, negotiate, tracker)
+	if from.Transfer(3, closed) == nil || tracker.m["/dataspace/from"] != 6 {
+		t.Fatalf("Expected endpoints that migrated to a closed swarm to be returned")
+	}
+
+	refusing := New("/dataspace/refusing", &testSwarmGateway{conn: &FakeConn{}, totalEndpoints: 4, refusing: 1}, negotiate, tracker)
+	if refusing.Transfer(3, to) == nil || tracker.m["/dataspace/refusing"] != 4 || tracker.m["/dataspace/to"] != 6 {
+		t.Fatalf("Expected the endpoints that migrated to be returned when another one failed to migrate")
+	}
+
+	merged := New("/dataspace/merged", &testSwarmGateway{conn: &FakeConn{}, totalEndpoints: 5, unmovable: 2}, negotiate, tracker)
+	if merged.GetMovableSize() != 3 {
+		t.Fatalf("Expected pinned endpoints to not be counted as movable")
+	}
+	toGateway.retrievals = nil
+	if err = to.Absorb(merged); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("\tSize after absorbing a swarm: %d\n", tracker.m["/dataspace/to"])
+	if tracker.m["/dataspace/to"] != 11 || merged.GetSize() != 0 {
+		t.Fatalf("Expected every endpoint of the merged swarm to be absorbed")
+	}
+	fmt.Printf("\tEndpoints held while migrating endpoints retrieved the swarm state: %v\n", toGateway.retrievals)
+	if len(toGateway.retrievals) != 9 {
+		t.Fatalf("Expected every endpoint that migrated to retrieve the swarm state")
+	}
+	for _, held := range toGateway.retrievals {
+		if held == 0 {
+			t.Fatalf("Expected migrating endpoints to retrieve the swarm state from endpoints the swarm holds")
+		}
+	}
+}
+
+func TestPairingRetry(t *testing.T) {
+	DebriefProcedure = func(io.Reader) interface{} { return nil }
+	responder.MarshalNotice = marshalNotice
+	responder.UnmarshalNotice = unmarshalNotice
+	tracker := &testSwarmTracker{m: make(map[string]int)}
+	offerers := []Conn{&OffererConn{name: "/offerer/bad/0"}, &OffererConn{name: "/offerer/bad/1"},
+		&OffererConn{name: "/offerer/good"}}
+	gateway := &retryingGateway{offerers: offerers}
+	negotiated := make([]string, 0)
+	negotiate := func(ctx context.Context, offerer Conn, acceptor Conn) error {
+		negotiated = append(negotiated, offerer.GetAddress())
+		if strings.HasPrefix(offerer.GetAddress(), "/offerer/bad") {
+			return &OffererError{Err: fmt.Errorf("Offerer went away")}
+		}
+		return nil
+	}
+	manager := New("/dataspace/retry", gateway, negotiate, tracker)
+
+	requester := &RequesterConn{}
+	err := manager.AttemptToPair(context.Background(), requester, metadata.Requirements{})
+	fmt.Printf("Negotiated with %v\n", negotiated)
+	if err != nil || len(negotiated) != 3 {
+		t.Fatalf("Expected pairing to fall back to the working offerer, got %v", err)
+	}
+	received := requester.notices()
+	fmt.Printf("Requester received notices %v\n", received)
+	if fmt.Sprint(received) != fmt.Sprint([]int32{responder.NoticeReset, responder.NoticeReset}) {
+		t.Fatalf("Expected the requester to be reset before each retry, got %v", received)
+	}
+
+	PairingRetries = 1
+	defer func() { PairingRetries = 2 }()
+	negotiated = negotiated[:0]
+	err = manager.AttemptToPair(context.Background(), &RequesterConn{}, metadata.Requirements{})
+	if responder.CategoryOf(err, responder.NoError) != responder.NegotiationFailed || len(negotiated) != 2 {
+		t.Fatalf("Expected pairing to give up after %d retries, got %v", PairingRetries, err)
+	}
+
+	//Requesters that cannot be sent notices are neither reset nor paired with many peers
+	negotiated = negotiated[:0]
+	legacy := &RequesterConn{legacy: true}
+	paired, err := manager.AttemptToPairMany(context.Background(), legacy, "", metadata.Requirements{}, 4)
+	fmt.Printf("Legacy requester negotiated with %v\n", negotiated)
+	if err == nil || paired != 0 || len(negotiated) != 1 || legacy.Len() != 0 {
+		t.Fatalf("Expected a requester without notices to be paired once and never reset, got %d: %v", paired, err)
+	}
+
+	//Every peer is a distinct offerer so only the working one can be paired
+	negotiated = negotiated[:0]
+	gateway.offerers = append(gateway.offerers, &OffererConn{name: "/offerer/good/1"})
+	PairingRetries = 2
+	requester = &RequesterConn{}
+	paired, err = manager.AttemptToPairMany(context.Background(), requester, "", metadata.Requirements{}, 4)
+	fmt.Printf("Paired with %d peers after negotiating with %v\n", paired, negotiated)
+	if err != nil || paired != 2 {
+		t.Fatalf("Expected both working offerers to be paired, got %d: %v", paired, err)
+	}
+	received = requester.notices()
+	fmt.Printf("Requester received notices %v\n", received)
+	expected := []int32{responder.NoticePeer, responder.NoticeReset, responder.NoticeReset, responder.NoticePeer,
+		responder.NoticePeer}
+	if fmt.Sprint(received) != fmt.Sprint(expected) {
+		t.Fatalf("Expected the resets to follow the notice of the peer they retry, got %v", received)
+	}
+}
+
+func TestBindContext(t *testing.T) {
+	conn := &DeadlineFakeConn{}
+	outer, cancelOuter := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelOuter()
+	unbindOuter := BindContext(outer, conn)
+	outerDeadline := conn.deadline
+
+	inner, cancelInner := context.WithTimeout(outer, time.Millisecond)
+	unbindInner := BindContext(inner, conn)
+	<-inner.Done()
+	cancelInner()
+	unbindInner()
+	fmt.Printf("Deadline after a nested unbind: %v\n", conn.deadline)
+	if !conn.deadline.Equal(outerDeadline) {
+		t.Fatalf("Expected the nested unbind to restore the outer deadline")
+	}
+	unbindOuter()
+	if !conn.deadline.IsZero() {
+		t.Fatalf("Expected the last unbind to clear the deadline")
+	}
 }
 
 type testSwarmTracker struct {
@@ -48,19 +195,80 @@ func (st *testSwarmTracker) Delete(s string) {
 }
 
 func (st *testSwarmTracker) AddDebriefDatapoint(string, interface{}) {}
+func (st *testSwarmTracker) SetWaiting(string, int)                  {}
 
 type testSwarmGateway struct {
 	conn           *FakeConn
 	totalEndpoints int
+	closed         bool
+	//Endpoints among totalEndpoints that are pinned
+	unmovable int
+	//Endpoints among those detached first that refuse to migrate
+	refusing int
+	//Endpoints held by the swarm each time the state of the swarm was retrieved
+	retrievals []int
 }
 
-func (sg *testSwarmGateway) GetEndpoint() (Conn, error) {
+func (sg *testSwarmGateway) GetEndpoint(ctx context.Context, selection Selection) (Conn, error) {
+	if selection.NoWait {
+		sg.retrievals = append(sg.retrievals, sg.totalEndpoints)
+	}
 	return sg.conn, nil
 }
 func (sg *testSwarmGateway) PushEndpoint(Conn) error {
 	sg.totalEndpoints++
 	return nil
 }
+func (sg *testSwarmGateway) PushPinnedEndpoint(c Conn, _ string) error { return sg.PushEndpoint(c) }
+func (sg *testSwarmGateway) ReleaseEndpoint(Conn) error                { return nil }
+func (sg *testSwarmGateway) RecordDebrief(Conn, interface{})           {}
+func (sg *testSwarmGateway) OnEviction(func())                         {}
+func (sg *testSwarmGateway) OnWaiting(func(int))                       {}
+func (sg *testSwarmGateway) RestrictEndpoints([]string)                {}
+func (sg *testSwarmGateway) DetachEndpoints(n int) ([]Conn, error) {
+	if n < 0 {
+		n = sg.totalEndpoints - sg.unmovable
+	} else if n > sg.totalEndpoints-sg.unmovable {
+		return nil, fmt.Errorf("Not enough endpoints to detach")
+	}
+	sg.totalEndpoints -= n
+	conns := make([]Conn, n)
+	for i := range conns {
+		if i < sg.refusing {
+			conns[i] = &RefusingConn{}
+		} else {
+			conns[i] = &MigratingConn{}
+		}
+	}
+	return conns, nil
+}
+func (sg *testSwarmGateway) GetMovableEndpoints() int {
+	return sg.totalEndpoints - sg.unmovable
+}
+func (sg *testSwarmGateway) DetachRestricted([]string) ([]Restricted, error) {
+	pinned := make([]Restricted, sg.unmovable)
+	for i := range pinned {
+		pinned[i] = Restricted{Conn: &FakeConn{}, Pinned: true}
+	}
+	sg.totalEndpoints -= sg.unmovable
+	sg.unmovable = 0
+	return pinned, nil
+}
+func (sg *testSwarmGateway) AdoptRestricted(endpoints []Restricted) error {
+	if sg.closed {
+		return fmt.Errorf("Gateway closed")
+	}
+	sg.totalEndpoints += len(endpoints)
+	sg.unmovable += len(endpoints)
+	return nil
+}
+func (sg *testSwarmGateway) AdoptEndpoints(conns []Conn) error {
+	if sg.closed {
+		return fmt.Errorf("Gateway closed")
+	}
+	sg.totalEndpoints += len(conns)
+	return nil
+}
 func (sg *testSwarmGateway) RemoveEndpoint(string) error {
 	if sg.totalEndpoints == 0 {
 		return fmt.Errorf("No endpoint to retire")
@@ -83,7 +291,29 @@ func (sg *testSwarmGateway) Close() error {
 	return nil
 }
 
-func negotiate(Conn, Conn) error {
+//retryingGateway checks out the first offerer not excluded by the Selection
+type retryingGateway struct {
+	testSwarmGateway
+	offerers []Conn
+}
+
+func (rg *retryingGateway) GetEndpoint(ctx context.Context, selection Selection) (Conn, error) {
+	for _, offerer := range rg.offerers {
+		if !selection.Excludes(offerer) {
+			return offerer, nil
+		}
+	}
+	return nil, fmt.Errorf("No offerers left")
+}
+
+type OffererConn struct {
+	FakeConn
+	name string
+}
+
+func (oc *OffererConn) GetAddress() string { return oc.name }
+
+func negotiate(context.Context, Conn, Conn) error {
 	return nil
 }
 
@@ -94,3 +324,91 @@ func (fc *FakeConn) Write([]byte) (int, error) { return 0, nil }
 func (fc *FakeConn) Close() error              { return nil }
 func (fc *FakeConn) GetAddress() string        { return "" }
 func (fc *FakeConn) IsClosed() bool            { return false }
+
+type DeadlineFakeConn struct {
+	FakeConn
+	deadline time.Time
+}
+
+func (dc *DeadlineFakeConn) SetDeadline(deadline time.Time) error {
+	dc.deadline = deadline
+	return nil
+}
+
+/*MigratingConn plays an endpoint that acknowledges every migration. The
+notices written to it are read back with migrations turned into acknowledgements*/
+type MigratingConn struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (mc *MigratingConn) Close() error       { mc.closed = true; return nil }
+func (mc *MigratingConn) GetAddress() string { return "/address/migrating" }
+
+//RefusingConn plays an endpoint that cannot be told about a migration
+type RefusingConn struct {
+	MigratingConn
+}
+
+func (rc *RefusingConn) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("Endpoint refuses to migrate")
+}
+
+/*RequesterConn records every frame written to a requester. Legacy
+requesters negotiated no capabilities*/
+type RequesterConn struct {
+	bytes.Buffer
+	legacy bool
+}
+
+func (rc *RequesterConn) Close() error              { return nil }
+func (rc *RequesterConn) GetAddress() string        { return "/address/requester" }
+func (rc *RequesterConn) GetProtocolVersion() int32 { return protocol.CurrentVersion }
+func (rc *RequesterConn) GetCapabilities() uint64 {
+	if rc.legacy {
+		return 0
+	}
+	return protocol.CapabilityNotices
+}
+
+//notices returns the type of every notice written to the requester
+func (rc *RequesterConn) notices() []int32 {
+	types := make([]int32, 0)
+	for {
+		notice, err := responder.ReadNotice(rc)
+		if err != nil {
+			return types
+		}
+		types = append(types, notice.Type)
+	}
+}
+
+func marshalNotice(n responder.Notice) ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%s", n.Type, n.Reason)), nil
+}
+
+func unmarshalNotice(raw []byte) (interface{}, error) {
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) < 2 {
+		//Responses written to the endpoint are read back as unknown notices
+		return &testNotice{noticeType: -1}, nil
+	}
+	noticeType, _ := strconv.Atoi(parts[0])
+	return &testNotice{noticeType: int32(noticeType), reason: parts[1]}, nil
+}
+
+type testNotice struct {
+	noticeType int32
+	reason     string
+}
+
+func (tn *testNotice) GetType() int32 {
+	if tn.noticeType == responder.NoticeMigrate {
+		return responder.NoticeMigrated
+	}
+	return tn.noticeType
+}
+func (tn *testNotice) GetReason() string       { return tn.reason }
+func (tn *testNotice) GetPeer() string         { return "" }
+func (tn *testNotice) GetRtt() int64           { return 0 }
+func (tn *testNotice) GetDataspaces() []string { return nil }
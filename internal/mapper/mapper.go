@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"sync"
+
+	"github.com/arstevens/go-hive-signal/internal/responder"
 )
 
 /*SwarmManagerGenerator describes an object that can create
@@ -12,55 +14,229 @@ type SwarmManagerGenerator interface {
 	New(id string) interface{}
 }
 
+/*SharedSwarm describes a swarm manager that wants to know every
+dataspace it serves. It is optional and only used when the
+layout of a swarm changes*/
+type SharedSwarm interface {
+	SetDataspaces([]string)
+}
+
 /*SwarmMap holds a thread-safe mapping of dataspaces
-to swarm managers*/
+to swarm managers along with the origin owning each
+dataspace if it has one. A swarm is identified by the
+dataspace it was created for and may serve several
+dataspaces after merges*/
 type SwarmMap struct {
-	mapMutex   *sync.Mutex
-	managerMap map[string]interface{}
-	generator  SwarmManagerGenerator
+	mapMutex  *sync.Mutex
+	swarms    map[string]interface{}
+	layout    map[string][]string
+	swarmOf   map[string]string
+	owners    map[string]string
+	generator SwarmManagerGenerator
 }
 
 //New creates a new instance of SwarmMap
 func New(generator SwarmManagerGenerator) *SwarmMap {
 	return &SwarmMap{
-		mapMutex:   &sync.Mutex{},
-		managerMap: make(map[string]interface{}),
-		generator:  generator,
+		mapMutex:  &sync.Mutex{},
+		swarms:    make(map[string]interface{}),
+		layout:    make(map[string][]string),
+		swarmOf:   make(map[string]string),
+		owners:    make(map[string]string),
+		generator: generator,
 	}
 }
 
 /*RemoveSwarm removes dataspace from the SwarmMap and cleans up
-resources*/
+resources. A swarm shared with other dataspaces keeps serving
+them and is only closed once it serves none*/
 func (sm *SwarmMap) RemoveSwarm(dataspace string) error {
 	sm.mapMutex.Lock()
 	defer sm.mapMutex.Unlock()
 
-	if manager, ok := sm.managerMap[dataspace]; ok {
-		closer := manager.(io.Closer)
-		closer.Close()
-		delete(sm.managerMap, dataspace)
+	swarmID, ok := sm.swarmOf[dataspace]
+	if !ok {
+		return responder.Errorf(responder.UnknownDataspace, "No swarm associated with dataspace %s in SwarmMap.RemoveSwarm()", dataspace)
+	}
+	delete(sm.swarmOf, dataspace)
+	delete(sm.owners, dataspace)
+
+	remaining := make([]string, 0, len(sm.layout[swarmID]))
+	for _, served := range sm.layout[swarmID] {
+		if served != dataspace {
+			remaining = append(remaining, served)
+		}
+	}
+	if len(remaining) > 0 {
+		sm.layout[swarmID] = remaining
+		sm.setDataspaces(swarmID)
 		return nil
 	}
-	return fmt.Errorf("No swarm associated with dataspace %s in SwarmMap.RemoveSwarm()", dataspace)
+
+	closer := sm.swarms[swarmID].(io.Closer)
+	closer.Close()
+	delete(sm.swarms, swarmID)
+	delete(sm.layout, swarmID)
+	return nil
 }
 
 /*AddSwarm creates a new swarm associated with the dataspace*/
 func (sm *SwarmMap) AddSwarm(dataspace string) error {
-	manager := sm.generator.New(dataspace)
+	return sm.AddOwnedSwarm(dataspace, "")
+}
+
+/*AddOwnedSwarm creates a new swarm associated with the dataspace that
+is owned by 'originID'. An empty 'originID' leaves it without an owner.
+If a shared swarm still carries the dataspace as its id the dataspace
+rejoins that swarm instead*/
+func (sm *SwarmMap) AddOwnedSwarm(dataspace string, originID string) error {
+	sm.mapMutex.Lock()
+	defer sm.mapMutex.Unlock()
+
+	if _, ok := sm.swarmOf[dataspace]; ok {
+		return responder.Errorf(responder.DataspaceExists, "Swarm already associated with dataspace %s in SwarmMap.AddSwarm()", dataspace)
+	}
+	if _, ok := sm.swarms[dataspace]; ok {
+		//The swarm id is kept at the front so bisecting never moves it
+		sm.layout[dataspace] = append([]string{dataspace}, sm.layout[dataspace]...)
+		sm.setDataspaces(dataspace)
+	} else {
+		sm.swarms[dataspace] = sm.generator.New(dataspace)
+		sm.layout[dataspace] = []string{dataspace}
+	}
+	sm.swarmOf[dataspace] = dataspace
+	if originID != "" {
+		sm.owners[dataspace] = originID
+	}
+	return nil
+}
+
+/*MergeSwarms makes the swarm identified by 'keptID' serve every
+dataspace of the swarm identified by 'mergedID'. The merged swarm
+manager is removed from the SwarmMap and returned so its endpoints
+can be moved before it is closed*/
+func (sm *SwarmMap) MergeSwarms(keptID string, mergedID string) (interface{}, error) {
+	sm.mapMutex.Lock()
+	defer sm.mapMutex.Unlock()
+
+	if keptID == mergedID {
+		return nil, fmt.Errorf("Cannot merge swarm %s with itself in SwarmMap.MergeSwarms()", keptID)
+	}
+	if _, ok := sm.swarms[keptID]; !ok {
+		return nil, responder.Errorf(responder.UnknownDataspace, "No swarm with id %s in SwarmMap.MergeSwarms()", keptID)
+	}
+	merged, ok := sm.swarms[mergedID]
+	if !ok {
+		return nil, responder.Errorf(responder.UnknownDataspace, "No swarm with id %s in SwarmMap.MergeSwarms()", mergedID)
+	}
+
+	for _, dataspace := range sm.layout[mergedID] {
+		sm.swarmOf[dataspace] = keptID
+		sm.layout[keptID] = append(sm.layout[keptID], dataspace)
+	}
+	delete(sm.swarms, mergedID)
+	delete(sm.layout, mergedID)
+	sm.setDataspaces(keptID)
+	return merged, nil
+}
+
+/*BisectSwarm splits the dataspaces served by the swarm identified
+by 'swarmID' in two. The first half stays with the swarm and the
+second half is given a new, empty swarm manager which is returned
+so endpoints can be moved into it*/
+func (sm *SwarmMap) BisectSwarm(swarmID string) (interface{}, error) {
+	sm.mapMutex.Lock()
+	defer sm.mapMutex.Unlock()
+
+	dataspaces, ok := sm.layout[swarmID]
+	if !ok {
+		return nil, responder.Errorf(responder.UnknownDataspace, "No swarm with id %s in SwarmMap.BisectSwarm()", swarmID)
+	}
+	if len(dataspaces) < 2 {
+		return nil, fmt.Errorf("Swarm %s serves a single dataspace in SwarmMap.BisectSwarm()", swarmID)
+	}
+
+	kept := dataspaces[:len(dataspaces)/2]
+	moved := append([]string{}, dataspaces[len(dataspaces)/2:]...)
+	newID := moved[0]
+	if _, ok := sm.swarms[newID]; ok {
+		return nil, fmt.Errorf("Swarm with id %s already exists in SwarmMap.BisectSwarm()", newID)
+	}
+
+	manager := sm.generator.New(newID)
+	sm.swarms[newID] = manager
+	sm.layout[newID] = moved
+	sm.layout[swarmID] = kept
+	for _, dataspace := range moved {
+		sm.swarmOf[dataspace] = newID
+	}
+	sm.setDataspaces(swarmID)
+	sm.setDataspaces(newID)
+	return manager, nil
+}
+
+//IsOwnedBy returns whether or not 'dataspace' is owned by 'originID'
+func (sm *SwarmMap) IsOwnedBy(dataspace string, originID string) bool {
+	sm.mapMutex.Lock()
+	defer sm.mapMutex.Unlock()
+	owner, ok := sm.owners[dataspace]
+	return ok && owner == originID
+}
 
+/*Close closes every swarm manager in the SwarmMap. Their endpoints
+are released as part of closing each manager*/
+func (sm *SwarmMap) Close() error {
 	sm.mapMutex.Lock()
-	sm.managerMap[dataspace] = manager
-	sm.mapMutex.Unlock()
+	defer sm.mapMutex.Unlock()
+
+	for swarmID, manager := range sm.swarms {
+		closer := manager.(io.Closer)
+		closer.Close()
+		delete(sm.swarms, swarmID)
+		delete(sm.layout, swarmID)
+	}
+	sm.swarmOf = make(map[string]string)
+	sm.owners = make(map[string]string)
 	return nil
 }
 
-//GetSwarm returns the swarm manager object associated with the dataspace
+//GetDataspaces returns every dataspace that has a swarm
+func (sm *SwarmMap) GetDataspaces() []string {
+	sm.mapMutex.Lock()
+	defer sm.mapMutex.Unlock()
+
+	dataspaces := make([]string, 0, len(sm.swarmOf))
+	for dataspace := range sm.swarmOf {
+		dataspaces = append(dataspaces, dataspace)
+	}
+	return dataspaces
+}
+
+//GetDataspacesOf returns the dataspaces served by the swarm with id 'swarmID'
+func (sm *SwarmMap) GetDataspacesOf(swarmID string) []string {
+	sm.mapMutex.Lock()
+	defer sm.mapMutex.Unlock()
+	return append([]string{}, sm.layout[swarmID]...)
+}
+
+/*GetSwarm returns the swarm manager object associated with the dataspace.
+A swarm id is also accepted so swarms that no longer serve the dataspace
+they were created for can still be reached*/
 func (sm *SwarmMap) GetSwarm(dataspace string) (interface{}, error) {
 	sm.mapMutex.Lock()
 	defer sm.mapMutex.Unlock()
 
-	if manager, ok := sm.managerMap[dataspace]; ok {
+	if swarmID, ok := sm.swarmOf[dataspace]; ok {
+		return sm.swarms[swarmID], nil
+	}
+	if manager, ok := sm.swarms[dataspace]; ok {
 		return manager, nil
 	}
-	return nil, fmt.Errorf("No swarm associated with dataspace %s in SwarmMap.GetSwarmManager()", dataspace)
+	return nil, responder.Errorf(responder.UnknownDataspace, "No swarm associated with dataspace %s in SwarmMap.GetSwarmManager()", dataspace)
+}
+
+func (sm *SwarmMap) setDataspaces(swarmID string) {
+	if shared, ok := sm.swarms[swarmID].(SharedSwarm); ok {
+		shared.SetDataspaces(append([]string{}, sm.layout[swarmID]...))
+	}
 }
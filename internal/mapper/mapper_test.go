@@ -23,8 +23,19 @@ func TestMapper(t *testing.T) {
 	}
 	fmt.Printf("Added Swarms (%d-%d)\n", 0, totalSwarms-1)
 
+	// Test ownership
+	err := swarmMapper.AddOwnedSwarm("/dataspace/owned", "/origin/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !swarmMapper.IsOwnedBy("/dataspace/owned", "/origin/0") ||
+		swarmMapper.IsOwnedBy("/dataspace/owned", "/origin/1") || swarmMapper.IsOwnedBy(dspaces[1], "") {
+		t.Fatalf("Only the owning origin should own a dataspace")
+	}
+	fmt.Printf("Dataspace (/dataspace/owned) is owned by (/origin/0)\n")
+
 	// Test GetSwarm
-	_, err := swarmMapper.GetSwarm(dspaces[0])
+	_, err = swarmMapper.GetSwarm(dspaces[0])
 	if err != nil {
 		panic(err)
 	}
@@ -41,11 +52,57 @@ func TestMapper(t *testing.T) {
 		t.Fatalf("Swarm should have been deleted")
 	}
 
+	// Test MergeSwarms
+	_, err = swarmMapper.MergeSwarms(dspaces[1], dspaces[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _ := swarmMapper.GetSwarm(dspaces[1])
+	shared, err := swarmMapper.GetSwarm(dspaces[2])
+	if err != nil || kept != shared {
+		t.Fatalf("Merged dataspaces should share a swarm")
+	}
+	fmt.Printf("Merged (%s) into (%s): %v\n", dspaces[2], dspaces[1], kept.(*TestSwarmManager).dataspaces)
+
+	// Test BisectSwarm
+	_, err = swarmMapper.BisectSwarm(dspaces[3])
+	if err == nil {
+		t.Fatalf("A swarm serving a single dataspace cannot be bisected")
+	}
+	_, err = swarmMapper.BisectSwarm(dspaces[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	bisected, _ := swarmMapper.GetSwarm(dspaces[2])
+	if bisected == kept || len(swarmMapper.GetDataspacesOf(dspaces[1])) != 1 {
+		t.Fatalf("Bisected dataspaces should have their own swarms")
+	}
+	fmt.Printf("Bisected (%s)\n", dspaces[1])
+
+	// Test RemoveSwarm of a shared swarm
+	swarmMapper.MergeSwarms(dspaces[1], dspaces[2])
+	err = swarmMapper.RemoveSwarm(dspaces[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining, err := swarmMapper.GetSwarm(dspaces[2])
+	if err != nil || remaining != kept || kept.(*TestSwarmManager).closed {
+		t.Fatalf("A shared swarm should keep serving its remaining dataspaces")
+	}
+	fmt.Printf("Removed (%s) from its shared swarm\n", dspaces[1])
 }
 
-type TestSwarmManager struct{}
+type TestSwarmManager struct {
+	dataspaces []string
+	closed     bool
+}
+
+func (tm *TestSwarmManager) SetDataspaces(dataspaces []string) {
+	tm.dataspaces = dataspaces
+}
 
 func (tm *TestSwarmManager) Close() error {
+	tm.closed = true
 	return nil
 }
 
@@ -0,0 +1,25 @@
+package metadata
+
+//MetadataMessage describes the EndpointMetadata decoded from a request
+type MetadataMessage interface {
+	GetTransports() []string
+	GetMaxUploadBandwidth() uint64
+	GetStorageSize() uint64
+	GetClientVersion() string
+	GetRegion() string
+}
+
+//RequirementsMessage describes the EndpointRequirements decoded from a request
+type RequirementsMessage interface {
+	GetTransports() []string
+	GetMinUploadBandwidth() uint64
+	GetMinStorageSize() uint64
+	GetMinClientVersion() string
+	GetRegions() []string
+}
+
+//DescribedConn describes a connection that carries the metadata its endpoint declared
+type DescribedConn interface {
+	GetMetadata() Metadata
+	SetMetadata(Metadata)
+}
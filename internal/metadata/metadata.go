@@ -0,0 +1,137 @@
+package metadata
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*Metadata is declared by an endpoint when it logs on to a swarm. Fields
+left unset by the endpoint hold their zero value*/
+type Metadata struct {
+	Transports []string
+	//Kilobits per second
+	MaxUploadBandwidth uint64
+	//Bytes
+	StorageSize   uint64
+	ClientVersion string
+	Region        string
+}
+
+/*Requirements restricts the endpoints a requester may be paired with.
+The zero value of every field matches any endpoint*/
+type Requirements struct {
+	//The endpoint must support at least one of the transports
+	Transports         []string
+	MinUploadBandwidth uint64
+	MinStorageSize     uint64
+	MinClientVersion   string
+	//The endpoint must be in one of the regions
+	Regions []string
+}
+
+//FromMessage converts a decoded MetadataMessage. Anything else yields empty Metadata
+func FromMessage(message interface{}) Metadata {
+	mMessage, ok := message.(MetadataMessage)
+	if !ok {
+		return Metadata{}
+	}
+	return Metadata{
+		Transports:         mMessage.GetTransports(),
+		MaxUploadBandwidth: mMessage.GetMaxUploadBandwidth(),
+		StorageSize:        mMessage.GetStorageSize(),
+		ClientVersion:      mMessage.GetClientVersion(),
+		Region:             mMessage.GetRegion(),
+	}
+}
+
+/*RequirementsFromMessage converts a decoded RequirementsMessage. Anything
+else yields Requirements matching any endpoint*/
+func RequirementsFromMessage(message interface{}) Requirements {
+	rMessage, ok := message.(RequirementsMessage)
+	if !ok {
+		return Requirements{}
+	}
+	return Requirements{
+		Transports:         rMessage.GetTransports(),
+		MinUploadBandwidth: rMessage.GetMinUploadBandwidth(),
+		MinStorageSize:     rMessage.GetMinStorageSize(),
+		MinClientVersion:   rMessage.GetMinClientVersion(),
+		Regions:            rMessage.GetRegions(),
+	}
+}
+
+//Of returns the metadata carried by 'conn' or empty Metadata if it carries none
+func Of(conn interface{}) Metadata {
+	if dConn, ok := conn.(DescribedConn); ok {
+		return dConn.GetMetadata()
+	}
+	return Metadata{}
+}
+
+//IsEmpty returns whether or not the Requirements match any endpoint
+func (r Requirements) IsEmpty() bool {
+	return len(r.Transports) == 0 && r.MinUploadBandwidth == 0 && r.MinStorageSize == 0 &&
+		r.MinClientVersion == "" && len(r.Regions) == 0
+}
+
+//IsMetBy returns whether or not an endpoint declaring 'm' meets the Requirements
+func (r Requirements) IsMetBy(m Metadata) bool {
+	if len(r.Transports) > 0 && !intersects(r.Transports, m.Transports) {
+		return false
+	}
+	if m.MaxUploadBandwidth < r.MinUploadBandwidth || m.StorageSize < r.MinStorageSize {
+		return false
+	}
+	if r.MinClientVersion != "" && CompareVersions(m.ClientVersion, r.MinClientVersion) < 0 {
+		return false
+	}
+	if len(r.Regions) > 0 && !intersects(r.Regions, []string{m.Region}) {
+		return false
+	}
+	return true
+}
+
+/*CompareVersions compares two dotted version strings such as "1.10.2" and
+returns -1, 0 or 1 if 'a' is older, equal or newer than 'b'. Numeric parts
+are compared as numbers and any others as strings. A missing part is older
+than any present one*/
+func CompareVersions(a string, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		if i >= len(aParts) {
+			return -1
+		} else if i >= len(bParts) {
+			return 1
+		}
+		if c := comparePart(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func comparePart(a string, b string) int {
+	aNum, aErr := strconv.ParseUint(a, 10, 64)
+	bNum, bErr := strconv.ParseUint(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		if aNum < bNum {
+			return -1
+		} else if aNum > bNum {
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+func intersects(a []string, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
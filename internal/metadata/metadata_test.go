@@ -0,0 +1,60 @@
+package metadata
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRequirements(t *testing.T) {
+	fmt.Printf("----------------------\nREQUIREMENTS TEST\n----------------------\n")
+	desktop := Metadata{Transports: []string{"webrtc", "tcp"}, MaxUploadBandwidth: 10000,
+		StorageSize: 1 << 30, ClientVersion: "1.10.0", Region: "eu-west"}
+	mobile := Metadata{Transports: []string{"webrtc"}, MaxUploadBandwidth: 500,
+		StorageSize: 1 << 20, ClientVersion: "1.9.3", Region: "us-east"}
+
+	tests := []struct {
+		name         string
+		requirements Requirements
+		desktop      bool
+		mobile       bool
+	}{
+		{"none", Requirements{}, true, true},
+		{"tcp", Requirements{Transports: []string{"tcp", "quic"}}, true, false},
+		{"bandwidth", Requirements{MinUploadBandwidth: 1000}, true, false},
+		{"storage", Requirements{MinStorageSize: 1 << 20}, true, true},
+		{"version", Requirements{MinClientVersion: "1.10"}, true, false},
+		{"region", Requirements{Regions: []string{"us-east", "us-west"}}, false, true},
+	}
+	for _, test := range tests {
+		desktopMeets := test.requirements.IsMetBy(desktop)
+		mobileMeets := test.requirements.IsMetBy(mobile)
+		fmt.Printf("\t%s: desktop=%t mobile=%t\n", test.name, desktopMeets, mobileMeets)
+		if desktopMeets != test.desktop || mobileMeets != test.mobile {
+			t.Fatalf("Unexpected match for %s requirements", test.name)
+		}
+	}
+	if !(Requirements{}).IsEmpty() || (Requirements{Regions: []string{"eu-west"}}).IsEmpty() {
+		t.Fatalf("IsEmpty should only hold for requirements matching any endpoint")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	fmt.Printf("----------------------\nCOMPARE VERSIONS TEST\n----------------------\n")
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.10.0", "1.9.3", 1},
+		{"v2.0", "2.0", 0},
+		{"1.2", "1.2.1", -1},
+		{"1.2.beta", "1.2.alpha", 1},
+		{"", "0.1", -1},
+	}
+	for _, test := range tests {
+		result := CompareVersions(test.a, test.b)
+		fmt.Printf("\t%q vs %q: %d\n", test.a, test.b, result)
+		if result != test.expected {
+			t.Fatalf("Expected %d comparing %q to %q, got %d", test.expected, test.a, test.b, result)
+		}
+	}
+}
@@ -1,6 +1,36 @@
 package negotiator
 
 type UnmarshalNegotiateMessage func(b []byte) (interface{}, error)
+
+/*MarshalNegotiateMessage describes a function that encodes the acceptance
+flag and payload of a NegotiateMessage*/
+type MarshalNegotiateMessage func(accepted bool, data []byte) ([]byte, error)
+
+/*MarshalSignalMessage describes a function that encodes a typed WebRTC
+signaling message*/
+type MarshalSignalMessage func(signal Signal) ([]byte, error)
+
 type NegotiateMessage interface {
 	IsAccepted() bool
+	GetMessageData() []byte
+}
+
+/*SignalMessage describes a NegotiateMessage that may carry a typed WebRTC
+signaling message. A signal type of SignalOpaque leaves it opaque*/
+type SignalMessage interface {
+	GetSignalType() int32
+	GetSdp() string
+	GetCandidate() interface{}
+}
+
+type IceCandidate interface {
+	GetCandidate() string
+	GetSdpMid() string
+	GetSdpMLineIndex() uint32
+	GetUsernameFragment() string
+}
+
+//EncodedConn describes a connection that knows the wire encoding its peer speaks
+type EncodedConn interface {
+	GetEncoding() string
 }
@@ -1,52 +1,261 @@
 package negotiator
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/arstevens/go-hive-signal/internal/manager"
+	"github.com/arstevens/go-hive-signal/internal/wrapper"
 )
 
 var RoundtripLimit = 5
 var UnmarshalMessage UnmarshalNegotiateMessage = nil
 
-var readErrorString = "Failed to read message in RoundtripLimitedNegotiate(): %v"
-var writeErrorString = "Failed to write message in RoundtripLimitedNegotiate(): %v"
+/*Unmarshalers and Marshalers hold the NegotiateMessage codec of each wire
+encoding. They are used to translate messages between parties that do not
+speak the same encoding*/
+var Unmarshalers = make(map[string]UnmarshalNegotiateMessage)
+var Marshalers = make(map[string]MarshalNegotiateMessage)
+
+var readErrorString = "Failed to read message in %s(): %v"
+var writeErrorString = "Failed to write message in %s(): %v"
+
+/*NegotiationBudget bounds the total time of a negotiation run by
+TimeBudgetedNegotiate*/
+var NegotiationBudget = time.Second * 30
+
+/*MessageIdleTimeout bounds the time TimeBudgetedNegotiate waits for
+each offer or response to be relayed*/
+var MessageIdleTimeout = time.Second * 5
+
+//Timeout errors returned by TimeBudgetedNegotiate. Test for them with errors.Is
+var (
+	ErrNegotiationTimeout = errors.New("Negotiation exceeded its time budget")
+	ErrIdleTimeout        = errors.New("Negotiating party went idle")
+)
+
+/*Strategies maps the names of the negotiation strategies that can be
+configured to their AgentNegotiator*/
+var Strategies = map[string]manager.AgentNegotiator{
+	"RoundtripLimited": RoundtripLimitedNegotiate,
+	"TimeBudgeted":     TimeBudgetedNegotiate,
+}
+
+/*RoundtripLimitedNegotiate relays offers and responses between 'offerer' and
+'acceptor' until the acceptor accepts or RoundtripLimit is reached. Once
+'ctx' is done any blocked read or write on either conn is abandoned. Failures
+caused by the offerer are returned as a *manager.OffererError. A WebRTC
+answer is followed by a trickle of ICE candidates. The offerer is closed
+if its side of the trickle does not end cleanly*/
+func RoundtripLimitedNegotiate(ctx context.Context, offerer manager.Conn, acceptor manager.Conn) error {
+	unbind := manager.BindContext(ctx, offerer, acceptor)
+	defer unbind()
+
+	for i := 0; i < RoundtripLimit; i++ {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Negotiation abandoned in RoundtripLimitedNegotiate(): %v", ctx.Err())
+		}
+		err := relayOffer(offerer, acceptor)
+		if err != nil {
+			return err
+		}
+		response, err := relayResponse(acceptor, offerer)
+		if err != nil {
+			return err
+		}
+		if isAnswer(response) {
+			if signalTypeOf(response) == SignalAnswer {
+				return trickle(ctx, offerer, acceptor)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("Roundtrip limit reached without consensus in RountripLimitedNegotiate()")
+}
+
+/*TimeBudgetedNegotiate relays offers and responses like RoundtripLimitedNegotiate
+but also gives up once the negotiation took NegotiationBudget or either party
+took MessageIdleTimeout to send or take a single message. Running out of time
+is reported with ErrNegotiationTimeout or ErrIdleTimeout respectively. The
+trickle that follows a WebRTC answer is bound by NegotiationBudget as well*/
+func TimeBudgetedNegotiate(ctx context.Context, offerer manager.Conn, acceptor manager.Conn) error {
+	budget, cancel := context.WithTimeout(ctx, NegotiationBudget)
+	defer cancel()
 
-func RoundtripLimitedNegotiate(offerer manager.Conn, acceptor manager.Conn) error {
 	for i := 0; i < RoundtripLimit; i++ {
-		rawOffer, err := readMessageFromWire(offerer)
+		if ctx.Err() != nil {
+			return fmt.Errorf("Negotiation abandoned in TimeBudgetedNegotiate(): %v", ctx.Err())
+		}
+		err := withinIdleTimeout(ctx, budget, func() error {
+			return relayOffer(offerer, acceptor)
+		}, offerer, acceptor)
 		if err != nil {
-			return fmt.Errorf(readErrorString, err)
+			return err
 		}
-		err = writeMessageToWire(acceptor, rawOffer)
+		var response NegotiateMessage
+		err = withinIdleTimeout(ctx, budget, func() error {
+			var relayErr error
+			response, relayErr = relayResponse(acceptor, offerer)
+			return relayErr
+		}, offerer, acceptor)
 		if err != nil {
-			return fmt.Errorf(writeErrorString, err)
+			return err
 		}
+		if isAnswer(response) {
+			if signalTypeOf(response) == SignalAnswer {
+				err = trickle(budget, offerer, acceptor)
+				if err == nil || ctx.Err() != nil || !expired(budget) {
+					return err
+				}
+				timeout := fmt.Errorf("%w in TimeBudgetedNegotiate(): %v", ErrNegotiationTimeout, err)
+				var offererErr *manager.OffererError
+				if errors.As(err, &offererErr) {
+					return &manager.OffererError{Err: timeout}
+				}
+				return timeout
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("Roundtrip limit reached without consensus in TimeBudgetedNegotiate()")
+}
+
+/*withinIdleTimeout runs 'relay' with the I/O on 'conns' bound to the sooner
+of MessageIdleTimeout and the end of 'budget'. Failures caused by either
+running out are turned into the matching timeout error while keeping the
+party they are blamed on*/
+func withinIdleTimeout(ctx context.Context, budget context.Context, relay func() error, conns ...manager.Conn) error {
+	idle, cancel := context.WithTimeout(budget, MessageIdleTimeout)
+	defer cancel()
+	unbind := manager.BindContext(idle, conns...)
+	err := relay()
+	unbind()
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	var timeout error
+	if expired(budget) {
+		timeout = fmt.Errorf("%w in TimeBudgetedNegotiate(): %v", ErrNegotiationTimeout, err)
+	} else if expired(idle) {
+		timeout = fmt.Errorf("%w in TimeBudgetedNegotiate(): %v", ErrIdleTimeout, err)
+	} else {
+		return err
+	}
+	var offererErr *manager.OffererError
+	if errors.As(err, &offererErr) {
+		return &manager.OffererError{Err: timeout}
+	}
+	return timeout
+}
+
+/*expired returns whether or not the deadline of 'ctx' has passed. Conn
+deadlines may fire before the context notices so its error is not enough*/
+func expired(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	return ctx.Err() != nil || (ok && !time.Now().Before(deadline))
+}
 
+/*relayOffer reads an offer from 'offerer' and writes it to 'acceptor'.
+Failures caused by the offerer are returned as a *manager.OffererError*/
+func relayOffer(offerer manager.Conn, acceptor manager.Conn) error {
+	rawOffer, err := readMessageFromWire(offerer)
+	if err != nil {
+		return &manager.OffererError{Err: fmt.Errorf(readErrorString, "relayOffer", err)}
+	}
+	rawOffer, err = transcode(rawOffer, offerer, acceptor)
+	if err != nil {
+		return &manager.OffererError{Err: fmt.Errorf("Failed to translate offer in relayOffer(): %v", err)}
+	}
+	err = writeMessageToWire(acceptor, rawOffer)
+	if err != nil {
+		return fmt.Errorf(writeErrorString, "relayOffer", err)
+	}
+	return nil
+}
+
+/*relayResponse reads a response from 'acceptor' and writes it to 'offerer'.
+Candidates the acceptor trickles ahead of its response are relayed as well*/
+func relayResponse(acceptor manager.Conn, offerer manager.Conn) (NegotiateMessage, error) {
+	for {
 		rawResponse, err := readMessageFromWire(acceptor)
 		if err != nil {
-			return fmt.Errorf(readErrorString, err)
+			return nil, fmt.Errorf(readErrorString, "relayResponse", err)
 		}
-		ifaceMsg, err := UnmarshalMessage(rawResponse)
+		message, err := unmarshalFrom(acceptor, rawResponse)
 		if err != nil {
-			return fmt.Errorf("Failed to unmarshal response in RoundtripLimitedNegotitate(): %v", err)
+			return nil, fmt.Errorf("Failed to unmarshal response in relayResponse(): %v", err)
+		}
+		rawResponse, err = transcode(rawResponse, acceptor, offerer)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to translate response in relayResponse(): %v", err)
 		}
-		message := ifaceMsg.(NegotiateMessage)
 
 		err = writeMessageToWire(offerer, rawResponse)
 		if err != nil {
-			return fmt.Errorf(writeErrorString, err)
+			return nil, &manager.OffererError{Err: fmt.Errorf(writeErrorString, "relayResponse", err)}
 		}
-		if message.IsAccepted() {
-			return nil
+		if signalTypeOf(message) != SignalCandidate {
+			return message, nil
 		}
 	}
-	return fmt.Errorf("Roundtrip limit reached without consensus in RountripLimitedNegotiate()")
+}
+
+/*transcode re-encodes a message read from 'from' into the encoding spoken
+by 'to'. Messages pass through untouched when both ends share an encoding*/
+func transcode(raw []byte, from interface{}, to interface{}) ([]byte, error) {
+	fromEncoding := encodingOf(from)
+	toEncoding := encodingOf(to)
+	if fromEncoding == toEncoding || fromEncoding == "" || toEncoding == "" {
+		return raw, nil
+	}
+
+	message, err := unmarshalFrom(from, raw)
+	if err != nil {
+		return nil, err
+	}
+	if sMessage, ok := message.(SignalMessage); ok && sMessage.GetSignalType() != SignalOpaque {
+		marshalSignal, ok := SignalMarshalers[toEncoding]
+		if !ok {
+			return nil, fmt.Errorf("No signal marshaler for encoding %s", toEncoding)
+		}
+		return marshalSignal(signalOf(sMessage))
+	}
+	marshal, ok := Marshalers[toEncoding]
+	if !ok {
+		return nil, fmt.Errorf("No marshaler for encoding %s", toEncoding)
+	}
+	return marshal(message.IsAccepted(), message.GetMessageData())
+}
+
+func unmarshalFrom(conn interface{}, raw []byte) (NegotiateMessage, error) {
+	unmarshal := UnmarshalMessage
+	if u, ok := Unmarshalers[encodingOf(conn)]; ok {
+		unmarshal = u
+	}
+	ifaceMsg, err := unmarshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return ifaceMsg.(NegotiateMessage), nil
+}
+
+func encodingOf(conn interface{}) string {
+	if eConn, ok := conn.(EncodedConn); ok {
+		return eConn.GetEncoding()
+	}
+	return ""
 }
 
 func writeMessageToWire(conn io.Writer, msg []byte) error {
+	if mConn, ok := conn.(wrapper.MessageConn); ok {
+		return mConn.WriteMessage(msg)
+	}
+
 	var size int32
 	size = int32(len(msg))
 	err := binary.Write(conn, binary.BigEndian, size)
@@ -62,6 +271,10 @@ func writeMessageToWire(conn io.Writer, msg []byte) error {
 }
 
 func readMessageFromWire(conn io.Reader) ([]byte, error) {
+	if mConn, ok := conn.(wrapper.MessageConn); ok {
+		return mConn.ReadMessage()
+	}
+
 	var size int32
 	err := binary.Read(conn, binary.BigEndian, &size)
 	if err != nil {
@@ -1,10 +1,17 @@
 package negotiator
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/manager"
 )
 
 func TestRountripLimitNegotiate(t *testing.T) {
@@ -19,10 +26,227 @@ func TestRountripLimitNegotiate(t *testing.T) {
 	writeNOffers(&offerer, numberOfTrips)
 	writeNResponses(&acceptor, numberOfTrips)
 
-	err := RoundtripLimitedNegotiate(&offerer, &acceptor)
+	err := RoundtripLimitedNegotiate(context.Background(), &offerer, &acceptor)
+	fmt.Printf("Status: %v\n", err)
+}
+
+func TestCancelledNegotiate(t *testing.T) {
+	fmt.Printf("----------------------\nCANCELLED NEGOTIATE TEST\n----------------------\n")
+	UnmarshalMessage = unmarshal
+
+	offerer, offererPeer := net.Pipe()
+	acceptor, acceptorPeer := net.Pipe()
+	defer offererPeer.Close()
+	defer acceptorPeer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	start := time.Now()
+	err := RoundtripLimitedNegotiate(ctx, &PipeConn{offerer}, &PipeConn{acceptor})
+	fmt.Printf("Status after %v: %v\n", time.Since(start), err)
+	if err == nil {
+		t.Fatalf("Negotiation with silent peers should fail once the deadline passes")
+	}
+	var offererErr *manager.OffererError
+	if !errors.As(err, &offererErr) {
+		t.Fatalf("Expected the silent offerer to be blamed for the failure")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Negotiation was not unblocked by the deadline")
+	}
+
+	//Deadlines are cleared once the negotiation is over
+	go offererPeer.Write([]byte{1})
+	one := make([]byte, 1)
+	if _, err = offerer.Read(one); err != nil {
+		t.Fatalf("Conn still bound to the deadline after negotiation: %v", err)
+	}
+}
+
+func TestTimeBudgetedNegotiate(t *testing.T) {
+	fmt.Printf("----------------------\nTIME BUDGETED NEGOTIATE TEST\n----------------------\n")
+	UnmarshalMessage = unmarshal
+	defer func() { NegotiationBudget, MessageIdleTimeout = time.Second*30, time.Second*5 }()
+
+	offerer := FakeConn{buf: make([]byte, 1000)}
+	acceptor := FakeConn{buf: make([]byte, 1000)}
+	writeNOffers(&offerer, 2)
+	writeNResponses(&acceptor, 2)
+	err := TimeBudgetedNegotiate(context.Background(), &offerer, &acceptor)
 	fmt.Printf("Status: %v\n", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//A silent offerer is blamed once it goes idle
+	NegotiationBudget, MessageIdleTimeout = time.Second, time.Millisecond*20
+	silent, silentPeer := net.Pipe()
+	listener, listenerPeer := net.Pipe()
+	defer silentPeer.Close()
+	defer listenerPeer.Close()
+	err = TimeBudgetedNegotiate(context.Background(), &PipeConn{silent}, &PipeConn{listener})
+	fmt.Printf("Status with an idle offerer: %v\n", err)
+	var offererErr *manager.OffererError
+	if !errors.Is(err, ErrIdleTimeout) || !errors.As(err, &offererErr) {
+		t.Fatalf("Expected an idle timeout blamed on the offerer, got %v", err)
+	}
+
+	//The budget runs out before the idle timeout does
+	NegotiationBudget, MessageIdleTimeout = time.Millisecond*20, time.Second
+	err = TimeBudgetedNegotiate(context.Background(), &PipeConn{silent}, &PipeConn{listener})
+	fmt.Printf("Status with an exhausted budget: %v\n", err)
+	if !errors.Is(err, ErrNegotiationTimeout) {
+		t.Fatalf("Expected the negotiation to run out of its time budget, got %v", err)
+	}
 }
 
+func TestTrickle(t *testing.T) {
+	fmt.Printf("----------------------\nTRICKLE TEST\n----------------------\n")
+	UnmarshalMessage = unmarshalSignal
+	defer func() { UnmarshalMessage, TrickleTimeout = unmarshal, time.Second*10 }()
+
+	offerer, offererPeer := net.Pipe()
+	acceptor, acceptorPeer := net.Pipe()
+	defer offererPeer.Close()
+	defer acceptorPeer.Close()
+	negotiated := make(chan error)
+	go func() {
+		negotiated <- RoundtripLimitedNegotiate(context.Background(), &PipeConn{offerer}, &PipeConn{acceptor})
+	}()
+
+	offererReceived := make(chan []string)
+	go func() {
+		writeMessageToWire(offererPeer, []byte("offer"))
+		readMessageFromWire(offererPeer)
+		offererReceived <- trickleFrom(offererPeer, "candidate:1")
+	}()
+	readMessageFromWire(acceptorPeer)
+	writeMessageToWire(acceptorPeer, []byte("answer"))
+	acceptorReceived := trickleFrom(acceptorPeer, "candidate:2", "candidate:3")
+
+	err := <-negotiated
+	fromAcceptor := <-offererReceived
+	fmt.Printf("Offerer received %v and acceptor received %v\n", fromAcceptor, acceptorReceived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fromAcceptor) != 2 || len(acceptorReceived) != 1 {
+		t.Fatalf("Expected every candidate to be relayed")
+	}
+
+	//An offerer that never ends its candidates fails the negotiation and is closed
+	TrickleTimeout = time.Millisecond * 20
+	go func() {
+		negotiated <- RoundtripLimitedNegotiate(context.Background(), &PipeConn{offerer}, &PipeConn{acceptor})
+	}()
+	go func() {
+		writeMessageToWire(offererPeer, []byte("offer"))
+		readMessageFromWire(offererPeer)
+	}()
+	readMessageFromWire(acceptorPeer)
+	writeMessageToWire(acceptorPeer, []byte("answer"))
+	select {
+	case err = <-negotiated:
+		fmt.Printf("Status with an unfinished trickle: %v\n", err)
+		var offererErr *manager.OffererError
+		if !errors.As(err, &offererErr) {
+			t.Fatalf("Expected an unfinished trickle to be blamed on the offerer, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Trickle outlived TrickleTimeout")
+	}
+	if _, err = offerer.Write([]byte("candidate")); err == nil {
+		t.Fatalf("Expected the offerer of an unfinished trickle to be closed")
+	}
+
+	//An acceptor that leaves mid trickle fails the negotiation but the offerer is drained and kept
+	offerer, offererPeer = net.Pipe()
+	defer offererPeer.Close()
+	leaving, leavingPeer := net.Pipe()
+	TrickleTimeout = time.Second
+	go func() {
+		negotiated <- RoundtripLimitedNegotiate(context.Background(), &PipeConn{offerer}, &PipeConn{leaving})
+	}()
+	left := make(chan struct{})
+	go func() {
+		writeMessageToWire(offererPeer, []byte("offer"))
+		readMessageFromWire(offererPeer)
+		<-left
+		writeMessageToWire(offererPeer, []byte("candidate:1"))
+		writeMessageToWire(offererPeer, []byte("end"))
+	}()
+	readMessageFromWire(leavingPeer)
+	writeMessageToWire(leavingPeer, []byte("answer"))
+	leavingPeer.Close()
+	close(left)
+	select {
+	case err = <-negotiated:
+		fmt.Printf("Status with an acceptor that left: %v\n", err)
+		var offererErr *manager.OffererError
+		if err == nil || errors.As(err, &offererErr) {
+			t.Fatalf("Expected a trickle the acceptor left to fail without blaming the offerer, got %v", err)
+		}
+	case <-time.After(time.Millisecond * 500):
+		t.Fatalf("Trickle waited out TrickleTimeout after the offerer ended its candidates")
+	}
+	go writeMessageToWire(offerer, []byte("offer"))
+	offererPeer.SetReadDeadline(time.Now().Add(time.Second))
+	if raw, err := readMessageFromWire(offererPeer); err != nil || string(raw) != "offer" {
+		t.Fatalf("Expected the drained offerer to be kept open and in sync, got %s (%v)", raw, err)
+	}
+	offererPeer.SetReadDeadline(time.Time{})
+
+	//The trickle of a time budgeted negotiation is bound by its budget
+	offerer, offererPeer = net.Pipe()
+	defer offererPeer.Close()
+	NegotiationBudget, TrickleTimeout = time.Millisecond*20, time.Second
+	defer func() { NegotiationBudget = time.Second * 30 }()
+	go func() {
+		negotiated <- TimeBudgetedNegotiate(context.Background(), &PipeConn{offerer}, &PipeConn{acceptor})
+	}()
+	go func() {
+		writeMessageToWire(offererPeer, []byte("offer"))
+		readMessageFromWire(offererPeer)
+	}()
+	readMessageFromWire(acceptorPeer)
+	writeMessageToWire(acceptorPeer, []byte("answer"))
+	select {
+	case err = <-negotiated:
+		fmt.Printf("Status with a trickle over budget: %v\n", err)
+		if !errors.Is(err, ErrNegotiationTimeout) {
+			t.Fatalf("Expected a trickle over budget to report ErrNegotiationTimeout, got %v", err)
+		}
+	case <-time.After(time.Millisecond * 500):
+		t.Fatalf("Trickle outlived NegotiationBudget")
+	}
+}
+
+/*trickleFrom sends 'candidates' followed by an end marker on 'conn' while
+collecting the candidates of the other party until its end marker*/
+func trickleFrom(conn net.Conn, candidates ...string) []string {
+	go func() {
+		for _, candidate := range candidates {
+			writeMessageToWire(conn, []byte(candidate))
+		}
+		writeMessageToWire(conn, []byte("end"))
+	}()
+
+	received := make([]string, 0)
+	for {
+		raw, err := readMessageFromWire(conn)
+		if err != nil || string(raw) == "end" {
+			return received
+		}
+		received = append(received, string(raw))
+	}
+}
+
+type PipeConn struct {
+	net.Conn
+}
+
+func (pc *PipeConn) GetAddress() string { return pc.RemoteAddr().String() }
+
 func writeNResponses(conn io.Writer, n int) {
 	resp1 := []byte("false")
 	resp2 := []byte("true")
@@ -59,6 +283,35 @@ func writeNOffers(conn io.Writer, n int) {
 	}
 }
 
+func TestTranscode(t *testing.T) {
+	fmt.Printf("----------------------\nTRANSCODE TEST\n----------------------\n")
+	Unmarshalers["upper"] = func(b []byte) (interface{}, error) {
+		return &message{isAccepted: b[0] == 'T', data: b[1:]}, nil
+	}
+	Marshalers["lower"] = func(accepted bool, data []byte) ([]byte, error) {
+		return []byte(fmt.Sprintf("%t:%s", accepted, strings.ToLower(string(data)))), nil
+	}
+
+	upper := &EncodedFakeConn{encoding: "upper"}
+	lower := &EncodedFakeConn{encoding: "lower"}
+	raw, err := transcode([]byte("TOFFER"), upper, lower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Transcoded: %s\n", string(raw))
+	if string(raw) != "true:offer" {
+		t.Fatalf("Unexpected transcoded message %s", string(raw))
+	}
+
+	raw, err = transcode([]byte("TOFFER"), upper, upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "TOFFER" {
+		t.Fatalf("Message between matching encodings was modified: %s", string(raw))
+	}
+}
+
 func TestMessageRead(t *testing.T) {
 	fmt.Printf("----------------------\nMESSAGE READ TEST\n----------------------\n")
 	conn := FakeConn{buf: make([]byte, 100), head: 0, tail: 0}
@@ -106,9 +359,29 @@ func unmarshal(b []byte) (interface{}, error) {
 
 type message struct {
 	isAccepted bool
+	data       []byte
 }
 
-func (m *message) IsAccepted() bool { return m.isAccepted }
+func (m *message) IsAccepted() bool       { return m.isAccepted }
+func (m *message) GetMessageData() []byte { return m.data }
+
+func unmarshalSignal(b []byte) (interface{}, error) {
+	signalTypes := map[string]int32{"offer": SignalOffer, "answer": SignalAnswer, "end": SignalEndOfCandidates}
+	signalType, ok := signalTypes[string(b)]
+	if !ok {
+		signalType = SignalCandidate
+	}
+	return &signal{signalType: signalType}, nil
+}
+
+type signal struct {
+	message
+	signalType int32
+}
+
+func (s *signal) GetSignalType() int32      { return s.signalType }
+func (s *signal) GetSdp() string            { return "" }
+func (s *signal) GetCandidate() interface{} { return nil }
 
 type FakeConn struct {
 	buf  []byte
@@ -140,3 +413,10 @@ func (fc *FakeConn) Write(b []byte) (int, error) {
 func (fc *FakeConn) Close() error {
 	return nil
 }
+
+type EncodedFakeConn struct {
+	FakeConn
+	encoding string
+}
+
+func (ec *EncodedFakeConn) GetEncoding() string { return ec.encoding }
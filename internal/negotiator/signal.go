@@ -0,0 +1,161 @@
+package negotiator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/manager"
+)
+
+//Signal types of WebRTC signaling messages. Values match SignalType in messages.proto
+const (
+	SignalOpaque = int32(iota)
+	SignalOffer
+	SignalAnswer
+	SignalCandidate
+	SignalEndOfCandidates
+)
+
+/*TrickleTimeout bounds the time ICE candidates are relayed between both
+parties after the acceptor answered an offer*/
+var TrickleTimeout = time.Second * 10
+
+/*SignalMarshalers hold the typed NegotiateMessage encoder of each wire
+encoding. They are used instead of Marshalers to translate WebRTC
+signaling messages*/
+var SignalMarshalers = make(map[string]MarshalSignalMessage)
+
+//Signal holds the fields of a WebRTC signaling message
+type Signal struct {
+	Type      int32
+	SDP       string
+	Candidate *Candidate
+}
+
+//Candidate holds the fields of a trickled ICE candidate
+type Candidate struct {
+	Candidate        string
+	SDPMid           string
+	SDPMLineIndex    uint32
+	UsernameFragment string
+}
+
+/*trickle relays candidates in both directions between 'offerer' and
+'acceptor' until each of them sent SignalEndOfCandidates or TrickleTimeout
+passes. Failures caused by the offerer are returned as a *manager.OffererError
+and close the offerer since they may leave unrelayed candidates on its
+stream. When the acceptor fails the rest of the candidates of the offerer
+are drained instead so that it can be released for the next negotiation*/
+func trickle(ctx context.Context, offerer manager.Conn, acceptor manager.Conn) error {
+	trickleCtx, cancel := context.WithTimeout(ctx, TrickleTimeout)
+	defer cancel()
+	acceptorCtx, abandon := context.WithCancel(trickleCtx)
+	defer abandon()
+	unbindOfferer := manager.BindContext(trickleCtx, offerer)
+	unbindAcceptor := manager.BindContext(acceptorCtx, acceptor)
+
+	//Offerer failures stop both directions while acceptor failures only stop the acceptor
+	stop := func(err error) {
+		var offererErr *manager.OffererError
+		if errors.As(err, &offererErr) {
+			cancel()
+		} else if err != nil {
+			abandon()
+		}
+	}
+	var offererErr error
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		offererErr = relayCandidates(offerer, acceptor, offerer)
+		stop(offererErr)
+	}()
+	err := relayCandidates(acceptor, offerer, offerer)
+	stop(err)
+	<-finished
+	unbindAcceptor()
+	unbindOfferer()
+
+	var blamed *manager.OffererError
+	if errors.As(offererErr, &blamed) || err == nil {
+		err = offererErr
+	}
+	if errors.As(err, &blamed) {
+		offerer.Close()
+		return &manager.OffererError{Err: fmt.Errorf("Failed to trickle candidates in trickle(): %v", err)}
+	} else if err != nil {
+		return fmt.Errorf("Failed to trickle candidates in trickle(): %v", err)
+	}
+	return nil
+}
+
+/*relayCandidates relays messages from 'from' to 'to' up to and including
+the end-of-candidates marker of 'from'. Failures caused by 'offerer' are
+returned as a *manager.OffererError. Once the acceptor cannot be written
+to the candidates of the offerer are still read up to its marker*/
+func relayCandidates(from manager.Conn, to manager.Conn, offerer manager.Conn) error {
+	blame := func(party manager.Conn, err error) error {
+		if party == offerer {
+			return &manager.OffererError{Err: err}
+		}
+		return err
+	}
+	var writeErr error
+	for {
+		raw, err := readMessageFromWire(from)
+		if err != nil {
+			return blame(from, fmt.Errorf(readErrorString, "relayCandidates", err))
+		}
+		message, err := unmarshalFrom(from, raw)
+		if err != nil {
+			return blame(from, fmt.Errorf("Failed to unmarshal candidate in relayCandidates(): %v", err))
+		}
+		if writeErr == nil {
+			raw, err = transcode(raw, from, to)
+			if err != nil {
+				return blame(from, fmt.Errorf("Failed to translate candidate in relayCandidates(): %v", err))
+			}
+			err = writeMessageToWire(to, raw)
+			if err != nil && to == offerer {
+				return blame(to, fmt.Errorf(writeErrorString, "relayCandidates", err))
+			}
+			writeErr = err
+		}
+		if signalTypeOf(message) == SignalEndOfCandidates {
+			if writeErr != nil {
+				return fmt.Errorf(writeErrorString, "relayCandidates", writeErr)
+			}
+			return nil
+		}
+	}
+}
+
+/*isAnswer returns whether or not 'message' accepts an offer. Opaque
+messages accept with their flag and WebRTC ones by being an answer*/
+func isAnswer(message NegotiateMessage) bool {
+	return message.IsAccepted() || signalTypeOf(message) == SignalAnswer
+}
+
+//signalTypeOf returns the signal type of 'message' or SignalOpaque if it is untyped
+func signalTypeOf(message NegotiateMessage) int32 {
+	if sMessage, ok := message.(SignalMessage); ok {
+		return sMessage.GetSignalType()
+	}
+	return SignalOpaque
+}
+
+//signalOf copies the fields of a typed 'message' into a Signal
+func signalOf(message SignalMessage) Signal {
+	signal := Signal{Type: message.GetSignalType(), SDP: message.GetSdp()}
+	if candidate, ok := message.GetCandidate().(IceCandidate); ok {
+		signal.Candidate = &Candidate{
+			Candidate:        candidate.GetCandidate(),
+			SDPMid:           candidate.GetSdpMid(),
+			SDPMLineIndex:    candidate.GetSdpMLineIndex(),
+			UsernameFragment: candidate.GetUsernameFragment(),
+		}
+	}
+	return signal
+}
@@ -0,0 +1,14 @@
+package protocol
+
+/*VersionedRequest is the type of routed request that
+carries the protocol spoken by its client*/
+type VersionedRequest interface {
+	GetVersion() int32
+	GetCapabilities() uint64
+}
+
+/*NegotiatedConn is a type of connection that records the protocol
+version and capabilities negotiated with its client*/
+type NegotiatedConn interface {
+	SetProtocol(version int32, capabilities uint64)
+}
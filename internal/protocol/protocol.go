@@ -0,0 +1,129 @@
+package protocol
+
+import (
+	"fmt"
+
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-request/handle"
+	"github.com/arstevens/go-request/route"
+)
+
+/*LegacyVersion is assumed for clients that send no protocol version. Clients
+speaking it are given no capabilities and are served the baseline protocol:
+the only Response they are sent is the single OperationSuccess byte once
+their endpoint joined a swarm, they cannot open sessions and they are never
+sent Notices*/
+const LegacyVersion = responder.LegacyVersion
+
+/*CurrentVersion is the newest protocol version the server speaks. Version 2
+introduces capability negotiation: the capabilities sent in the route envelope
+are only honoured from version 2 on and every optional behavior is gated by a
+capability bit rather than by the version itself*/
+const CurrentVersion int32 = 2
+
+//MinimumVersion is the oldest protocol version the server accepts
+var MinimumVersion = LegacyVersion
+
+const (
+	//CapabilitySessions marks clients that multiplex requests over a session
+	CapabilitySessions uint64 = 1 << iota
+	/*CapabilityNotices marks clients that accept Notices. Endpoints accept them
+	while idle and requesters while they are paired. Heartbeat, LatencyReports and
+	Migration are only honoured along with it*/
+	CapabilityNotices
+	//CapabilityHeartbeat marks endpoints that answer ping Notices while idle
+	CapabilityHeartbeat
+	//CapabilityLatencyReports marks endpoints that report their round trip times to peers before answering pings
+	CapabilityLatencyReports
+	//CapabilityMigration marks endpoints that can be moved to the swarm of another dataspace while idle
+	CapabilityMigration
+)
+
+/*SupportedCapabilities holds every capability bit understood by the
+server. Capabilities holds the name of each bit for configuration*/
+var SupportedCapabilities = CapabilitySessions | CapabilityNotices | CapabilityHeartbeat | CapabilityLatencyReports |
+	CapabilityMigration
+var Capabilities = map[string]uint64{
+	"Sessions":       CapabilitySessions,
+	"Notices":        CapabilityNotices,
+	"Heartbeat":      CapabilityHeartbeat,
+	"LatencyReports": CapabilityLatencyReports,
+	"Migration":      CapabilityMigration,
+}
+
+/*Negotiate returns the highest protocol version common to the server and
+a client supporting up to 'version' along with the capabilities both ends
+share. Legacy clients share no capabilities. An error is returned if the
+client is older than MinimumVersion*/
+func Negotiate(version int32, capabilities uint64) (int32, uint64, error) {
+	if version <= 0 {
+		version = LegacyVersion
+	}
+	if version > CurrentVersion {
+		version = CurrentVersion
+	}
+	if version < MinimumVersion {
+		return 0, 0, responder.Errorf(responder.IncompatibleProtocol,
+			"Protocol version %d is older than the minimum supported version %d", version, MinimumVersion)
+	}
+	if version == LegacyVersion {
+		return version, 0, nil
+	}
+	return version, capabilities & SupportedCapabilities, nil
+}
+
+/*NewNegotiatingReader wraps 'read' so the protocol of every request is
+negotiated before it is routed. The result is recorded on connections
+implementing NegotiatedConn. Incompatible clients are sent an error
+Response describing the versions the server supports and are dropped*/
+func NewNegotiatingReader(read route.ReadRequest, unpack route.UnpackRouteRequest) route.ReadRequest {
+	return func(conn handle.Conn) ([]byte, error) {
+		raw, err := read(conn)
+		if err != nil {
+			return nil, err
+		}
+		request, err := unpack(raw)
+		if err != nil {
+			//Left for the router to report and drop
+			return raw, nil
+		}
+
+		var version int32
+		var capabilities uint64
+		if vRequest, ok := request.(VersionedRequest); ok {
+			version = vRequest.GetVersion()
+			capabilities = vRequest.GetCapabilities()
+		}
+		negotiatedVersion, negotiatedCapabilities, err := Negotiate(version, capabilities)
+		if err != nil {
+			setProtocol(conn, CurrentVersion, SupportedCapabilities)
+			if rErr := responder.Failure(conn, err, responder.IncompatibleProtocol); rErr != nil {
+				err = fmt.Errorf("%v (%v)", err, rErr)
+			}
+			conn.Close()
+			return nil, fmt.Errorf("Rejected client in NegotiatingReader: %v", err)
+		}
+		setProtocol(conn, negotiatedVersion, negotiatedCapabilities)
+		return raw, nil
+	}
+}
+
+func setProtocol(conn handle.Conn, version int32, capabilities uint64) {
+	if nConn, ok := conn.(NegotiatedConn); ok {
+		nConn.SetProtocol(version, capabilities)
+	}
+}
+
+/*ParseCapabilities converts capability names into a capability bitmap. An
+error is returned for names that are not in Capabilities*/
+func ParseCapabilities(names []string) (uint64, error) {
+	var capabilities uint64
+	for _, name := range names {
+		bit, ok := Capabilities[name]
+		if !ok {
+			return 0, fmt.Errorf("Unknown capability %s in ParseCapabilities()", name)
+		}
+		capabilities |= bit
+	}
+	return capabilities, nil
+}
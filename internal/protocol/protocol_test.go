@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-request/handle"
+)
+
+func TestNegotiate(t *testing.T) {
+	fmt.Printf("----------------------\nNEGOTIATE TEST\n----------------------\n")
+	supported := SupportedCapabilities
+	SupportedCapabilities = 0x5
+	MinimumVersion = LegacyVersion
+	defer func() { SupportedCapabilities = supported }()
+
+	version, capabilities, err := Negotiate(0, 0)
+	fmt.Printf("Legacy client: %d %#x %v\n", version, capabilities, err)
+	if err != nil || version != LegacyVersion {
+		t.Fatalf("Legacy client should negotiate version %d", LegacyVersion)
+	}
+
+	version, capabilities, err = Negotiate(LegacyVersion, 0xF)
+	fmt.Printf("Legacy client with capabilities: %d %#x %v\n", version, capabilities, err)
+	if err != nil || capabilities != 0 {
+		t.Fatalf("Legacy client should not be given capabilities")
+	}
+
+	version, capabilities, err = Negotiate(CurrentVersion+3, 0xF)
+	fmt.Printf("Newer client: %d %#x %v\n", version, capabilities, err)
+	if err != nil || version != CurrentVersion || capabilities != 0x5 {
+		t.Fatalf("Newer client should negotiate version %d with shared capabilities", CurrentVersion)
+	}
+
+	MinimumVersion = CurrentVersion
+	defer func() { MinimumVersion = LegacyVersion }()
+	_, _, err = Negotiate(LegacyVersion, 0)
+	fmt.Printf("Outdated client: %v\n", err)
+	if responder.CategoryOf(err, responder.NoError) != responder.IncompatibleProtocol {
+		t.Fatalf("Outdated client should be rejected as incompatible")
+	}
+}
+
+func TestNegotiatingReader(t *testing.T) {
+	fmt.Printf("----------------------\nNEGOTIATING READER TEST\n----------------------\n")
+	responder.MarshalResponse = func(r responder.Response) ([]byte, error) {
+		return []byte(fmt.Sprintf("%d:%d:%d", r.Status, r.Category, r.Version)), nil
+	}
+	read := func(conn handle.Conn) ([]byte, error) { return []byte("request"), nil }
+
+	reader := NewNegotiatingReader(read, unpack(CurrentVersion, 0))
+	conn := FakeConn{}
+	_, err := reader(&conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Negotiated version %d\n", conn.version)
+	if conn.version != CurrentVersion || conn.closed {
+		t.Fatalf("Expected compatible client to negotiate version %d", CurrentVersion)
+	}
+
+	MinimumVersion = CurrentVersion
+	defer func() { MinimumVersion = LegacyVersion }()
+	reader = NewNegotiatingReader(read, unpack(LegacyVersion, 0))
+	conn = FakeConn{}
+	_, err = reader(&conn)
+	fmt.Printf("Rejected: %v\n", err)
+	if err == nil || !conn.closed {
+		t.Fatalf("Expected incompatible client to be rejected and dropped")
+	}
+
+	var size int32
+	binary.Read(&conn.Buffer, binary.BigEndian, &size)
+	response := string(conn.Next(int(size)))
+	fmt.Printf("Response: %s\n", response)
+	expected := fmt.Sprintf("%d:%d:%d", responder.StatusError, responder.IncompatibleProtocol, CurrentVersion)
+	if response != expected {
+		t.Fatalf("Unexpected rejection response %s", response)
+	}
+}
+
+func unpack(version int32, capabilities uint64) func([]byte) (handle.Request, error) {
+	return func([]byte) (handle.Request, error) {
+		return &FakeRequest{version: version, capabilities: capabilities}, nil
+	}
+}
+
+type FakeRequest struct {
+	version      int32
+	capabilities uint64
+}
+
+func (fr *FakeRequest) GetType() int32          { return 0 }
+func (fr *FakeRequest) GetRequest() []byte      { return nil }
+func (fr *FakeRequest) GetVersion() int32       { return fr.version }
+func (fr *FakeRequest) GetCapabilities() uint64 { return fr.capabilities }
+
+type FakeConn struct {
+	bytes.Buffer
+	version      int32
+	capabilities uint64
+	closed       bool
+}
+
+func (fc *FakeConn) SetProtocol(version int32, capabilities uint64) {
+	fc.version = version
+	fc.capabilities = capabilities
+}
+func (fc *FakeConn) GetProtocolVersion() int32 { return fc.version }
+func (fc *FakeConn) GetCapabilities() uint64   { return fc.capabilities }
+func (fc *FakeConn) Close() error {
+	fc.closed = true
+	return nil
+}
@@ -1,10 +1,12 @@
 package register
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
 
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	_ "github.com/lib/pq"
 )
 
@@ -66,15 +68,15 @@ func (ed *EndpointRegistrationDatabase) IsRegistered(originID string) bool {
 }
 
 //AddOrigin adds 'originID' to the set of registered IDs
-func (ed *EndpointRegistrationDatabase) AddOrigin(originID string) error {
+func (ed *EndpointRegistrationDatabase) AddOrigin(ctx context.Context, originID string) error {
 	ed.databaseMutex.Lock()
 	defer ed.databaseMutex.Unlock()
 
 	if ed.originSet[originID] {
-		return fmt.Errorf("Failed to add origin in EndpointRegistrationDatabase.AddOrigin(): "+
+		return responder.Errorf(responder.OriginExists, "Failed to add origin in EndpointRegistrationDatabase.AddOrigin(): "+
 			"Origin %s already exists", originID)
 	}
-	_, err := ed.backupDB.Exec(insertStatement, originID)
+	_, err := ed.backupDB.ExecContext(ctx, insertStatement, originID)
 	if err != nil {
 		return fmt.Errorf("Failed to add origin in EndpointRegistrationDatabase.AddOrigin(): "+
 			"Failed to insert %s into postgres database: %v", originID, err)
@@ -85,15 +87,15 @@ func (ed *EndpointRegistrationDatabase) AddOrigin(originID string) error {
 }
 
 //RemoveOrigin removes 'originID' from the set of registered IDs
-func (ed *EndpointRegistrationDatabase) RemoveOrigin(originID string) error {
+func (ed *EndpointRegistrationDatabase) RemoveOrigin(ctx context.Context, originID string) error {
 	ed.databaseMutex.Lock()
 	defer ed.databaseMutex.Unlock()
 
 	if !ed.originSet[originID] {
-		return fmt.Errorf("Failed to remove origin in EndpointRegistrationDatabase.RemoveOrigin(): "+
+		return responder.Errorf(responder.UnknownOrigin, "Failed to remove origin in EndpointRegistrationDatabase.RemoveOrigin(): "+
 			"Origin %s is not registered", originID)
 	}
-	_, err := ed.backupDB.Exec(removeStatement, originID)
+	_, err := ed.backupDB.ExecContext(ctx, removeStatement, originID)
 	if err != nil {
 		return fmt.Errorf("Failed to remove origin in EndpointRegistrationDatabase.RemoveOrigin(): "+
 			"Failed to remove %s from postgres database: %v", originID, err)
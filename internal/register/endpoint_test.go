@@ -1,6 +1,7 @@
 package register
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"testing"
@@ -24,14 +25,14 @@ func TestEndpoint(t *testing.T) {
 	origin := "/origin/TESTORIGIN"
 	fmt.Printf("(%s)[REGISTRATION STATUS] = %t\n", origin, db.IsRegistered(origin))
 
-	err = db.AddOrigin(origin)
+	err = db.AddOrigin(context.Background(), origin)
 	if err != nil {
 		panic(err)
 	}
 	fmt.Printf("Inserted (%s) into database\n", origin)
 	fmt.Printf("(%s)[REGISTRATION STATUS] = %t\n", origin, db.IsRegistered(origin))
 
-	err = db.RemoveOrigin(origin)
+	err = db.RemoveOrigin(context.Background(), origin)
 	if err != nil {
 		panic(err)
 	}
@@ -1,10 +1,12 @@
 package registrator
 
+import "context"
+
 /*OriginRegistrator describes a datastore that keeps track of
 which valid 'points of origin' for endpoints to come from */
 type OriginRegistrator interface {
-	AddOrigin(string) error
-	RemoveOrigin(string) error
+	AddOrigin(context.Context, string) error
+	RemoveOrigin(context.Context, string) error
 }
 
 /*SwarmMap describes an object that can map a dataspace to a
@@ -12,6 +14,8 @@ specific swarm as well as having the ability to return the swarm
 with the least number of dataspaces*/
 type SwarmMap interface {
 	AddSwarm(dataspace string) error
+	//Adds a swarm whose dataspace is owned by an origin
+	AddOwnedSwarm(dataspace string, originID string) error
 	RemoveSwarm(dataspace string) error
 }
 
@@ -24,3 +28,9 @@ type RegistrationRequest interface {
 	IsOrigin() bool
 	GetDataField() string
 }
+
+/*OwnedRequest is a RegistrationRequest that may name the origin owning
+a dataspace being added. GetOriginID returns "" if it names none*/
+type OwnedRequest interface {
+	GetOriginID() string
+}
@@ -1,10 +1,13 @@
 package registrator
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"testing"
 	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/admission"
 )
 
 func TestRegistrationHandler(t *testing.T) {
@@ -27,6 +30,9 @@ func TestRegistrationHandler(t *testing.T) {
 		if i%2 == 0 {
 			dspace := "/dataspace/" + strconv.Itoa(1000+i)
 			requests[i] = RegistrationRequestTest{isOrigin: false, isAdd: true, datafield: dspace}
+			if i%4 == 0 {
+				requests[i].origin = "/origin/" + strconv.Itoa(i)
+			}
 		} else {
 			dspace := "/dataspace/" + strconv.Itoa(killCount)
 			requests[i] = RegistrationRequestTest{isOrigin: false, isAdd: false, datafield: dspace}
@@ -48,13 +54,14 @@ func TestRegistrationHandler(t *testing.T) {
 
 	fconn := FakeConn{}
 	queueSize := 3
-	regHandler := New(queueSize, &swarmMap, &originReg)
+	regHandler := New(queueSize, 1, admission.Overflow{}, &swarmMap, &originReg)
 
 	for _, request := range requests {
 		regHandler.AddJob(&RegistrationRequestTest{
 			isAdd:     request.IsAdd(),
 			isOrigin:  request.IsOrigin(),
 			datafield: request.GetDataField(),
+			origin:    request.GetOriginID(),
 		}, &fconn)
 	}
 	time.Sleep(time.Second)
@@ -74,6 +81,10 @@ func (sm *SwarmMapTest) AddSwarm(dspace string) error {
 	sm.smap[dspace] = true
 	return nil
 }
+func (sm *SwarmMapTest) AddOwnedSwarm(dspace string, origin string) error {
+	fmt.Printf("Adding dataspace %s owned by %s\n", dspace, origin)
+	return sm.AddSwarm(dspace)
+}
 func (sm *SwarmMapTest) RemoveSwarm(dspace string) error {
 	if _, ok := sm.smap[dspace]; ok {
 		delete(sm.smap, dspace)
@@ -85,13 +96,13 @@ type OriginRegistratorTest struct {
 	origins map[string]bool
 }
 
-func (ot *OriginRegistratorTest) AddOrigin(s string) error {
+func (ot *OriginRegistratorTest) AddOrigin(ctx context.Context, s string) error {
 	fmt.Printf("Adding origin %s\n", s)
 	ot.origins[s] = true
 	return nil
 }
 
-func (ot *OriginRegistratorTest) RemoveOrigin(s string) error {
+func (ot *OriginRegistratorTest) RemoveOrigin(ctx context.Context, s string) error {
 	if _, ok := ot.origins[s]; !ok {
 		return fmt.Errorf("Cannot remove nonexisted registration entity %s", s)
 	}
@@ -104,8 +115,10 @@ type RegistrationRequestTest struct {
 	isAdd     bool
 	isOrigin  bool
 	datafield string
+	origin    string
 }
 
 func (rt *RegistrationRequestTest) IsAdd() bool          { return rt.isAdd }
 func (rt *RegistrationRequestTest) IsOrigin() bool       { return rt.isOrigin }
 func (rt *RegistrationRequestTest) GetDataField() string { return rt.datafield }
+func (rt *RegistrationRequestTest) GetOriginID() string  { return rt.origin }
@@ -1,35 +1,72 @@
 package registrator
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/admission"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	"github.com/arstevens/go-request/handle"
 )
 
+/*RequestTimeout bounds the time from admitting a registration request
+to storing it. A RequestTimeout of 0 leaves requests unbounded*/
+var RequestTimeout time.Duration = 0
+
 /*RegistrationHandler is an object that can handle requests that add a dataspace or
 origin to the set of all supported by this signaling server*/
 type RegistrationHandler struct {
 	closed        bool
-	requestStream chan<- handle.RequestPair
+	ctx           context.Context
+	cancel        context.CancelFunc
+	mutex         *sync.RWMutex
+	workers       *sync.WaitGroup
+	overflow      admission.Overflow
+	requestStream chan handle.RequestPair
 }
 
-//New creates a new instance of RegistrationHandler
-func New(size int, swarmMap SwarmMap, originReg OriginRegistrator) *RegistrationHandler {
+/*New creates a new instance of RegistrationHandler that admits jobs following
+'overflow' once its job queue is full. The queue is drained by 'workers'
+concurrent goroutines, at least one*/
+func New(size int, workers int, overflow admission.Overflow, swarmMap SwarmMap, originReg OriginRegistrator) *RegistrationHandler {
 	requestStream := make(chan handle.RequestPair, size)
-	go processRequestStream(requestStream, swarmMap, originReg)
+	ctx, cancel := context.WithCancel(context.Background())
+	workerGroup := &sync.WaitGroup{}
+	for i := 0; i < workers || i == 0; i++ {
+		workerGroup.Add(1)
+		go func() {
+			defer workerGroup.Done()
+			processRequestStream(ctx, requestStream, swarmMap, originReg)
+		}()
+	}
 	return &RegistrationHandler{
 		closed:        false,
+		ctx:           ctx,
+		cancel:        cancel,
+		mutex:         &sync.RWMutex{},
+		workers:       workerGroup,
+		overflow:      overflow,
 		requestStream: requestStream,
 	}
 }
 
-//AddJob adds a request to the job queue. AddJob hangs if the job queue is full
+/*AddJob adds a request to the job queue. A full job queue is handled
+according to the overflow policy of the RegistrationHandler*/
 func (rh *RegistrationHandler) AddJob(request interface{}, conn handle.Conn) error {
+	pair := handle.RequestPair{Request: admission.NewJob(request), Conn: conn}
+	rh.mutex.RLock()
+	defer rh.mutex.RUnlock()
 	if rh.closed {
+		go admission.TurnAway(pair, rh.overflow.RetryAfter)
 		return fmt.Errorf("Cannot add a job on a closed RegistrationHandler")
 	}
-	rh.requestStream <- handle.RequestPair{Request: request, Conn: conn}
+	err := admission.Admit(rh.requestStream, pair, rh.overflow)
+	if err != nil {
+		return fmt.Errorf("Failed to add job to RegistrationHandler: %v", err)
+	}
 	return nil
 }
 
@@ -48,39 +85,75 @@ func (rh *RegistrationHandler) QueuedJobs() int {
 /*Close closes a RegistrationHandler for work and returns an error if the
 Handler is already closed*/
 func (rh *RegistrationHandler) Close() error {
-	if !rh.closed {
-		rh.closed = true
-		close(rh.requestStream)
-		return nil
+	rh.mutex.Lock()
+	defer rh.mutex.Unlock()
+	if rh.closed {
+		return fmt.Errorf("Cannot close a closed DataspaceHandler")
+	}
+	rh.closed = true
+	close(rh.requestStream)
+	return nil
+}
+
+/*Shutdown closes the RegistrationHandler and handles the jobs left in its queue if
+'drain' is set or turns them away as busy otherwise. It then waits up to
+'timeout' for the jobs being handled to finish before cancelling them*/
+func (rh *RegistrationHandler) Shutdown(drain bool, timeout time.Duration) error {
+	err := rh.Close()
+	if err != nil {
+		return err
+	}
+	if !drain {
+		admission.Flush(rh.requestStream, rh.overflow.RetryAfter)
 	}
-	return fmt.Errorf("Cannot close a closed DataspaceHandler")
+	err = admission.Wait(rh.workers, timeout)
+	if err != nil {
+		//Abandon the jobs still being handled
+		rh.cancel()
+		return fmt.Errorf("Failed to shut down RegistrationHandler: %v", err)
+	}
+	rh.cancel()
+	return nil
 }
 
-func processRequestStream(requestStream <-chan handle.RequestPair, swarmMap SwarmMap, originReg OriginRegistrator) {
+func processRequestStream(ctx context.Context, requestStream <-chan handle.RequestPair, swarmMap SwarmMap, originReg OriginRegistrator) {
 	for {
 		requestPair, ok := <-requestStream
 		if !ok {
 			return
 		}
-		request := requestPair.Request.(RegistrationRequest)
+		job := requestPair.Request.(admission.Job)
+		request := job.Request.(RegistrationRequest)
 
-		err := handleRegistrationRequest(request, swarmMap, originReg)
+		jobCtx, cancel := job.Context(ctx, RequestTimeout)
+		err := handleRegistrationRequest(jobCtx, request, swarmMap, originReg)
+		cancel()
 		if err != nil {
 			log.Println(err)
+			err = responder.Failure(requestPair.Conn, err, responder.InternalError)
+		} else {
+			err = responder.Success(requestPair.Conn)
+		}
+		if err != nil {
+			log.Printf("Failed to respond to requester in RegistrationHandler: %v", err)
 		}
+		requestPair.Conn.Close()
 	}
 }
 
-func handleRegistrationRequest(request RegistrationRequest, swarmMap SwarmMap, originReg OriginRegistrator) error {
+func handleRegistrationRequest(ctx context.Context, request RegistrationRequest, swarmMap SwarmMap, originReg OriginRegistrator) error {
 	var err error
 	if request.IsOrigin() {
 		if request.IsAdd() {
-			err = originReg.AddOrigin(request.GetDataField())
+			err = originReg.AddOrigin(ctx, request.GetDataField())
 		} else {
-			err = originReg.RemoveOrigin(request.GetDataField())
+			err = originReg.RemoveOrigin(ctx, request.GetDataField())
 		}
 	} else {
-		if request.IsAdd() {
+		oRequest, isOwned := request.(OwnedRequest)
+		if request.IsAdd() && isOwned && oRequest.GetOriginID() != "" {
+			err = swarmMap.AddOwnedSwarm(request.GetDataField(), oRequest.GetOriginID())
+		} else if request.IsAdd() {
 			err = swarmMap.AddSwarm(request.GetDataField())
 		} else {
 			err = swarmMap.RemoveSwarm(request.GetDataField())
@@ -88,7 +161,7 @@ func handleRegistrationRequest(request RegistrationRequest, swarmMap SwarmMap, o
 	}
 
 	if err != nil {
-		return fmt.Errorf("Failed to handle registration request in RegistrationHandler: %v", err)
+		return fmt.Errorf("Failed to handle registration request in RegistrationHandler: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,31 @@
+package responder
+
+//MarshalResponseMessage describes a function that encodes a Response
+type MarshalResponseMessage func(Response) ([]byte, error)
+
+//MarshalNoticeMessage describes a function that encodes a Notice
+type MarshalNoticeMessage func(Notice) ([]byte, error)
+
+//UnmarshalNoticeMessage describes a function that decodes a NoticeMessage
+type UnmarshalNoticeMessage func([]byte) (interface{}, error)
+
+//NoticeMessage describes a Notice decoded from the wire
+type NoticeMessage interface {
+	GetType() int32
+	GetReason() string
+	GetPeer() string
+	//Round trip time in microseconds
+	GetRtt() int64
+	GetDataspaces() []string
+}
+
+//EncodedConn describes a connection that knows the wire encoding its peer speaks
+type EncodedConn interface {
+	GetEncoding() string
+}
+
+//ProtocolConn describes a connection that has negotiated a protocol version
+type ProtocolConn interface {
+	GetProtocolVersion() int32
+	GetCapabilities() uint64
+}
@@ -0,0 +1,285 @@
+package responder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/wrapper"
+)
+
+//Status codes carried by a Response. Values match ResponseStatus in messages.proto
+const (
+	StatusOK int32 = iota
+	StatusError
+	StatusBusy
+)
+
+//Error categories carried by a Response. Values match ErrorCategory in messages.proto
+const (
+	NoError int32 = iota
+	InternalError
+	UnknownDataspace
+	DataspaceExists
+	UnknownOrigin
+	OriginExists
+	VerificationFailed
+	NoEndpointsAvailable
+	NegotiationFailed
+	SwarmUnavailable
+	IncompatibleProtocol
+	Overloaded
+	UnknownEndpoint
+)
+
+//Notice types carried by a Notice. Values match NoticeType in messages.proto
+const (
+	NoticeClosing int32 = iota
+	NoticePing
+	NoticePong
+	NoticeLatencyReport
+	NoticeMigrate
+	NoticeMigrated
+	NoticePeer
+	NoticeReset
+)
+
+/*LegacyVersion is the protocol version of clients that predate typed
+Responses. It matches protocol.LegacyVersion*/
+const LegacyVersion int32 = 1
+
+/*OperationSuccess is the single byte legacy clients are sent once their
+endpoint joined a swarm. They are sent no other Response and learn that a
+request failed by their connection being closed*/
+var OperationSuccess byte = 1
+
+/*Response holds every field written back to a client. Version and
+Capabilities are filled in from the connection it is written to*/
+type Response struct {
+	Status       int32
+	Category     int32
+	Reason       string
+	Version      int32
+	Capabilities uint64
+	RetryAfter   time.Duration
+	Peers        int32
+	EndpointID   string
+}
+
+/*Notice is pushed unprompted to an idle endpoint. Only endpoints that
+negotiated the capability to receive notices may be sent one. Peer and
+RTT are only set on latency reports sent back by endpoints. Dataspaces
+is only set on migrations*/
+type Notice struct {
+	Type       int32
+	Reason     string
+	Peer       string
+	RTT        time.Duration
+	Dataspaces []string
+}
+
+/*MarshalResponse encodes a status, error category and reason into
+a Response in the wire format understood by clients*/
+var MarshalResponse MarshalResponseMessage = nil
+
+/*Marshalers holds the Response encoder of each wire encoding. Conns that
+report their encoding are answered in it, all others use MarshalResponse*/
+var Marshalers = make(map[string]MarshalResponseMessage)
+
+//MarshalNotice and NoticeMarshalers do the same for Notices
+var MarshalNotice MarshalNoticeMessage = nil
+var NoticeMarshalers = make(map[string]MarshalNoticeMessage)
+
+//UnmarshalNotice and NoticeUnmarshalers decode Notices sent back by endpoints
+var UnmarshalNotice UnmarshalNoticeMessage = nil
+var NoticeUnmarshalers = make(map[string]UnmarshalNoticeMessage)
+
+/*Error is an error tagged with the category it should be reported
+under when it is written back to a client*/
+type Error struct {
+	category int32
+	err      error
+}
+
+//Errorf creates a new Error of 'category' with a formatted message
+func Errorf(category int32, format string, a ...interface{}) error {
+	return &Error{category: category, err: fmt.Errorf(format, a...)}
+}
+
+func (e *Error) Error() string      { return e.err.Error() }
+func (e *Error) Unwrap() error      { return e.err }
+func (e *Error) GetCategory() int32 { return e.category }
+
+/*CategoryOf returns the category of the first Error in the chain
+of 'err' or 'fallback' if the chain holds no categorized errors*/
+func CategoryOf(err error, fallback int32) int32 {
+	var categorized *Error
+	if errors.As(err, &categorized) {
+		return categorized.category
+	}
+	return fallback
+}
+
+//Success writes a successful Response to 'conn'
+func Success(conn io.Writer) error {
+	return Respond(conn, StatusOK, NoError, "")
+}
+
+/*LoggedOn writes a successful Response to 'conn' carrying the identity
+issued to the endpoint. The endpoint must present it to log off*/
+func LoggedOn(conn io.Writer, endpointID string) error {
+	if isLegacy(conn) {
+		err := binary.Write(conn, binary.BigEndian, OperationSuccess)
+		if err != nil {
+			return fmt.Errorf("Failed to write legacy response in LoggedOn(): %v", err)
+		}
+		return nil
+	}
+	return Send(conn, Response{Status: StatusOK, Category: NoError, EndpointID: endpointID})
+}
+
+/*Paired writes a successful Response to 'conn' reporting the number
+of endpoints a requester was paired with*/
+func Paired(conn io.Writer, peers int32) error {
+	return Send(conn, Response{Status: StatusOK, Category: NoError, Peers: peers})
+}
+
+/*Failure writes an error Response describing 'err' to 'conn'. 'fallback'
+is reported as the category if 'err' was never categorized*/
+func Failure(conn io.Writer, err error, fallback int32) error {
+	return Respond(conn, StatusError, CategoryOf(err, fallback), err.Error())
+}
+
+/*Busy writes a Response to 'conn' telling the client the server is
+overloaded and that it should retry after 'retryAfter'*/
+func Busy(conn io.Writer, retryAfter time.Duration) error {
+	return Send(conn, Response{Status: StatusBusy, Category: Overloaded,
+		Reason: "Server is busy", RetryAfter: retryAfter})
+}
+
+//Respond writes a length prefixed Response to 'conn'
+func Respond(conn io.Writer, status int32, category int32, reason string) error {
+	return Send(conn, Response{Status: status, Category: category, Reason: reason})
+}
+
+/*Send writes 'response' to 'conn' in the encoding spoken by the client.
+Nothing is written to legacy clients*/
+func Send(conn io.Writer, response Response) error {
+	if isLegacy(conn) {
+		return nil
+	}
+	marshal := MarshalResponse
+	if eConn, ok := conn.(EncodedConn); ok {
+		if m, ok := Marshalers[eConn.GetEncoding()]; ok {
+			marshal = m
+		}
+	}
+	if marshal == nil {
+		return fmt.Errorf("No response encoding configured in Send()")
+	}
+	if pConn, ok := conn.(ProtocolConn); ok {
+		response.Version = pConn.GetProtocolVersion()
+		response.Capabilities = pConn.GetCapabilities()
+	}
+	raw, err := marshal(response)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal response in Send(): %v", err)
+	}
+	err = writeMessageToWire(conn, raw)
+	if err != nil {
+		return fmt.Errorf("Failed to write response in Send(): %v", err)
+	}
+	return nil
+}
+
+//Notify writes 'notice' to 'conn' in the encoding spoken by the endpoint
+func Notify(conn io.Writer, notice Notice) error {
+	marshal := MarshalNotice
+	if eConn, ok := conn.(EncodedConn); ok {
+		if m, ok := NoticeMarshalers[eConn.GetEncoding()]; ok {
+			marshal = m
+		}
+	}
+	if marshal == nil {
+		return fmt.Errorf("No notice encoding configured in Notify()")
+	}
+	raw, err := marshal(notice)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal notice in Notify(): %v", err)
+	}
+	err = writeMessageToWire(conn, raw)
+	if err != nil {
+		return fmt.Errorf("Failed to write notice in Notify(): %v", err)
+	}
+	return nil
+}
+
+//ReadNotice reads a Notice sent by 'conn' in the encoding spoken by the endpoint
+func ReadNotice(conn io.Reader) (Notice, error) {
+	unmarshal := UnmarshalNotice
+	if eConn, ok := conn.(EncodedConn); ok {
+		if u, ok := NoticeUnmarshalers[eConn.GetEncoding()]; ok {
+			unmarshal = u
+		}
+	}
+	if unmarshal == nil {
+		return Notice{}, fmt.Errorf("No notice encoding configured in ReadNotice()")
+	}
+	raw, err := readMessageFromWire(conn)
+	if err != nil {
+		return Notice{}, fmt.Errorf("Failed to read notice in ReadNotice(): %v", err)
+	}
+	iNotice, err := unmarshal(raw)
+	if err != nil {
+		return Notice{}, fmt.Errorf("Failed to unmarshal notice in ReadNotice(): %v", err)
+	}
+	notice := iNotice.(NoticeMessage)
+	return Notice{Type: notice.GetType(), Reason: notice.GetReason(), Peer: notice.GetPeer(),
+		RTT: time.Duration(notice.GetRtt()) * time.Microsecond, Dataspaces: notice.GetDataspaces()}, nil
+}
+
+//isLegacy returns whether or not 'conn' negotiated the legacy protocol
+func isLegacy(conn io.Writer) bool {
+	pConn, ok := conn.(ProtocolConn)
+	return ok && pConn.GetProtocolVersion() == LegacyVersion
+}
+
+func writeMessageToWire(conn io.Writer, msg []byte) error {
+	if mConn, ok := conn.(wrapper.MessageConn); ok {
+		return mConn.WriteMessage(msg)
+	}
+
+	var size int32
+	size = int32(len(msg))
+	err := binary.Write(conn, binary.BigEndian, size)
+	if err != nil {
+		return fmt.Errorf("Failed to write header to connection: %v", err)
+	}
+
+	_, err = conn.Write(msg)
+	if err != nil {
+		return fmt.Errorf("Failed to write message to connection: %v", err)
+	}
+	return nil
+}
+
+func readMessageFromWire(conn io.Reader) ([]byte, error) {
+	if mConn, ok := conn.(wrapper.MessageConn); ok {
+		return mConn.ReadMessage()
+	}
+
+	var size int32
+	err := binary.Read(conn, binary.BigEndian, &size)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read message header from conn: %v", err)
+	}
+
+	buf := make([]byte, size)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read message from conn: %v", err)
+	}
+	return buf, nil
+}
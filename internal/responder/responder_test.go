@@ -0,0 +1,192 @@
+package responder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestResponder(t *testing.T) {
+	fmt.Printf("----------------------\nRESPONDER TEST\n----------------------\n")
+	MarshalResponse = marshal
+
+	conn := &bytes.Buffer{}
+	err := Success(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := readMessage(t, conn)
+	fmt.Printf("Success: %s\n", msg)
+	if msg != "0:0:" {
+		t.Fatalf("Unexpected success response %s", msg)
+	}
+
+	categorized := Errorf(UnknownDataspace, "No swarm for dataspace %s", "/dataspace/TEST")
+	wrapped := fmt.Errorf("Failed to handle request: %w", categorized)
+	err = Failure(conn, wrapped, InternalError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = readMessage(t, conn)
+	fmt.Printf("Categorized Failure: %s\n", msg)
+	if msg != fmt.Sprintf("1:%d:%s", UnknownDataspace, wrapped.Error()) {
+		t.Fatalf("Unexpected categorized response %s", msg)
+	}
+
+	err = Failure(conn, fmt.Errorf("uncategorized"), InternalError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = readMessage(t, conn)
+	fmt.Printf("Fallback Failure: %s\n", msg)
+	if msg != fmt.Sprintf("1:%d:uncategorized", InternalError) {
+		t.Fatalf("Unexpected fallback response %s", msg)
+	}
+
+	err = Busy(conn, time.Millisecond*250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = readMessage(t, conn)
+	fmt.Printf("Busy: %s\n", msg)
+	if msg != fmt.Sprintf("2:%d:Server is busy:250", Overloaded) {
+		t.Fatalf("Unexpected busy response %s", msg)
+	}
+}
+
+func TestEncodedResponder(t *testing.T) {
+	fmt.Printf("----------------------\nENCODED RESPONDER TEST\n----------------------\n")
+	MarshalResponse = marshal
+	Marshalers["braces"] = func(r Response) ([]byte, error) {
+		return []byte(fmt.Sprintf("{%d,%d,%s,%d,%d}", r.Status, r.Category, r.Reason, r.Version, r.Capabilities)), nil
+	}
+
+	conn := &EncodedBuffer{encoding: "braces", version: 2, capabilities: 3}
+	err := Success(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := readMessage(t, &conn.Buffer)
+	fmt.Printf("Encoded Success: %s\n", msg)
+	if msg != "{0,0,,2,3}" {
+		t.Fatalf("Unexpected encoded response %s", msg)
+	}
+
+	conn = &EncodedBuffer{encoding: "unknown"}
+	err = Success(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = readMessage(t, &conn.Buffer)
+	fmt.Printf("Default Success: %s\n", msg)
+	if msg != "0:0:" {
+		t.Fatalf("Unknown encoding should use the default marshaler, got %s", msg)
+	}
+}
+
+func TestLegacyResponder(t *testing.T) {
+	fmt.Printf("----------------------\nLEGACY RESPONDER TEST\n----------------------\n")
+	MarshalResponse = marshal
+
+	conn := &EncodedBuffer{version: LegacyVersion}
+	err := Success(conn)
+	if err == nil {
+		err = Failure(conn, fmt.Errorf("failed"), InternalError)
+	}
+	if err == nil {
+		err = Paired(conn, 1)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Written before logging on: %v\n", conn.Bytes())
+	if conn.Len() != 0 {
+		t.Fatalf("Expected legacy clients to be sent no Responses, got %v", conn.Bytes())
+	}
+
+	err = LoggedOn(conn, "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Written after logging on: %v\n", conn.Bytes())
+	if !bytes.Equal(conn.Bytes(), []byte{OperationSuccess}) {
+		t.Fatalf("Expected legacy endpoints to be sent OperationSuccess, got %v", conn.Bytes())
+	}
+}
+
+func TestNotify(t *testing.T) {
+	fmt.Printf("----------------------\nNOTIFY TEST\n----------------------\n")
+	MarshalNotice = func(n Notice) ([]byte, error) {
+		return []byte(fmt.Sprintf("notice:%d:%s", n.Type, n.Reason)), nil
+	}
+
+	conn := &bytes.Buffer{}
+	err := Notify(conn, Notice{Type: NoticeClosing, Reason: "Server shutting down"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := readMessage(t, conn)
+	fmt.Printf("Notice: %s\n", msg)
+	if msg != fmt.Sprintf("notice:%d:Server shutting down", NoticeClosing) {
+		t.Fatalf("Unexpected notice %s", msg)
+	}
+
+	UnmarshalNotice = func(raw []byte) (interface{}, error) {
+		var noticeType int32
+		var reason string
+		_, err := fmt.Sscanf(string(raw), "notice:%d:%s", &noticeType, &reason)
+		return &FakeNotice{noticeType: noticeType, reason: reason}, err
+	}
+	err = Notify(conn, Notice{Type: NoticePong, Reason: "nonce"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice, err := ReadNotice(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Read Notice: %v\n", notice)
+	if notice.Type != NoticePong || notice.Reason != "nonce" {
+		t.Fatalf("Unexpected notice read %v", notice)
+	}
+}
+
+type FakeNotice struct {
+	noticeType int32
+	reason     string
+}
+
+func (fn *FakeNotice) GetType() int32          { return fn.noticeType }
+func (fn *FakeNotice) GetReason() string       { return fn.reason }
+func (fn *FakeNotice) GetPeer() string         { return "" }
+func (fn *FakeNotice) GetRtt() int64           { return 0 }
+func (fn *FakeNotice) GetDataspaces() []string { return nil }
+
+type EncodedBuffer struct {
+	bytes.Buffer
+	encoding     string
+	version      int32
+	capabilities uint64
+}
+
+func (eb *EncodedBuffer) GetEncoding() string       { return eb.encoding }
+func (eb *EncodedBuffer) GetProtocolVersion() int32 { return eb.version }
+func (eb *EncodedBuffer) GetCapabilities() uint64   { return eb.capabilities }
+
+func readMessage(t *testing.T, conn *bytes.Buffer) string {
+	var size int32
+	err := binary.Read(conn, binary.BigEndian, &size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(conn.Next(int(size)))
+}
+
+func marshal(r Response) ([]byte, error) {
+	if r.RetryAfter > 0 {
+		return []byte(fmt.Sprintf("%d:%d:%s:%d", r.Status, r.Category, r.Reason, r.RetryAfter.Milliseconds())), nil
+	}
+	return []byte(fmt.Sprintf("%d:%d:%s", r.Status, r.Category, r.Reason)), nil
+}
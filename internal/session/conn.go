@@ -0,0 +1,263 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+)
+
+/*Conn is a single request multiplexed over a session. Messages read and
+written on a Conn travel in session frames tagged with its request ID.
+Read and Write treat the request as a stream of message payloads*/
+type Conn struct {
+	id           uint32
+	session      *session
+	inbox        chan []byte
+	remoteDone   chan struct{}
+	done         chan struct{}
+	closeOnce    *sync.Once
+	pending      []byte
+	version      int32
+	capabilities uint64
+	metadata     metadata.Metadata
+	endpointID   string
+
+	deadlineMutex *sync.Mutex
+	deadline      *time.Timer
+	expired       chan struct{}
+}
+
+func newConn(id uint32, s *session) *Conn {
+	conn := &Conn{
+		id:         id,
+		session:    s,
+		inbox:      make(chan []byte, InboxSize),
+		remoteDone: make(chan struct{}),
+		done:       make(chan struct{}),
+		closeOnce:  &sync.Once{},
+		pending:    nil,
+
+		deadlineMutex: &sync.Mutex{},
+		expired:       make(chan struct{}),
+	}
+	if pConn, ok := s.conn.(ProtocolConn); ok {
+		conn.version = pConn.GetProtocolVersion()
+		conn.capabilities = pConn.GetCapabilities()
+	}
+	return conn
+}
+
+/*push queues a payload read by the session unless the Conn was closed.
+It never blocks and returns false if the inbox is full*/
+func (c *Conn) push(payload []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+	select {
+	case c.inbox <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+/*closeInbox marks that the client will send no more frames for the
+request. Only the session's serve routine may push to or close an inbox*/
+func (c *Conn) closeInbox() {
+	if !c.isInboxClosed() {
+		close(c.remoteDone)
+		close(c.inbox)
+	}
+}
+
+func (c *Conn) isInboxClosed() bool {
+	select {
+	case <-c.remoteDone:
+		return true
+	default:
+		return false
+	}
+}
+
+//ReadMessage returns the payload of the next frame sent for the request
+func (c *Conn) ReadMessage() ([]byte, error) {
+	c.deadlineMutex.Lock()
+	expired := c.expired
+	c.deadlineMutex.Unlock()
+
+	select {
+	case msg, ok := <-c.inbox:
+		if !ok {
+			return nil, io.EOF
+		}
+		return msg, nil
+	case <-c.done:
+		return nil, fmt.Errorf("Read on closed Conn in session.Conn.ReadMessage()")
+	case <-c.session.done:
+		return nil, io.EOF
+	case <-expired:
+		return nil, fmt.Errorf("Deadline exceeded in session.Conn.ReadMessage()")
+	}
+}
+
+/*SetDeadline makes reads that are blocked or started after 't' fail. A
+zero 't' clears the deadline. Writes go straight to the session and are
+never held up by the deadline*/
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.deadlineMutex.Lock()
+	defer c.deadlineMutex.Unlock()
+	if c.deadline != nil {
+		c.deadline.Stop()
+		c.deadline = nil
+	}
+
+	expired := make(chan struct{})
+	c.expired = expired
+	if t.IsZero() {
+		return nil
+	}
+	if wait := time.Until(t); wait > 0 {
+		c.deadline = time.AfterFunc(wait, func() { close(expired) })
+	} else {
+		close(expired)
+	}
+	return nil
+}
+
+//WriteMessage sends 'msg' to the client in a single frame
+func (c *Conn) WriteMessage(msg []byte) error {
+	select {
+	case <-c.done:
+		return fmt.Errorf("Write on closed Conn in session.Conn.WriteMessage()")
+	default:
+	}
+	err := c.session.writeFrame(EncodeFrame(c.id, 0, msg))
+	if err != nil {
+		return fmt.Errorf("Failed to write frame in session.Conn.WriteMessage(): %v", err)
+	}
+	return nil
+}
+
+//Read reads from the payloads of the frames sent for the request
+func (c *Conn) Read(b []byte) (int, error) {
+	for len(c.pending) == 0 {
+		msg, err := c.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		c.pending = msg
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+//Write sends 'b' to the client in a single frame
+func (c *Conn) Write(b []byte) (int, error) {
+	err := c.WriteMessage(b)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+/*Close ends the request and tells the client no more frames will be
+sent for it. The session itself stays open*/
+func (c *Conn) Close() error {
+	closed := false
+	c.closeOnce.Do(func() {
+		closed = true
+		close(c.done)
+		c.session.remove(c.id)
+	})
+	if !closed {
+		return fmt.Errorf("Connection already closed in session.Conn.Close()")
+	}
+	return c.session.writeFrame(EncodeFrame(c.id, FlagClose, nil))
+}
+
+//IsClosed returns whether the request was closed by either end or its session ended
+func (c *Conn) IsClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	case <-c.remoteDone:
+		return true
+	case <-c.session.done:
+		return true
+	default:
+		return false
+	}
+}
+
+//GetID returns the request ID of the Conn within its session
+func (c *Conn) GetID() uint32 {
+	return c.id
+}
+
+//GetAddress returns the address of the session followed by the request ID
+func (c *Conn) GetAddress() string {
+	address := ""
+	if aConn, ok := c.session.conn.(AddressedConn); ok {
+		address = aConn.GetAddress()
+	}
+	return fmt.Sprintf("%s#%d", address, c.id)
+}
+
+//GetIP returns the IP address of the session's client
+func (c *Conn) GetIP() net.IP {
+	if nConn, ok := c.session.conn.(NetConn); ok {
+		return nConn.GetIP()
+	}
+	return nil
+}
+
+//GetEncoding returns the wire encoding spoken by the session's client
+func (c *Conn) GetEncoding() string {
+	if eConn, ok := c.session.conn.(EncodedConn); ok {
+		return eConn.GetEncoding()
+	}
+	return ""
+}
+
+//SetProtocol records the protocol version and capabilities negotiated for the request
+func (c *Conn) SetProtocol(version int32, capabilities uint64) {
+	c.version = version
+	c.capabilities = capabilities
+}
+
+//GetProtocolVersion returns the negotiated protocol version
+func (c *Conn) GetProtocolVersion() int32 {
+	return c.version
+}
+
+//GetCapabilities returns the negotiated capabilities
+func (c *Conn) GetCapabilities() uint64 {
+	return c.capabilities
+}
+
+//SetMetadata records the metadata declared by the remote end when logging on
+func (c *Conn) SetMetadata(m metadata.Metadata) {
+	c.metadata = m
+}
+
+//GetMetadata returns the metadata declared by the remote end
+func (c *Conn) GetMetadata() metadata.Metadata {
+	return c.metadata
+}
+
+//SetEndpointID records the identity issued to the remote end when it logged on
+func (c *Conn) SetEndpointID(id string) {
+	c.endpointID = id
+}
+
+//GetEndpointID returns the identity issued to the remote end or "" if it never logged on
+func (c *Conn) GetEndpointID() string {
+	return c.endpointID
+}
@@ -0,0 +1,32 @@
+package session
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*A session frame is carried as one message on the session's connection. It
+holds a 4 byte big endian request ID, a byte of flags and the payload*/
+const frameHeaderSize = 5
+
+const (
+	//FlagClose marks the last frame sent for a request ID
+	FlagClose byte = 1 << iota
+)
+
+//EncodeFrame creates a session frame carrying 'payload' for request 'id'
+func EncodeFrame(id uint32, flags byte, payload []byte) []byte {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame, id)
+	frame[4] = flags
+	copy(frame[frameHeaderSize:], payload)
+	return frame
+}
+
+//DecodeFrame splits a session frame into its request ID, flags and payload
+func DecodeFrame(frame []byte) (uint32, byte, []byte, error) {
+	if len(frame) < frameHeaderSize {
+		return 0, 0, nil, fmt.Errorf("Frame of %d bytes is shorter than its header in DecodeFrame()", len(frame))
+	}
+	return binary.BigEndian.Uint32(frame), frame[4], frame[frameHeaderSize:], nil
+}
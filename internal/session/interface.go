@@ -0,0 +1,34 @@
+package session
+
+import (
+	"net"
+
+	"github.com/arstevens/go-request/handle"
+)
+
+/*UnreadableConn is a type of connection that can push a message it has
+already read back so that it is returned again by the next read*/
+type UnreadableConn interface {
+	handle.Conn
+	Unread([]byte)
+}
+
+/*The interfaces below describe the optional methods of a session's
+connection that are shared with every request multiplexed over it*/
+
+type AddressedConn interface {
+	GetAddress() string
+}
+
+type NetConn interface {
+	GetIP() net.IP
+}
+
+type EncodedConn interface {
+	GetEncoding() string
+}
+
+type ProtocolConn interface {
+	GetProtocolVersion() int32
+	GetCapabilities() uint64
+}
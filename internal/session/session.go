@@ -0,0 +1,305 @@
+package session
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/arstevens/go-hive-signal/internal/protocol"
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-hive-signal/internal/wrapper"
+	"github.com/arstevens/go-request/handle"
+	"github.com/arstevens/go-request/route"
+)
+
+//InboxSize is the number of frames buffered for a request before the request is reset
+var InboxSize = 16
+
+//MaxFrameSize is the size of the largest frame accepted on a session
+var MaxFrameSize = 1 << 20
+
+/*Listener wraps a route.Listener so that clients may open persistent
+sessions. A client opens a session by sending a request of the session
+routing code as its first request. Every request frame sent over the session
+afterwards is returned by Accept as its own Conn so request handlers are
+unaware of sessions. Clients that did not negotiate the Sessions capability
+are refused a session and dropped. Connections opened without a session
+are returned as is*/
+type Listener struct {
+	listener     route.Listener
+	read         route.ReadRequest
+	unpack       route.UnpackRouteRequest
+	sessionCode  int32
+	conns        chan handle.Conn
+	done         chan struct{}
+	stopOnce     *sync.Once
+	err          error
+	sessionMutex *sync.Mutex
+	sessions     map[*session]bool
+}
+
+/*NewListener creates a new Listener that reads the first request of every
+connection with 'read' and 'unpack' to find the clients opening sessions*/
+func NewListener(listener route.Listener, read route.ReadRequest, unpack route.UnpackRouteRequest,
+	sessionCode int32) *Listener {
+	sl := &Listener{
+		listener:     listener,
+		read:         read,
+		unpack:       unpack,
+		sessionCode:  sessionCode,
+		conns:        make(chan handle.Conn),
+		done:         make(chan struct{}),
+		stopOnce:     &sync.Once{},
+		err:          nil,
+		sessionMutex: &sync.Mutex{},
+		sessions:     make(map[*session]bool),
+	}
+	go sl.acceptConns()
+	return sl
+}
+
+/*Accept returns the next connection or session request. An error is
+returned once the underlying listener stops accepting connections*/
+func (sl *Listener) Accept() (handle.Conn, error) {
+	select {
+	case conn := <-sl.conns:
+		return conn, nil
+	case <-sl.done:
+		return nil, sl.err
+	}
+}
+
+//Close closes the underlying listener and every open session
+func (sl *Listener) Close() error {
+	err := sl.listener.Close()
+	sl.stop(fmt.Errorf("Listener closed in session.Listener.Close()"))
+
+	sl.sessionMutex.Lock()
+	defer sl.sessionMutex.Unlock()
+	for s := range sl.sessions {
+		s.close()
+	}
+	return err
+}
+
+func (sl *Listener) stop(err error) {
+	sl.stopOnce.Do(func() {
+		sl.err = err
+		close(sl.done)
+	})
+}
+
+func (sl *Listener) acceptConns() {
+	for {
+		conn, err := sl.listener.Accept()
+		if err != nil {
+			sl.stop(err)
+			return
+		}
+		go sl.classify(conn)
+	}
+}
+
+/*classify reads the first request of 'conn' and either serves a session
+over it or returns the request so it is read again by the router*/
+func (sl *Listener) classify(conn handle.Conn) {
+	raw, err := sl.read(conn)
+	if err != nil {
+		log.Printf("Failed to read first request in session.Listener: %v", err)
+		return
+	}
+
+	request, err := sl.unpack(raw)
+	if err != nil || request.GetType() != sl.sessionCode {
+		uConn, ok := conn.(UnreadableConn)
+		if !ok {
+			log.Printf("Cannot return request to connection in session.Listener")
+			conn.Close()
+			return
+		}
+		uConn.Unread(raw)
+		sl.deliver(conn)
+		return
+	}
+
+	if pConn, ok := conn.(ProtocolConn); ok && pConn.GetCapabilities()&protocol.CapabilitySessions == 0 {
+		err = responder.Errorf(responder.IncompatibleProtocol, "Sessions capability was not negotiated")
+		if rErr := responder.Failure(conn, err, responder.IncompatibleProtocol); rErr != nil {
+			err = fmt.Errorf("%v (%v)", err, rErr)
+		}
+		log.Printf("Refused session in session.Listener: %v", err)
+		conn.Close()
+		return
+	}
+
+	err = responder.Success(conn)
+	if err != nil {
+		log.Printf("Failed to acknowledge session in session.Listener: %v", err)
+		conn.Close()
+		return
+	}
+	s := newSession(conn, sl)
+	sl.track(s, true)
+	s.serve()
+	sl.track(s, false)
+}
+
+func (sl *Listener) deliver(conn handle.Conn) bool {
+	select {
+	case sl.conns <- conn:
+		return true
+	case <-sl.done:
+		conn.Close()
+		return false
+	}
+}
+
+func (sl *Listener) track(s *session, open bool) {
+	sl.sessionMutex.Lock()
+	defer sl.sessionMutex.Unlock()
+	if open {
+		sl.sessions[s] = true
+	} else {
+		delete(sl.sessions, s)
+	}
+}
+
+//session demultiplexes the frames of one connection into request Conns
+type session struct {
+	conn         handle.Conn
+	listener     *Listener
+	writeMutex   *sync.Mutex
+	requestMutex *sync.Mutex
+	requests     map[uint32]*Conn
+	closed       map[uint32]bool
+	done         chan struct{}
+	closeOnce    *sync.Once
+}
+
+func newSession(conn handle.Conn, listener *Listener) *session {
+	return &session{
+		conn:         conn,
+		listener:     listener,
+		writeMutex:   &sync.Mutex{},
+		requestMutex: &sync.Mutex{},
+		requests:     make(map[uint32]*Conn),
+		closed:       make(map[uint32]bool),
+		done:         make(chan struct{}),
+		closeOnce:    &sync.Once{},
+	}
+}
+
+/*serve reads frames until the connection fails. The first frame of a new
+request ID opens a request Conn that is passed to the listener. Later frames
+are queued on that Conn until the client marks the request closed. Frames
+arriving after either end closed a request are dropped and its ID is never
+reopened. A request whose handler falls InboxSize frames behind is reset
+so that it does not hold up the other requests of the session*/
+func (s *session) serve() {
+	defer s.close()
+	for {
+		frame, err := s.readFrame()
+		if err != nil {
+			if err != io.EOF && !s.isClosed() {
+				log.Printf("Failed to read frame in session: %v", err)
+			}
+			return
+		}
+		id, flags, payload, err := DecodeFrame(frame)
+		if err != nil {
+			log.Printf("Dropping session: %v", err)
+			return
+		}
+
+		s.requestMutex.Lock()
+		conn, ok := s.requests[id]
+		if !ok && len(payload) > 0 && !s.closed[id] {
+			conn = newConn(id, s)
+			s.requests[id] = conn
+		}
+		s.requestMutex.Unlock()
+
+		if conn == nil || conn.isInboxClosed() {
+			continue
+		}
+		if len(payload) > 0 && !conn.push(payload) {
+			log.Printf("Resetting request %d of session with a full inbox", id)
+			conn.closeInbox()
+			//The close frame is written off the read loop in case the client is slow to read
+			go conn.Close()
+			continue
+		}
+		if flags&FlagClose != 0 {
+			conn.closeInbox()
+		}
+		if !ok && !s.listener.deliver(conn) {
+			return
+		}
+	}
+}
+
+//remove forgets the request 'id' and keeps the client from reopening it
+func (s *session) remove(id uint32) {
+	s.requestMutex.Lock()
+	defer s.requestMutex.Unlock()
+	delete(s.requests, id)
+	s.closed[id] = true
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+func (s *session) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *session) readFrame() ([]byte, error) {
+	if mConn, ok := s.conn.(wrapper.MessageConn); ok {
+		return mConn.ReadMessage()
+	}
+
+	var size int32
+	err := binary.Read(s.conn, binary.BigEndian, &size)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 || int(size) > MaxFrameSize {
+		return nil, fmt.Errorf("Frame size %d out of bounds", size)
+	}
+	frame := make([]byte, size)
+	_, err = io.ReadFull(s.conn, frame)
+	if err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (s *session) writeFrame(frame []byte) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	if s.isClosed() {
+		return fmt.Errorf("Session is closed")
+	}
+	if mConn, ok := s.conn.(wrapper.MessageConn); ok {
+		return mConn.WriteMessage(frame)
+	}
+
+	err := binary.Write(s.conn, binary.BigEndian, int32(len(frame)))
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,264 @@
+package session
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/protocol"
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-hive-signal/internal/wrapper"
+	"github.com/arstevens/go-request/handle"
+	"github.com/arstevens/go-request/route"
+)
+
+var sessionCode int32 = 3
+
+func TestSessionListener(t *testing.T) {
+	fmt.Printf("----------------------\nSESSION LISTENER TEST\n----------------------\n")
+	responder.MarshalResponse = func(r responder.Response) ([]byte, error) {
+		return []byte(fmt.Sprintf("response:%d", r.Status)), nil
+	}
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := NewListener(&FakeListener{listener: netListener}, negotiate, unpack, sessionCode)
+	defer listener.Close()
+
+	plain := dial(t, netListener.Addr().String())
+	defer plain.Close()
+	writeMessage(t, plain, []byte("0:plain"))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := readRequest(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Plain request: %s\n", string(raw))
+	if string(raw) != "0:plain" {
+		t.Fatalf("Expected first request to be returned to plain connection, got %s", string(raw))
+	}
+
+	client := dial(t, netListener.Addr().String())
+	defer client.Close()
+	writeMessage(t, client, []byte("3:open"))
+	fmt.Printf("Session opened: %s\n", string(readMessage(t, client)))
+	writeMessage(t, client, EncodeFrame(1, 0, []byte("0:first")))
+	writeMessage(t, client, EncodeFrame(2, 0, []byte("1:second")))
+
+	requests := make(map[string]*Conn)
+	for i := 0; i < 2; i++ {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := readRequest(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Printf("Session request from %s: %s\n", conn.(*Conn).GetAddress(), string(raw))
+		requests[string(raw)] = conn.(*Conn)
+	}
+	first, second := requests["0:first"], requests["1:second"]
+	if first == nil || second == nil {
+		t.Fatalf("Session requests were not demultiplexed: %v", requests)
+	}
+
+	err = responder.Success(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+	expectFrame(t, client, 1, 0, "response:0")
+	expectFrame(t, client, 1, FlagClose, "")
+
+	second.SetDeadline(time.Now().Add(time.Millisecond * 20))
+	_, err = second.ReadMessage()
+	fmt.Printf("Read past deadline: %v\n", err)
+	if err == nil {
+		t.Fatalf("Expected read to fail once the deadline passed")
+	}
+	second.SetDeadline(time.Time{})
+
+	writeMessage(t, client, EncodeFrame(2, 0, []byte("offer")))
+	msg, err := second.ReadMessage()
+	if err != nil || string(msg) != "offer" {
+		t.Fatalf("Expected follow up frame on second request, got %s %v", string(msg), err)
+	}
+	writeMessage(t, client, EncodeFrame(2, FlagClose, nil))
+	_, err = second.ReadMessage()
+	if err != io.EOF || !second.IsClosed() {
+		t.Fatalf("Expected second request to be closed by the client, got %v", err)
+	}
+	second.Close()
+
+	//A request that falls behind is reset without holding up the rest of the session
+	InboxSize = 2
+	defer func() { InboxSize = 16 }()
+	writeMessage(t, client, EncodeFrame(1, 0, []byte("0:reopened")))
+	writeMessage(t, client, EncodeFrame(4, 0, []byte("0:slow")))
+	slow := acceptRequest(t, listener, "0:slow")
+	for i := 0; i <= InboxSize; i++ {
+		writeMessage(t, client, EncodeFrame(4, 0, []byte("flood")))
+	}
+	writeMessage(t, client, EncodeFrame(5, 0, []byte("1:fast")))
+	expectFrame(t, client, 2, FlagClose, "")
+	expectFrame(t, client, 4, FlagClose, "")
+	if !slow.IsClosed() {
+		t.Fatalf("Expected the request with a full inbox to be reset")
+	}
+	acceptRequest(t, listener, "1:fast").Close()
+
+	refused := dial(t, netListener.Addr().String())
+	defer refused.Close()
+	writeMessage(t, refused, []byte("3:legacy"))
+	response := string(readMessage(t, refused))
+	fmt.Printf("Session refused: %s\n", response)
+	refused.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = refused.Read(make([]byte, 1))
+	if response != fmt.Sprintf("response:%d", responder.StatusError) || err != io.EOF {
+		t.Fatalf("Expected a client without the Sessions capability to be refused and dropped, got %v", err)
+	}
+
+	listener.Close()
+	_, err = listener.Accept()
+	fmt.Printf("Accept after close: %v\n", err)
+	if err == nil {
+		t.Fatalf("Expected Accept to fail after Close")
+	}
+}
+
+/*acceptRequest returns the next request of 'listener' and fails unless it
+is 'expected'. Requests reopened under a closed ID would be returned instead*/
+func acceptRequest(t *testing.T, listener *Listener, expected string) *Conn {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := readRequest(conn)
+	fmt.Printf("Accepted request: %s\n", string(raw))
+	if err != nil || string(raw) != expected {
+		t.Fatalf("Expected request %s, got %s %v", expected, string(raw), err)
+	}
+	return conn.(*Conn)
+}
+
+func expectFrame(t *testing.T, conn net.Conn, id uint32, flags byte, payload string) {
+	fID, fFlags, fPayload, err := DecodeFrame(readMessage(t, conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Frame %d (flags %d): %s\n", fID, fFlags, string(fPayload))
+	if fID != id || fFlags != flags || string(fPayload) != payload {
+		t.Fatalf("Unexpected frame %d %d %s", fID, fFlags, string(fPayload))
+	}
+}
+
+/*negotiate reads a request and grants the Sessions capability to every
+client that does not claim to be legacy*/
+func negotiate(conn handle.Conn) ([]byte, error) {
+	raw, err := readRequest(conn)
+	if err == nil && !strings.HasSuffix(string(raw), ":legacy") {
+		conn.(*FakeConn).capabilities = protocol.CapabilitySessions
+	}
+	return raw, err
+}
+
+func readRequest(conn handle.Conn) ([]byte, error) {
+	if mConn, ok := conn.(wrapper.MessageConn); ok {
+		return mConn.ReadMessage()
+	}
+	return route.ReadRequestFromNetConn(conn)
+}
+
+func unpack(raw []byte) (handle.Request, error) {
+	parts := strings.SplitN(string(raw), ":", 2)
+	code, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	return &FakeRequest{code: int32(code)}, nil
+}
+
+func dial(t *testing.T, addr string) net.Conn {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func writeMessage(t *testing.T, conn net.Conn, msg []byte) {
+	err := binary.Write(conn, binary.BigEndian, int32(len(msg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write(msg)
+}
+
+func readMessage(t *testing.T, conn net.Conn) []byte {
+	var size int32
+	err := binary.Read(conn, binary.BigEndian, &size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, size)
+	_, err = io.ReadFull(conn, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+type FakeRequest struct {
+	code int32
+}
+
+func (fr *FakeRequest) GetType() int32     { return fr.code }
+func (fr *FakeRequest) GetRequest() []byte { return nil }
+
+type FakeListener struct {
+	listener net.Listener
+}
+
+func (fl *FakeListener) Accept() (handle.Conn, error) {
+	conn, err := fl.listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &FakeConn{Conn: conn}, nil
+}
+func (fl *FakeListener) Close() error { return fl.listener.Close() }
+
+type FakeConn struct {
+	net.Conn
+	unread       []byte
+	capabilities uint64
+}
+
+func (fc *FakeConn) Read(b []byte) (int, error) {
+	if len(fc.unread) > 0 {
+		n := copy(b, fc.unread)
+		fc.unread = fc.unread[n:]
+		return n, nil
+	}
+	return fc.Conn.Read(b)
+}
+
+func (fc *FakeConn) Unread(msg []byte) {
+	fc.unread = make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(fc.unread, uint32(len(msg)))
+	fc.unread = append(fc.unread, msg...)
+}
+
+func (fc *FakeConn) GetAddress() string        { return fc.RemoteAddr().String() }
+func (fc *FakeConn) GetProtocolVersion() int32 { return protocol.CurrentVersion }
+func (fc *FakeConn) GetCapabilities() uint64   { return fc.capabilities }
@@ -1,6 +1,7 @@
 package integration_tests
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
@@ -8,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/admission"
 	"github.com/arstevens/go-hive-signal/internal/analyzer"
 	"github.com/arstevens/go-hive-signal/internal/cache"
 	"github.com/arstevens/go-hive-signal/internal/comparator"
@@ -19,6 +21,7 @@ import (
 	"github.com/arstevens/go-hive-signal/internal/mapper"
 	"github.com/arstevens/go-hive-signal/internal/register"
 	"github.com/arstevens/go-hive-signal/internal/registrator"
+	"github.com/arstevens/go-hive-signal/internal/responder"
 	"github.com/arstevens/go-hive-signal/internal/tracker"
 	"github.com/arstevens/go-hive-signal/internal/transmuter"
 	"github.com/arstevens/go-hive-signal/internal/verifier"
@@ -44,7 +47,7 @@ func TestIdentityVerifier(t *testing.T) {
 	origins := make([]string, totalOrigins)
 	for i := 0; i < totalOrigins; i++ {
 		id := "/origin/" + strconv.Itoa(i)
-		err = endpointRegister.AddOrigin(id)
+		err = endpointRegister.AddOrigin(context.Background(), id)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -77,7 +80,7 @@ func TestIdentityVerifier(t *testing.T) {
 	}
 
 	for _, origin := range origins {
-		err = endpointRegister.RemoveOrigin(origin)
+		err = endpointRegister.RemoveOrigin(context.Background(), origin)
 		if err != nil {
 			log.Printf("%v\n", err)
 		}
@@ -91,7 +94,7 @@ func TestRequestLocalizerSubtree(t *testing.T) {
 	analyzer.DistancePollTime = time.Millisecond * 10
 	tracker.FrequencyCalculationPeriod = time.Second //time.Millisecond * 50
 	transmuter.PollPeriod = time.Second
-	negotiate := func(a manager.Conn, b manager.Conn) error { return nil }
+	negotiate := func(ctx context.Context, a manager.Conn, b manager.Conn) error { return nil }
 
 	logName := "test.log"
 	var err error
@@ -111,28 +114,31 @@ func TestRequestLocalizerSubtree(t *testing.T) {
 
 	activeSize := 10
 	manager.DebriefProcedure = debriefer.LoadPreferrenceDebrief
-	gatewayGen := gateway.NewGenerator(activeSize)
+	responder.MarshalResponse = func(r responder.Response) ([]byte, error) {
+		return protomsg.NewResponse(r.Status, r.Category, r.Reason, r.Version, r.Capabilities)
+	}
+	gatewayGen := gateway.NewGenerator(activeSize, gateway.RoundRobin{}, nil)
 	managerGen := manager.NewGenerator(gatewayGen, negotiate, infoTracker)
 
 	swarmMap := mapper.New(managerGen)
-	dataRequestAnalyzer := analyzer.New(infoTracker, optimalFinder)
+	dataRequestAnalyzer := analyzer.New(infoTracker, optimalFinder, swarmMap)
 	swarmTransmuter := transmuter.New(swarmMap, dataRequestAnalyzer)
 
 	requestBufferSize := 10
-	requestLocalizer := localizer.New(requestBufferSize, swarmMap, infoTracker)
+	requestLocalizer := localizer.New(requestBufferSize, 1, admission.Overflow{}, swarmMap, infoTracker)
 
 	endpointRegister, err := register.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	registrationHandler := registrator.New(requestBufferSize, swarmMap, endpointRegister)
+	registrationHandler := registrator.New(requestBufferSize, 1, admission.Overflow{}, swarmMap, endpointRegister)
 
 	cache.GarbageCollectionPeriod = time.Millisecond * 50
 	cache.ConnectionTTL = time.Second
 	cache.DisconnectionTTL = time.Second
 	connectionCache := cache.New()
 	identityVerifier := verifier.New(endpointRegister, connectionCache)
-	connectionHandler := connector.New(requestBufferSize, identityVerifier, swarmTransmuter)
+	connectionHandler := connector.New(requestBufferSize, 1, admission.Overflow{}, identityVerifier, swarmTransmuter)
 
 	done := make(chan struct{})
 	defer close(done)
@@ -384,8 +390,8 @@ func (rr *TestRegistrationRequest) GetDataField() string { return rr.field }
 
 type TestOriginRegistrator struct{}
 
-func (or *TestOriginRegistrator) AddOrigin(string) error    { return nil }
-func (or *TestOriginRegistrator) RemoveOrigin(string) error { return nil }
+func (or *TestOriginRegistrator) AddOrigin(context.Context, string) error    { return nil }
+func (or *TestOriginRegistrator) RemoveOrigin(context.Context, string) error { return nil }
 
 type TestLocalizeRequest string
 
@@ -8,9 +8,14 @@ import (
 var FrequencyCalculationPeriod = time.Minute
 
 func calculateFrequencyOnInterval(trackerSize int, frequencies map[string]*loadEntry, trackers map[string]*SwarmLoadTracker,
-	freqMutex *sync.RWMutex, trackMutex *sync.Mutex) {
+	freqMutex *sync.RWMutex, trackMutex *sync.Mutex, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
 	for {
-		time.Sleep(FrequencyCalculationPeriod)
+		select {
+		case <-time.After(FrequencyCalculationPeriod):
+		case <-stop:
+			return
+		}
 
 		trackMutex.Lock()
 		freqMutex.Lock()
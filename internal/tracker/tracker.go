@@ -1,6 +1,7 @@
 package tracker
 
 import (
+	"fmt"
 	"sync"
 )
 
@@ -18,10 +19,13 @@ type SwarmInfoTracker struct {
 	trackers        map[string]*SwarmLoadTracker
 	trackersMutex   *sync.Mutex
 	sizeMap         map[string]int
+	waitingMap      map[string]int
 	sizeMutex       *sync.RWMutex
 	debriefMap      map[string]StorageEngine
 	debriefMutex    *sync.Mutex
 	engineGenerator StorageEngineGenerator
+	stop            chan struct{}
+	stopped         chan struct{}
 }
 
 //New creates a new instance of SwarmInfoTracker
@@ -33,16 +37,32 @@ func New(generator StorageEngineGenerator, historyLength int) *SwarmInfoTracker
 		trackers:        make(map[string]*SwarmLoadTracker),
 		trackersMutex:   &sync.Mutex{},
 		sizeMap:         make(map[string]int),
+		waitingMap:      make(map[string]int),
 		sizeMutex:       &sync.RWMutex{},
 		debriefMap:      make(map[string]StorageEngine),
 		debriefMutex:    &sync.Mutex{},
 		engineGenerator: generator,
+		stop:            make(chan struct{}),
+		stopped:         make(chan struct{}),
 	}
 	go calculateFrequencyOnInterval(historyLength, tracker.loadMap, tracker.trackers,
-		tracker.loadMutex, tracker.trackersMutex)
+		tracker.loadMutex, tracker.trackersMutex, tracker.stop, tracker.stopped)
 	return tracker
 }
 
+/*Close stops the periodic frequency calculation and returns
+once it has stopped*/
+func (st *SwarmInfoTracker) Close() error {
+	select {
+	case <-st.stop:
+		return fmt.Errorf("Failed to close in SwarmInfoTracker.Close(): Already closed")
+	default:
+	}
+	close(st.stop)
+	<-st.stopped
+	return nil
+}
+
 func (st *SwarmInfoTracker) IncrementFrequencyCounter(dataspace string) {
 	st.loadMutex.RLock()
 	var ok bool
@@ -97,6 +117,25 @@ func (st *SwarmInfoTracker) SetSize(swarmID string, size int) {
 	st.sizeMutex.Unlock()
 }
 
+/*SetWaiting records the number of requesters waiting for an endpoint
+of 'swarmID'*/
+func (st *SwarmInfoTracker) SetWaiting(swarmID string, waiting int) {
+	st.sizeMutex.Lock()
+	defer st.sizeMutex.Unlock()
+	if waiting > 0 {
+		st.waitingMap[swarmID] = waiting
+	} else {
+		delete(st.waitingMap, swarmID)
+	}
+}
+
+//GetWaiting returns the number of requesters waiting for an endpoint of 'swarmID'
+func (st *SwarmInfoTracker) GetWaiting(swarmID string) int {
+	st.sizeMutex.RLock()
+	defer st.sizeMutex.RUnlock()
+	return st.waitingMap[swarmID]
+}
+
 func (st *SwarmInfoTracker) AddDebriefDatapoint(swarmID string, debrief interface{}) {
 	st.debriefMutex.Lock()
 	if _, ok := st.debriefMap[swarmID]; !ok {
@@ -120,6 +159,7 @@ func (st *SwarmInfoTracker) GetDebriefData(swarmID string) interface{} {
 func (st *SwarmInfoTracker) Delete(swarmID string) {
 	st.sizeMutex.Lock()
 	delete(st.sizeMap, swarmID)
+	delete(st.waitingMap, swarmID)
 	st.sizeMutex.Unlock()
 
 	st.debriefMutex.Lock()
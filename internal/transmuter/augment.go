@@ -13,15 +13,20 @@ const (
 )
 
 const (
-	bisectKey = iota
-	mergeKey
+	BisectKey = iota
+	MergeKey
 )
 
 var PollPeriod = time.Minute
 
-func pollForTransmutation(swarmMap SwarmMap, analyzer SwarmAnalyzer) {
+func pollForTransmutation(swarmMap SwarmMap, analyzer SwarmAnalyzer, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
 	for {
-		time.Sleep(PollPeriod)
+		select {
+		case <-time.After(PollPeriod):
+		case <-stop:
+			return
+		}
 		candidates, err := analyzer.CalculateCandidates()
 		if err != nil {
 			log.Println(err)
@@ -34,6 +39,13 @@ func pollForTransmutation(swarmMap SwarmMap, analyzer SwarmAnalyzer) {
 				log.Println(err)
 			}
 		}
+
+		restructures, err := analyzer.CalculateRestructures()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		restructureSwarms(swarmMap, restructures)
 	}
 }
 
@@ -54,6 +66,13 @@ func transmuteSwarms(swarmMap SwarmMap, candidates []Candidate) error {
 			return fmt.Errorf("Failed to retrieve transferee SwarmManager of dataspace %s in SwarmTransmuter daemon: %v", transfererID, err)
 		}
 		transferee := t.(SwarmManager)
+		//Endpoints that cannot be told about their new dataspace stay where they are
+		if movable := transferer.GetMovableSize(); transferSize > movable {
+			transferSize = movable
+		}
+		if transferSize <= 0 {
+			continue
+		}
 		err = transferer.Transfer(transferSize, transferee)
 		if err != nil {
 			return fmt.Errorf("Failed to transfer endpoints in SwarmTransmuter daemon: %v", err)
@@ -61,3 +80,76 @@ func transmuteSwarms(swarmMap SwarmMap, candidates []Candidate) error {
 	}
 	return nil
 }
+
+/*restructureSwarms applies each restructure in turn. A failed
+restructure is logged and the rest are still applied since they
+are computed independently of each other*/
+func restructureSwarms(swarmMap SwarmMap, restructures []Restructure) {
+	for _, restructure := range restructures {
+		swarmIDs := restructure.GetSwarmIDs()
+		switch restructure.GetKind() {
+		case MergeKey:
+			err := mergeSwarms(swarmMap, swarmIDs[0], swarmIDs[1])
+			if err != nil {
+				log.Printf(mergeSwarmFailFormat, err)
+			}
+		case BisectKey:
+			err := bisectSwarm(swarmMap, swarmIDs[0])
+			if err != nil {
+				log.Printf(splitSwarmFailFormat, err)
+			}
+		}
+	}
+}
+
+/*mergeSwarms makes the kept swarm serve the dataspaces of the merged
+swarm. The kept swarm absorbs the endpoints of the merged swarm before
+it is closed. Endpoints checked out by the merged swarm at that time are
+disconnected once they are released*/
+func mergeSwarms(swarmMap SwarmMap, keptID string, mergedID string) error {
+	k, err := swarmMap.GetSwarm(keptID)
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve kept SwarmManager %s in mergeSwarms(): %v", keptID, err)
+	}
+	kept := k.(SwarmManager)
+
+	m, err := swarmMap.MergeSwarms(keptID, mergedID)
+	if err != nil {
+		return fmt.Errorf("Failed to merge %s into %s in mergeSwarms(): %v", mergedID, keptID, err)
+	}
+	merged := m.(SwarmManager)
+
+	err = kept.Absorb(merged)
+	if err != nil {
+		log.Printf(mergeSwarmFailFormat, err)
+	}
+	return merged.Close()
+}
+
+/*bisectSwarm moves half the dataspaces of a swarm to a new swarm along
+with half the endpoints it can move. Endpoints pinned to the dataspaces
+that moved follow them. If the endpoints cannot be moved the new swarm
+starts empty and is filled as the most needy swarm*/
+func bisectSwarm(swarmMap SwarmMap, swarmID string) error {
+	k, err := swarmMap.GetSwarm(swarmID)
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve SwarmManager %s in bisectSwarm(): %v", swarmID, err)
+	}
+	kept := k.(SwarmManager)
+
+	b, err := swarmMap.BisectSwarm(swarmID)
+	if err != nil {
+		return fmt.Errorf("Failed to bisect %s in bisectSwarm(): %v", swarmID, err)
+	}
+	bisected := b.(SwarmManager)
+
+	err = kept.TransferRestricted(bisected)
+	if err != nil {
+		log.Printf(splitSwarmFailFormat, err)
+	}
+	err = kept.Transfer(kept.GetMovableSize()/2, bisected)
+	if err != nil {
+		return fmt.Errorf("Failed to move endpoints out of %s in bisectSwarm(): %v", swarmID, err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package transmuter
 
 import (
+	"context"
 	"io"
 )
 
@@ -8,12 +9,19 @@ import (
 to the dataspaces they serve*/
 type SwarmMap interface {
 	GetSwarm(string) (interface{}, error)
+	GetDataspaces() []string
+	IsOwnedBy(dataspace string, originID string) bool
+	//Returns the manager of the merged swarm after unmapping it
+	MergeSwarms(keptID string, mergedID string) (interface{}, error)
+	//Returns the manager of the new swarm holding half the dataspaces
+	BisectSwarm(swarmID string) (interface{}, error)
 }
 
 /*SwarmAnalyzer describes an object that can make
 recommendations for how to split/merge swarms*/
 type SwarmAnalyzer interface {
 	CalculateCandidates() ([]Candidate, error)
+	CalculateRestructures() ([]Restructure, error)
 	GetMostNeedy() (string, error)
 }
 
@@ -24,8 +32,31 @@ type Candidate interface {
 	GetTransferSize() int
 }
 
+/*Restructure describes a change to the layout of swarms. A
+MergeKey kind lists the kept swarm followed by the merged swarm
+and a BisectKey kind lists the swarm to bisect*/
+type Restructure interface {
+	GetKind() int
+	GetSwarmIDs() []string
+}
+
+//TransferAll is passed to SwarmManager.Transfer to move every endpoint that can be moved
+const TransferAll = -1
+
 type SwarmManager interface {
-	AddEndpoint(interface{}) error
+	AddEndpoint(context.Context, interface{}) error
+	//Adds an endpoint that only serves the dataspace and is never transferred to another swarm without it
+	AddPinnedEndpoint(context.Context, interface{}, string) error
+	//Removes the endpoint that was issued the identity after it logged off
+	RemoveEndpoint(string) error
+	//Moves exactly that many endpoints that can be told about their new dataspaces or none at all
 	Transfer(int, SwarmManager) error
+	//Moves every endpoint of a swarm merged into this one and tells its own endpoints about the dataspaces it gained
+	Absorb(SwarmManager) error
+	//Moves the endpoints that cannot migrate and only serve dataspaces of the other swarm, such as those pinned to them
+	TransferRestricted(SwarmManager) error
+	GetSize() int
+	//Returns the number of endpoints Transfer can move at the moment
+	GetMovableSize() int
 	io.Closer
 }
@@ -1,47 +1,119 @@
 package transmuter
 
 import (
+	"context"
 	"fmt"
-	"log"
 
+	"github.com/arstevens/go-hive-signal/internal/responder"
+	"github.com/arstevens/go-hive-signal/internal/vivaldi"
 	"github.com/arstevens/go-request/handle"
 )
 
-const transmuterFailFormat = "Failed to process connection in SwarmTransmuter: %v"
+const transmuterFailFormat = "Failed to process connection in SwarmTransmuter: %w"
 
 /*SwarmTransmuter handles any commands that result in a change in
 the makeup of a swarm*/
 type SwarmTransmuter struct {
 	swarmMap SwarmMap
 	analyzer SwarmAnalyzer
+	stop     chan struct{}
+	stopped  chan struct{}
 }
 
 //New creates a new SwarmTransmuter
 func New(mapper SwarmMap, analyzer SwarmAnalyzer) *SwarmTransmuter {
-	go pollForTransmutation(mapper, analyzer)
-	return &SwarmTransmuter{
+	transmuter := &SwarmTransmuter{
 		swarmMap: mapper,
 		analyzer: analyzer,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
 	}
+	go pollForTransmutation(mapper, analyzer, transmuter.stop, transmuter.stopped)
+	return transmuter
 }
 
-//ProcessConnection processes a new request identified by 'code'
-func (st *SwarmTransmuter) ProcessConnection(dataspaceID string, swarmConnect bool, conn handle.Conn) error {
-	if swarmConnect {
-		needyID, err := st.analyzer.GetMostNeedy()
+/*Close stops the periodic restructuring of swarms and returns once
+any restructuring in progress has finished*/
+func (st *SwarmTransmuter) Close() error {
+	select {
+	case <-st.stop:
+		return fmt.Errorf("Failed to close in SwarmTransmuter.Close(): Already closed")
+	default:
+	}
+	close(st.stop)
+	<-st.stopped
+	return nil
+}
+
+/*ProcessConnection adds the endpoint behind 'conn' to the neediest swarm
+when 'swarmConnect' is set and removes it from its swarm otherwise. Adding
+the endpoint to a swarm is abandoned once 'ctx' is done*/
+func (st *SwarmTransmuter) ProcessConnection(ctx context.Context, dataspaceID string, swarmConnect bool, conn handle.Conn) error {
+	if !swarmConnect {
+		return st.logOff(dataspaceID, conn)
+	}
+	needyID, err := st.analyzer.GetMostNeedy()
+	if err != nil {
+		/*No swarms in need to a new endpoint so reject connection.
+		In reality this only happens when the analyzer has yet to
+		run it's first swarm analysis or if no swarms are registered*/
+		return responder.Errorf(responder.SwarmUnavailable, transmuterFailFormat, err)
+	}
+	m, err := st.swarmMap.GetSwarm(needyID)
+	if err != nil {
+		return fmt.Errorf(transmuterFailFormat, err)
+	}
+	manager := m.(SwarmManager)
+	err = manager.AddEndpoint(ctx, conn)
+	if err != nil {
+		return fmt.Errorf(transmuterFailFormat, err)
+	}
+	return nil
+}
+
+/*PinConnection adds the endpoint behind 'conn' to the swarm of 'dataspaceID'
+for good. The endpoint must come from the origin owning the dataspace. The
+addition is abandoned once 'ctx' is done*/
+func (st *SwarmTransmuter) PinConnection(ctx context.Context, dataspaceID string, originID string, conn handle.Conn) error {
+	m, err := st.swarmMap.GetSwarm(dataspaceID)
+	if err != nil {
+		return fmt.Errorf(transmuterFailFormat, err)
+	}
+	if !st.swarmMap.IsOwnedBy(dataspaceID, originID) {
+		return responder.Errorf(responder.VerificationFailed, transmuterFailFormat,
+			fmt.Errorf("Origin %s does not own dataspace %s", originID, dataspaceID))
+	}
+	err = m.(SwarmManager).AddPinnedEndpoint(ctx, conn, dataspaceID)
+	if err != nil {
+		return fmt.Errorf(transmuterFailFormat, err)
+	}
+	return nil
+}
+
+/*logOff removes the endpoint that logged off through 'conn' from its swarm.
+The swarm of 'dataspaceID' is searched first and every other swarm after it
+since endpoints do not know which swarm they were added to. Endpoints are
+identified by their key in the CoordinateRegistry*/
+func (st *SwarmTransmuter) logOff(dataspaceID string, conn handle.Conn) error {
+	//The identity was issued to the endpoint alone so no other client can log it off
+	id := vivaldi.IdentityOf(conn)
+	if id == "" {
+		return responder.Errorf(responder.UnknownEndpoint, transmuterFailFormat,
+			fmt.Errorf("Endpoint logging off presented no identity"))
+	}
+	dataspaces := st.swarmMap.GetDataspaces()
+	if dataspaceID != "" {
+		dataspaces = append([]string{dataspaceID}, dataspaces...)
+	}
+	for _, dataspace := range dataspaces {
+		m, err := st.swarmMap.GetSwarm(dataspace)
 		if err != nil {
-			/*No swarms in need to a new endpoint so drop connection.
-			In reality this only happens when the analyzer has yet to
-			run it's first swarm analysis or if no swarms are registered*/
-			log.Printf(transmuterFailFormat, err)
-			return nil
+			continue
 		}
-		m, err := st.swarmMap.GetSwarm(needyID)
-		if err != nil {
-			return fmt.Errorf(transmuterFailFormat, err)
+		if m.(SwarmManager).RemoveEndpoint(id) == nil {
+			return nil
 		}
-		manager := m.(SwarmManager)
-		manager.AddEndpoint(conn)
 	}
-	return nil
+	return responder.Errorf(responder.UnknownEndpoint, transmuterFailFormat,
+		fmt.Errorf("No endpoint with identity %s in any swarm", id))
 }
@@ -1,17 +1,20 @@
 package transmuter
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strconv"
 	"testing"
 	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/responder"
 )
 
 func TestSwarmTransmuter(t *testing.T) {
 	totalSwarms := 5
 	endpointsPerSwarm := 5
-	smap := &TestSwarmMap{managers: make(map[string]SwarmManager)}
+	smap := &TestSwarmMap{managers: make(map[string]SwarmManager), owners: map[string]string{"/dataspace/0": "/origin/0"}}
 	analyzer := &TestSwarmAnalyzer{smap: smap}
 	for i := 0; i < totalSwarms; i++ {
 		endpoints := make([]string, endpointsPerSwarm)
@@ -31,11 +34,63 @@ func TestSwarmTransmuter(t *testing.T) {
 	for i := 0; i < totalConnections; i++ {
 		fc := &FakeConn{id: "/endpoint/" + strconv.Itoa(totalSwarms*endpointsPerSwarm+i)}
 		dspace := "/dataspace/" + strconv.Itoa(rand.Intn(totalSwarms))
-		transmuter.ProcessConnection(dspace, true, fc)
+		transmuter.ProcessConnection(context.Background(), dspace, true, fc)
 	}
 	printSwarmSizes(smap.managers)
 	time.Sleep(time.Second * 5)
 	printSwarmSizes(smap.managers)
+	transmuter.Close()
+
+	fmt.Printf("Logging off /endpoint/0...\n")
+	err := transmuter.ProcessConnection(context.Background(), "", false, &FakeConn{id: "/endpoint/0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = transmuter.ProcessConnection(context.Background(), "", false, &FakeConn{id: "/endpoint/0"})
+	if err == nil {
+		t.Fatalf("Expected a second log off of the same endpoint to fail")
+	}
+	if responder.CategoryOf(err, responder.NoError) != responder.UnknownEndpoint {
+		t.Fatalf("Expected an unknown endpoint to be reported as such: %v", err)
+	}
+
+	fmt.Printf("Pinning /endpoint/pinned to /dataspace/0...\n")
+	err = transmuter.PinConnection(context.Background(), "/dataspace/0", "/origin/1", &FakeConn{id: "/endpoint/pinned"})
+	if responder.CategoryOf(err, responder.NoError) != responder.VerificationFailed {
+		t.Fatalf("Expected an origin not owning the dataspace to be refused, got %v", err)
+	}
+	err = transmuter.PinConnection(context.Background(), "/dataspace/0", "/origin/0", &FakeConn{id: "/endpoint/pinned"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	printSwarmSizes(smap.managers)
+}
+
+func TestRestructureSwarms(t *testing.T) {
+	smap := &TestSwarmMap{managers: make(map[string]SwarmManager)}
+	smap.managers["/dataspace/0"] = &TestSwarmManager{endpoints: []string{"/endpoint/0", "/endpoint/1"}}
+	smap.managers["/dataspace/1"] = &TestSwarmManager{endpoints: []string{"/endpoint/2", "/endpoint/3"},
+		pinned: []string{"/endpoint/pinned"}}
+
+	fmt.Printf("Merging /dataspace/1 into /dataspace/0...\n")
+	restructureSwarms(smap, []Restructure{&TestRestructure{kind: MergeKey, swarmIDs: []string{"/dataspace/0", "/dataspace/1"}}})
+	printSwarmSizes(smap.managers)
+	if len(smap.managers) != 1 || smap.managers["/dataspace/0"].GetSize() != 4 {
+		t.Fatalf("Expected every endpoint to be in the kept swarm after a merge")
+	}
+	if len(smap.managers["/dataspace/0"].(*TestSwarmManager).pinned) != 1 {
+		t.Fatalf("Expected the pinned endpoint to be in the kept swarm after a merge")
+	}
+
+	fmt.Printf("Bisecting /dataspace/0...\n")
+	restructureSwarms(smap, []Restructure{&TestRestructure{kind: BisectKey, swarmIDs: []string{"/dataspace/0"}}})
+	printSwarmSizes(smap.managers)
+	if len(smap.managers) != 2 || smap.managers["/dataspace/0"].GetSize() != 2 {
+		t.Fatalf("Expected half the endpoints to be in the new swarm after a bisect")
+	}
+	if len(smap.managers["/dataspace/0/bisected"].(*TestSwarmManager).pinned) != 1 {
+		t.Fatalf("Expected the pinned endpoint to follow its dataspace to the new swarm")
+	}
 }
 
 func printSwarmSizes(m map[string]SwarmManager) {
@@ -52,6 +107,11 @@ func printSwarmSizes(m map[string]SwarmManager) {
 
 type TestSwarmMap struct {
 	managers map[string]SwarmManager
+	owners   map[string]string
+}
+
+func (tm *TestSwarmMap) IsOwnedBy(id string, origin string) bool {
+	return tm.owners[id] == origin
 }
 
 func (tm *TestSwarmMap) GetSwarm(id string) (interface{}, error) {
@@ -61,6 +121,36 @@ func (tm *TestSwarmMap) GetSwarm(id string) (interface{}, error) {
 	return nil, fmt.Errorf("No Swarm with id %s", id)
 }
 
+func (tm *TestSwarmMap) GetDataspaces() []string {
+	return mapToSlice(tm.managers)
+}
+
+func (tm *TestSwarmMap) MergeSwarms(keptID string, mergedID string) (interface{}, error) {
+	merged, ok := tm.managers[mergedID]
+	if _, kept := tm.managers[keptID]; !ok || !kept {
+		return nil, fmt.Errorf("No Swarm with id %s or %s", keptID, mergedID)
+	}
+	delete(tm.managers, mergedID)
+	return merged, nil
+}
+
+func (tm *TestSwarmMap) BisectSwarm(id string) (interface{}, error) {
+	if _, ok := tm.managers[id]; !ok {
+		return nil, fmt.Errorf("No Swarm with id %s", id)
+	}
+	manager := &TestSwarmManager{endpoints: make([]string, 0)}
+	tm.managers[id+"/bisected"] = manager
+	return manager, nil
+}
+
+type TestRestructure struct {
+	kind     int
+	swarmIDs []string
+}
+
+func (tr *TestRestructure) GetKind() int          { return tr.kind }
+func (tr *TestRestructure) GetSwarmIDs() []string { return tr.swarmIDs }
+
 type TestCandidate struct {
 	transferer string
 	transferee string
@@ -100,6 +190,10 @@ func (ta *TestSwarmAnalyzer) CalculateCandidates() ([]Candidate, error) {
 	return candidates, nil
 }
 
+func (ta *TestSwarmAnalyzer) CalculateRestructures() ([]Restructure, error) {
+	return []Restructure{}, nil
+}
+
 func mapToSlice(m map[string]SwarmManager) []string {
 	s := make([]string, 0, len(m))
 	for key, _ := range m {
@@ -110,16 +204,21 @@ func mapToSlice(m map[string]SwarmManager) []string {
 
 type TestSwarmManager struct {
 	endpoints []string
+	//Endpoints pinned to a dataspace are kept apart from those that can be transferred
+	pinned []string
 }
 
 func (sm *TestSwarmManager) SetID(string) {}
-func (sm *TestSwarmManager) AddEndpoint(i interface{}) error {
+func (sm *TestSwarmManager) AddEndpoint(ctx context.Context, i interface{}) error {
 	c := i.(*FakeConn)
 	return sm.TakeEndpoint(c.id)
 }
-func (sm *TestSwarmManager) RemoveEndpoint(i interface{}) error {
-	c := i.(*FakeConn)
-	return sm.DropEndpoint(c.id)
+func (sm *TestSwarmManager) AddPinnedEndpoint(ctx context.Context, i interface{}, dataspace string) error {
+	sm.pinned = append(sm.pinned, i.(*FakeConn).id)
+	return nil
+}
+func (sm *TestSwarmManager) RemoveEndpoint(id string) error {
+	return sm.DropEndpoint(id)
 }
 func (sm *TestSwarmManager) TakeEndpoint(s string) error {
 	sm.endpoints = append(sm.endpoints, s)
@@ -133,18 +232,36 @@ func (sm *TestSwarmManager) DropEndpoint(s string) error {
 			return nil
 		}
 	}
-	return nil
+	return fmt.Errorf("No endpoint %s", s)
 }
 
 func (sm *TestSwarmManager) Transfer(size int, man2 SwarmManager) error {
 	man := man2.(*TestSwarmManager)
-	for i := 0; len(sm.endpoints) > 0 && i < size; i++ {
+	for i := 0; len(sm.endpoints) > 0 && (size == TransferAll || i < size); i++ {
 		man.endpoints = append(man.endpoints, sm.endpoints[0])
 		sm.endpoints = sm.endpoints[1:]
 	}
 	return nil
 }
 
+func (sm *TestSwarmManager) Absorb(man2 SwarmManager) error {
+	man2.Transfer(TransferAll, sm)
+	return man2.TransferRestricted(sm)
+}
+
+func (sm *TestSwarmManager) TransferRestricted(man2 SwarmManager) error {
+	man := man2.(*TestSwarmManager)
+	man.pinned = append(man.pinned, sm.pinned...)
+	sm.pinned = nil
+	return nil
+}
+
+func (sm *TestSwarmManager) GetSize() int {
+	return len(sm.endpoints)
+}
+func (sm *TestSwarmManager) GetMovableSize() int {
+	return len(sm.endpoints)
+}
 func (sm *TestSwarmManager) GetEndpoints() []string {
 	return sm.endpoints
 }
@@ -157,3 +274,5 @@ type FakeConn struct{ id string }
 func (fc *FakeConn) Read([]byte) (int, error)  { return 0, nil }
 func (fc *FakeConn) Write([]byte) (int, error) { return 0, nil }
 func (fc *FakeConn) Close() error              { return nil }
+func (fc *FakeConn) GetAddress() string        { return fc.id }
+func (fc *FakeConn) GetEndpointID() string     { return fc.id }
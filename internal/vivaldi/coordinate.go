@@ -0,0 +1,120 @@
+package vivaldi
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+//Dimensions is the number of euclidean dimensions of new Coordinates
+var Dimensions = 3
+
+/*ErrorDamping and CorrectionDamping are the Ce and Cc constants of the
+Vivaldi algorithm. They bound how much a single sample moves the error
+estimate and the position of a Coordinate*/
+var ErrorDamping = 0.25
+var CorrectionDamping = 0.25
+
+const (
+	//maxError is the error of a Coordinate that has never been updated
+	maxError = 1.5
+	//minHeight keeps the height of a Coordinate positive (in seconds)
+	minHeight = 1.0e-5
+	//zeroDistance is the distance (in seconds) below which two points are considered equal
+	zeroDistance = 1.0e-6
+)
+
+/*Coordinate places a node in a euclidean space augmented with a height
+such that the distance between two Coordinates predicts the round trip
+time between their nodes. Distances are in seconds. Height models the
+latency of the access link of the node which every path to it shares*/
+type Coordinate struct {
+	Vec    []float64
+	Height float64
+	//Relative error of the predictions made with the Coordinate
+	Error float64
+}
+
+//NewCoordinate returns a Coordinate at the origin with the highest error
+func NewCoordinate() Coordinate {
+	return Coordinate{Vec: make([]float64, Dimensions), Height: minHeight, Error: maxError}
+}
+
+//DistanceTo returns the round trip time predicted between 'c' and 'other'
+func (c Coordinate) DistanceTo(other Coordinate) time.Duration {
+	seconds := c.rawDistanceTo(other) + c.Height + other.Height
+	return time.Duration(seconds * float64(time.Second))
+}
+
+/*Update returns 'c' moved according to a round trip time of 'rtt'
+measured between the node at 'c' and the node at 'other'*/
+func (c Coordinate) Update(other Coordinate, rtt time.Duration) Coordinate {
+	sample := rtt.Seconds()
+	if sample <= 0 || len(c.Vec) != len(other.Vec) {
+		return c
+	}
+	predicted := c.DistanceTo(other).Seconds()
+
+	//Weigh the sample by how confident both ends are in their Coordinates
+	weight := c.Error / (c.Error + other.Error)
+	relativeError := math.Abs(predicted-sample) / sample
+	updated := Coordinate{
+		Vec:    make([]float64, len(c.Vec)),
+		Height: c.Height,
+		Error:  relativeError*ErrorDamping*weight + c.Error*(1-ErrorDamping*weight),
+	}
+	if updated.Error > maxError {
+		updated.Error = maxError
+	}
+
+	//Move along the line between both nodes to correct the prediction
+	force := CorrectionDamping * weight * (sample - predicted)
+	unit, magnitude := c.unitVectorFrom(other)
+	for i := range c.Vec {
+		updated.Vec[i] = c.Vec[i] + unit[i]*force
+	}
+	if magnitude > zeroDistance {
+		updated.Height = c.Height + (c.Height+other.Height)*force/magnitude
+	}
+	if updated.Height < minHeight {
+		updated.Height = minHeight
+	}
+	return updated
+}
+
+func (c Coordinate) rawDistanceTo(other Coordinate) float64 {
+	sum := 0.0
+	for i := range c.Vec {
+		diff := c.Vec[i] - other.Vec[i]
+		sum += diff * diff
+	}
+	return math.Sqrt(sum)
+}
+
+/*unitVectorFrom returns the unit vector pointing from 'other' to 'c' along
+with the distance between them. A random direction is returned when both
+share a position so that they can drift apart*/
+func (c Coordinate) unitVectorFrom(other Coordinate) ([]float64, float64) {
+	unit := make([]float64, len(c.Vec))
+	magnitude := c.rawDistanceTo(other)
+	if magnitude > zeroDistance {
+		for i := range unit {
+			unit[i] = (c.Vec[i] - other.Vec[i]) / magnitude
+		}
+		return unit, magnitude
+	}
+
+	length := 0.0
+	for i := range unit {
+		unit[i] = rand.Float64() - 0.5
+		length += unit[i] * unit[i]
+	}
+	length = math.Sqrt(length)
+	if length == 0 {
+		return unit, 0
+	}
+	for i := range unit {
+		unit[i] /= length
+	}
+	return unit, 0
+}
@@ -0,0 +1,19 @@
+package vivaldi
+
+import "net"
+
+//IPConn describes a connection that knows the IP address of its remote end
+type IPConn interface {
+	GetIP() net.IP
+}
+
+/*IdentifiedConn describes a connection to an endpoint that was issued an
+identity when it logged on. GetEndpointID returns "" for any other node*/
+type IdentifiedConn interface {
+	GetEndpointID() string
+}
+
+//AddressedConn describes a connection that knows the address of its remote end
+type AddressedConn interface {
+	GetAddress() string
+}
@@ -0,0 +1,133 @@
+package vivaldi
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"time"
+)
+
+/*Server is the key under which the Coordinate of the server itself is
+kept. It can never collide with the key of another node*/
+const Server = "server"
+
+/*Registry keeps the Coordinates of the nodes the server hears about keyed
+by the identity of endpoints and the IP address of any other node. Once it holds 'capacity' nodes the Coordinate updated
+the longest ago is forgotten to make room. It is safe for concurrent use*/
+type Registry struct {
+	mutex    *sync.Mutex
+	nodes    map[string]*list.Element
+	recency  *list.List
+	capacity int
+}
+
+type node struct {
+	key        string
+	coordinate Coordinate
+}
+
+//NewRegistry creates a Registry holding up to 'capacity' Coordinates
+func NewRegistry(capacity int) *Registry {
+	return &Registry{
+		mutex:    &sync.Mutex{},
+		nodes:    make(map[string]*list.Element),
+		recency:  list.New(),
+		capacity: capacity,
+	}
+}
+
+/*Observe records a round trip time of 'rtt' measured between the nodes
+'a' and 'b' and moves both of their Coordinates accordingly*/
+func (r *Registry) Observe(a string, b string, rtt time.Duration) {
+	if a == b || a == "" || b == "" {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	aCoord, _ := r.get(a)
+	bCoord, _ := r.get(b)
+	r.set(a, aCoord.Update(bCoord, rtt))
+	r.set(b, bCoord.Update(aCoord, rtt))
+}
+
+/*Estimate returns the round trip time predicted between the nodes 'a'
+and 'b'. It fails if either node has never been observed*/
+func (r *Registry) Estimate(a string, b string) (time.Duration, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	aCoord, aKnown := r.get(a)
+	bCoord, bKnown := r.get(b)
+	if !aKnown || !bKnown {
+		return 0, false
+	}
+	return aCoord.DistanceTo(bCoord), true
+}
+
+//GetCoordinate returns the Coordinate of 'key' and whether or not it was ever observed
+func (r *Registry) GetCoordinate(key string) (Coordinate, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.get(key)
+}
+
+//GetSize returns the number of nodes with a Coordinate
+func (r *Registry) GetSize() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.recency.Len()
+}
+
+func (r *Registry) get(key string) (Coordinate, bool) {
+	if element, ok := r.nodes[key]; ok {
+		return element.Value.(*node).coordinate, true
+	}
+	return NewCoordinate(), false
+}
+
+func (r *Registry) set(key string, coordinate Coordinate) {
+	if element, ok := r.nodes[key]; ok {
+		element.Value.(*node).coordinate = coordinate
+		r.recency.MoveToFront(element)
+		return
+	}
+	if r.capacity > 0 && r.recency.Len() >= r.capacity {
+		oldest := r.recency.Back()
+		r.recency.Remove(oldest)
+		delete(r.nodes, oldest.Value.(*node).key)
+	}
+	r.nodes[key] = r.recency.PushFront(&node{key: key, coordinate: coordinate})
+}
+
+//IdentityOf returns the identity issued to the endpoint behind 'conn' or "" if it has none
+func IdentityOf(conn interface{}) string {
+	if iConn, ok := conn.(IdentifiedConn); ok {
+		return iConn.GetEndpointID()
+	}
+	return ""
+}
+
+/*KeyOf returns the key of the node at the remote end of 'conn'. It is the
+identity of the endpoint if it was issued one so endpoints behind the same
+NAT keep Coordinates of their own. Other nodes are keyed by their IP address
+when it is known or by their address otherwise. An empty key is returned for
+conns that know neither*/
+func KeyOf(conn interface{}) string {
+	if iConn, ok := conn.(IdentifiedConn); ok {
+		if id := iConn.GetEndpointID(); id != "" {
+			return id
+		}
+	}
+	if ipConn, ok := conn.(IPConn); ok {
+		if ip := ipConn.GetIP(); ip != nil {
+			return ip.String()
+		}
+	}
+	if aConn, ok := conn.(AddressedConn); ok {
+		address := aConn.GetAddress()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			return host
+		}
+		return address
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package vivaldi
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	fmt.Printf("----------------------\nVIVALDI REGISTRY TEST\n----------------------\n")
+	//Round trip times between three cities and the server
+	rtts := map[[2]string]time.Duration{
+		{Server, "paris"}:      time.Millisecond * 10,
+		{Server, "newyork"}:    time.Millisecond * 80,
+		{Server, "tokyo"}:      time.Millisecond * 220,
+		{"paris", "newyork"}:   time.Millisecond * 75,
+		{"paris", "tokyo"}:     time.Millisecond * 225,
+		{"newyork", "tokyo"}:   time.Millisecond * 160,
+		{"paris", "london"}:    time.Millisecond * 8,
+		{"london", "newyork"}:  time.Millisecond * 70,
+		{Server, "london"}:     time.Millisecond * 12,
+		{"london", "tokyo"}:    time.Millisecond * 230,
+		{"newyork", "chicago"}: time.Millisecond * 20,
+	}
+	registry := NewRegistry(0)
+	for round := 0; round < 200; round++ {
+		for pair, rtt := range rtts {
+			registry.Observe(pair[0], pair[1], rtt)
+		}
+	}
+
+	for pair, rtt := range rtts {
+		estimate, ok := registry.Estimate(pair[0], pair[1])
+		if !ok {
+			t.Fatalf("No estimate between %s and %s", pair[0], pair[1])
+		}
+		relativeError := math.Abs(float64(estimate-rtt)) / float64(rtt)
+		fmt.Printf("\t%s <-> %s: measured %v, predicted %v\n", pair[0], pair[1], rtt, estimate.Round(time.Millisecond))
+		if relativeError > 0.5 {
+			t.Fatalf("Prediction between %s and %s is off by %.0f%%", pair[0], pair[1], relativeError*100)
+		}
+	}
+
+	toParis, _ := registry.Estimate("chicago", "paris")
+	toTokyo, _ := registry.Estimate("chicago", "tokyo")
+	fmt.Printf("\tNever measured: chicago <-> paris %v, chicago <-> tokyo %v\n",
+		toParis.Round(time.Millisecond), toTokyo.Round(time.Millisecond))
+	if toParis >= toTokyo {
+		t.Fatalf("Expected chicago to be predicted closer to paris than to tokyo")
+	}
+	if _, ok := registry.Estimate("paris", "sydney"); ok {
+		t.Fatalf("Expected no estimate for a node never observed")
+	}
+}
+
+func TestRegistryCapacity(t *testing.T) {
+	fmt.Printf("----------------------\nVIVALDI CAPACITY TEST\n----------------------\n")
+	registry := NewRegistry(3)
+	registry.Observe("a", "b", time.Millisecond*10)
+	registry.Observe("c", "b", time.Millisecond*10)
+	registry.Observe("d", "b", time.Millisecond*10)
+	fmt.Printf("\tNodes held: %d\n", registry.GetSize())
+	if registry.GetSize() != 3 {
+		t.Fatalf("Expected the registry to hold 3 nodes, got %d", registry.GetSize())
+	}
+	if _, ok := registry.GetCoordinate("a"); ok {
+		t.Fatalf("Expected the node updated the longest ago to be forgotten")
+	}
+	if _, ok := registry.GetCoordinate("b"); !ok {
+		t.Fatalf("Expected the most recently updated node to be kept")
+	}
+}
@@ -0,0 +1,89 @@
+package wrapper
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/arstevens/go-request/handle"
+)
+
+/*acceptQueue accepts connections from a net.Listener on a goroutine of its
+own and prepares every connection on a separate goroutine. Connections are
+handed out in the order they finish preparing so a client stalling its
+handshake cannot hold up the clients behind it*/
+type acceptQueue struct {
+	listener net.Listener
+	prepare  func(net.Conn) (handle.Conn, error)
+	ready    chan handle.Conn
+	failed   chan struct{}
+	closed   chan struct{}
+	err      error
+	start    *sync.Once
+	stop     *sync.Once
+}
+
+func newAcceptQueue(listener net.Listener, prepare func(net.Conn) (handle.Conn, error)) *acceptQueue {
+	return &acceptQueue{
+		listener: listener,
+		prepare:  prepare,
+		ready:    make(chan handle.Conn),
+		failed:   make(chan struct{}),
+		closed:   make(chan struct{}),
+		start:    &sync.Once{},
+		stop:     &sync.Once{},
+	}
+}
+
+/*next returns the next prepared connection or the error that stopped
+the listener*/
+func (aq *acceptQueue) next() (handle.Conn, error) {
+	aq.start.Do(func() { go aq.acceptLoop() })
+	select {
+	case conn := <-aq.ready:
+		return conn, nil
+	case <-aq.failed:
+		return nil, aq.err
+	case <-aq.closed:
+		return nil, fmt.Errorf("Listener closed in acceptQueue.next()")
+	}
+}
+
+//close closes the listener and every connection still being prepared
+func (aq *acceptQueue) close() error {
+	var err error
+	aq.stop.Do(func() {
+		close(aq.closed)
+		err = aq.listener.Close()
+	})
+	return err
+}
+
+func (aq *acceptQueue) acceptLoop() {
+	for {
+		nConn, err := aq.listener.Accept()
+		if err != nil {
+			aq.err = err
+			close(aq.failed)
+			return
+		}
+		go aq.prepareConn(nConn)
+	}
+}
+
+func (aq *acceptQueue) prepareConn(nConn net.Conn) {
+	conn, err := aq.prepare(nConn)
+	if err != nil {
+		log.Println(err)
+		nConn.Close()
+		return
+	}
+	select {
+	case aq.ready <- conn:
+	case <-aq.failed:
+		conn.Close()
+	case <-aq.closed:
+		conn.Close()
+	}
+}
@@ -0,0 +1,29 @@
+package wrapper
+
+import (
+	"fmt"
+
+	"github.com/arstevens/go-request/handle"
+	"github.com/arstevens/go-request/route"
+)
+
+/*MessageConn describes a connection that carries discrete messages
+and therefore needs no length prefix to frame them*/
+type MessageConn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage([]byte) error
+}
+
+/*ReadRequest implements route.ReadRequest. It reads a whole message from
+a MessageConn and falls back to length prefixed framing for any other conn*/
+func ReadRequest(conn handle.Conn) ([]byte, error) {
+	if mConn, ok := conn.(MessageConn); ok {
+		raw, err := mConn.ReadMessage()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("message read error in ReadRequest(): %v", err)
+		}
+		return raw, nil
+	}
+	return route.ReadRequestFromNetConn(conn)
+}
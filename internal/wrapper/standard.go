@@ -1,50 +1,96 @@
 package wrapper
 
 import (
+	"crypto/tls"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"net"
 	"time"
 
+	"github.com/arstevens/go-hive-signal/internal/metadata"
 	"github.com/arstevens/go-request/handle"
 )
 
+/*HandshakeTimeout is the time a client is given to complete a TLS
+handshake before its connection is dropped*/
+var HandshakeTimeout = time.Second * 10
+
 // NetListener wraps a net.Listener so it implements the Listener interface
 type NetListener struct {
-	listener net.Listener
+	queue    *acceptQueue
+	encoding string
 }
 
-func NewNetListener(listener net.Listener) *NetListener {
-	return &NetListener{
-		listener: listener,
-	}
+/*NewNetListener creates a new NetListener whose clients speak
+the wire encoding 'encoding'*/
+func NewNetListener(listener net.Listener, encoding string) *NetListener {
+	nl := &NetListener{encoding: encoding}
+	nl.queue = newAcceptQueue(listener, nl.prepare)
+	return nl
 }
 
-// Accept returns a net.Conn wrapped as a NetConn and an error
+/*Accept returns a net.Conn wrapped as a NetConn and an error. TLS connections
+are handshaken concurrently before being returned and connections failing the
+handshake are dropped so that a single bad client cannot stop the listener*/
 func (nl *NetListener) Accept() (handle.Conn, error) {
-	nConn, err := nl.listener.Accept()
-	conn := GatewayNetConnWrapper{conn: nConn, closeCalled: false, closeDetected: false}
-	return &conn, err
+	return nl.queue.next()
+}
+
+func (nl *NetListener) prepare(nConn net.Conn) (handle.Conn, error) {
+	if tlsConn, ok := nConn.(*tls.Conn); ok {
+		if err := handshake(tlsConn); err != nil {
+			return nil, fmt.Errorf("Failed TLS handshake in NetListener.Accept(): %v", err)
+		}
+	}
+	conn := GatewayNetConnWrapper{conn: nConn, encoding: nl.encoding, closeCalled: false, closeDetected: false}
+	return &conn, nil
+}
+
+func handshake(conn *tls.Conn) error {
+	conn.SetDeadline(time.Now().Add(HandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+	return conn.Handshake()
 }
 
 // Close closes the underlying net.Listener
 func (nl *NetListener) Close() error {
-	return nl.listener.Close()
+	return nl.queue.close()
 }
 
 /*GatewayNetConnWrapper implements manager.Conn and gateway.Conn,
 wrapping around a standard net.Conn*/
 type GatewayNetConnWrapper struct {
 	conn          net.Conn
+	encoding      string
+	version       int32
+	capabilities  uint64
+	metadata      metadata.Metadata
+	endpointID    string
+	unread        []byte
 	closeCalled   bool
 	closeDetected bool
 }
 
-//Read delegates to net.Conn.Read
+//Read returns any unread message before delegating to net.Conn.Read
 func (gw *GatewayNetConnWrapper) Read(b []byte) (int, error) {
+	if len(gw.unread) > 0 {
+		n := copy(b, gw.unread)
+		gw.unread = gw.unread[n:]
+		return n, nil
+	}
 	return gw.conn.Read(b)
 }
 
+/*Unread pushes 'msg' back onto the connection with its length
+prefix so that it is returned again by the next read*/
+func (gw *GatewayNetConnWrapper) Unread(msg []byte) {
+	prefixed := make([]byte, 4, 4+len(msg)+len(gw.unread))
+	binary.BigEndian.PutUint32(prefixed, uint32(len(msg)))
+	prefixed = append(prefixed, msg...)
+	gw.unread = append(prefixed, gw.unread...)
+}
+
 //Write delegates to net.Conn.Write
 func (gw *GatewayNetConnWrapper) Write(b []byte) (int, error) {
 	return gw.conn.Write(b)
@@ -59,21 +105,88 @@ func (gw *GatewayNetConnWrapper) Close() error {
 	return gw.conn.Close()
 }
 
+//SetDeadline delegates to net.Conn.SetDeadline
+func (gw *GatewayNetConnWrapper) SetDeadline(t time.Time) error {
+	return gw.conn.SetDeadline(t)
+}
+
 //GetAddress returns net.Conn.RemoteAddr().String()
 func (gw *GatewayNetConnWrapper) GetAddress() string {
 	addr := gw.conn.RemoteAddr()
 	return addr.String()
 }
 
-//IsClosed tests whether or not the connection was closed
+//GetIP returns the IP address of the remote end of the net.Conn
+func (gw *GatewayNetConnWrapper) GetIP() net.IP {
+	addr := gw.conn.RemoteAddr()
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return net.ParseIP(addr.String())
+	}
+	return net.ParseIP(host)
+}
+
+//GetEncoding returns the wire encoding spoken by the remote end
+func (gw *GatewayNetConnWrapper) GetEncoding() string {
+	return gw.encoding
+}
+
+//SetProtocol records the protocol version and capabilities negotiated with the remote end
+func (gw *GatewayNetConnWrapper) SetProtocol(version int32, capabilities uint64) {
+	gw.version = version
+	gw.capabilities = capabilities
+}
+
+//GetProtocolVersion returns the negotiated protocol version
+func (gw *GatewayNetConnWrapper) GetProtocolVersion() int32 {
+	return gw.version
+}
+
+//GetCapabilities returns the negotiated capabilities
+func (gw *GatewayNetConnWrapper) GetCapabilities() uint64 {
+	return gw.capabilities
+}
+
+//SetMetadata records the metadata declared by the remote end when logging on
+func (gw *GatewayNetConnWrapper) SetMetadata(m metadata.Metadata) {
+	gw.metadata = m
+}
+
+//GetMetadata returns the metadata declared by the remote end
+func (gw *GatewayNetConnWrapper) GetMetadata() metadata.Metadata {
+	return gw.metadata
+}
+
+//SetEndpointID records the identity issued to the remote end when it logged on
+func (gw *GatewayNetConnWrapper) SetEndpointID(id string) {
+	gw.endpointID = id
+}
+
+//GetEndpointID returns the identity issued to the remote end or "" if it never logged on
+func (gw *GatewayNetConnWrapper) GetEndpointID() string {
+	return gw.endpointID
+}
+
+/*IsClosed tests whether or not the connection was closed. A byte read
+while probing the connection is kept for the next Read*/
 func (gw *GatewayNetConnWrapper) IsClosed() bool {
 	if gw.closeCalled || gw.closeDetected {
 		return true
 	}
+	if len(gw.unread) > 0 {
+		return false
+	}
 
 	one := make([]byte, 1)
 	gw.conn.SetReadDeadline(time.Now())
-	if _, err := gw.conn.Read(one); err == io.EOF {
+	n, err := gw.conn.Read(one)
+	if n > 0 {
+		gw.unread = append(gw.unread, one[:n]...)
+	}
+	if err == io.EOF {
 		gw.closeDetected = true
 	} else {
 		gw.conn.SetReadDeadline(time.Time{})
@@ -0,0 +1,445 @@
+package wrapper
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arstevens/go-hive-signal/internal/metadata"
+	"github.com/arstevens/go-request/handle"
+)
+
+//MaxMessageSize is the largest WebSocket message accepted from a client
+var MaxMessageSize = 1 << 20
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	continuationFrame byte = 0x0
+	textFrame         byte = 0x1
+	binaryFrame       byte = 0x2
+	closeFrame        byte = 0x8
+	pingFrame         byte = 0x9
+	pongFrame         byte = 0xA
+)
+
+const (
+	closeNormal          uint16 = 1000
+	closeProtocolError   uint16 = 1002
+	closeUnsupportedData uint16 = 1003
+	closeMessageTooBig   uint16 = 1009
+)
+
+/*WebSocketListener wraps a net.Listener and upgrades every accepted
+connection to a WebSocket (RFC 6455) so that browsers can reach the
+server. It implements the route.Listener interface*/
+type WebSocketListener struct {
+	queue    *acceptQueue
+	path     string
+	encoding string
+}
+
+/*NewWebSocketListener creates a new WebSocketListener that accepts upgrade
+requests for 'path'. An empty path accepts upgrades on any path. 'encoding'
+names the wire encoding spoken by clients of the listener*/
+func NewWebSocketListener(listener net.Listener, path string, encoding string) *WebSocketListener {
+	wl := &WebSocketListener{
+		path:     path,
+		encoding: encoding,
+	}
+	wl.queue = newAcceptQueue(listener, wl.prepare)
+	return wl
+}
+
+/*Accept returns the next connection that completes a WebSocket upgrade.
+Handshakes and upgrades run concurrently and connections that fail either
+are dropped*/
+func (wl *WebSocketListener) Accept() (handle.Conn, error) {
+	return wl.queue.next()
+}
+
+// Close closes the underlying net.Listener
+func (wl *WebSocketListener) Close() error {
+	return wl.queue.close()
+}
+
+func (wl *WebSocketListener) prepare(nConn net.Conn) (handle.Conn, error) {
+	if tlsConn, ok := nConn.(*tls.Conn); ok {
+		if err := handshake(tlsConn); err != nil {
+			return nil, fmt.Errorf("Failed TLS handshake in WebSocketListener.Accept(): %v", err)
+		}
+	}
+	conn, err := wl.upgrade(nConn)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to upgrade connection in WebSocketListener.Accept(): %v", err)
+	}
+	return conn, nil
+}
+
+func (wl *WebSocketListener) upgrade(nConn net.Conn) (*WebSocketConn, error) {
+	nConn.SetDeadline(time.Now().Add(HandshakeTimeout))
+	defer nConn.SetDeadline(time.Time{})
+
+	reader := bufio.NewReader(nConn)
+	request, err := http.ReadRequest(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read upgrade request: %v", err)
+	}
+
+	key, status, err := wl.validateUpgrade(request)
+	if err != nil {
+		fmt.Fprintf(nConn, "HTTP/1.1 %d %s\r\nSec-WebSocket-Version: 13\r\nConnection: close\r\n\r\n",
+			status, http.StatusText(status))
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(nConn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n"+
+		"Connection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", computeAcceptKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write upgrade response: %v", err)
+	}
+	return newWebSocketConn(nConn, reader, wl.encoding), nil
+}
+
+func (wl *WebSocketListener) validateUpgrade(request *http.Request) (string, int, error) {
+	if request.Method != http.MethodGet {
+		return "", http.StatusMethodNotAllowed, fmt.Errorf("Upgrade requested with method %s", request.Method)
+	}
+	if wl.path != "" && request.URL.Path != wl.path {
+		return "", http.StatusNotFound, fmt.Errorf("Upgrade requested on unknown path %s", request.URL.Path)
+	}
+	if !headerContainsToken(request.Header, "Connection", "upgrade") ||
+		!headerContainsToken(request.Header, "Upgrade", "websocket") {
+		return "", http.StatusBadRequest, fmt.Errorf("Request is not a WebSocket upgrade")
+	}
+	if request.Header.Get("Sec-WebSocket-Version") != "13" {
+		return "", http.StatusUpgradeRequired, fmt.Errorf("Unsupported WebSocket version %s",
+			request.Header.Get("Sec-WebSocket-Version"))
+	}
+
+	key := request.Header.Get("Sec-WebSocket-Key")
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 16 {
+		return "", http.StatusBadRequest, fmt.Errorf("Invalid Sec-WebSocket-Key %s", key)
+	}
+	return key, http.StatusSwitchingProtocols, nil
+}
+
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func computeAcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+/*WebSocketConn implements manager.Conn and gateway.Conn over a WebSocket.
+Every binary message carries exactly one framed message so ReadMessage and
+WriteMessage exchange whole messages without a length prefix. Read and Write
+treat the connection as a stream of message payloads*/
+type WebSocketConn struct {
+	conn         net.Conn
+	reader       *bufio.Reader
+	pending      []byte
+	writeMutex   *sync.Mutex
+	encoding     string
+	version      int32
+	capabilities uint64
+	metadata     metadata.Metadata
+	endpointID   string
+	unread       []byte
+	closeCalled  bool
+	closeRecvd   bool
+}
+
+func newWebSocketConn(conn net.Conn, reader *bufio.Reader, encoding string) *WebSocketConn {
+	return &WebSocketConn{
+		conn:       conn,
+		reader:     reader,
+		pending:    nil,
+		writeMutex: &sync.Mutex{},
+		encoding:   encoding,
+	}
+}
+
+//ReadMessage returns the payload of the next binary message
+func (wc *WebSocketConn) ReadMessage() ([]byte, error) {
+	if wc.unread != nil {
+		message := wc.unread
+		wc.unread = nil
+		return message, nil
+	}
+
+	message := make([]byte, 0)
+	fragmented := false
+	for {
+		fin, opcode, payload, err := wc.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case pingFrame:
+			err = wc.writeFrame(pongFrame, payload)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case pongFrame:
+			continue
+		case closeFrame:
+			wc.closeRecvd = true
+			wc.writeFrame(closeFrame, payload)
+			return nil, io.EOF
+		case textFrame:
+			wc.fail(closeUnsupportedData)
+			return nil, fmt.Errorf("Text messages are not supported in WebSocketConn.ReadMessage()")
+		case binaryFrame:
+			if fragmented {
+				wc.fail(closeProtocolError)
+				return nil, fmt.Errorf("New message started before previous finished in WebSocketConn.ReadMessage()")
+			}
+		case continuationFrame:
+			if !fragmented {
+				wc.fail(closeProtocolError)
+				return nil, fmt.Errorf("Unexpected continuation frame in WebSocketConn.ReadMessage()")
+			}
+		default:
+			wc.fail(closeProtocolError)
+			return nil, fmt.Errorf("Unknown opcode %d in WebSocketConn.ReadMessage()", opcode)
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			wc.fail(closeMessageTooBig)
+			return nil, fmt.Errorf("Message exceeds %d bytes in WebSocketConn.ReadMessage()", MaxMessageSize)
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+		fragmented = true
+	}
+}
+
+//WriteMessage sends 'msg' as a single binary message
+func (wc *WebSocketConn) WriteMessage(msg []byte) error {
+	return wc.writeFrame(binaryFrame, msg)
+}
+
+//Unread pushes 'msg' back so that it is returned by the next call to ReadMessage
+func (wc *WebSocketConn) Unread(msg []byte) {
+	wc.unread = msg
+}
+
+//Read reads from the payloads of incoming binary messages
+func (wc *WebSocketConn) Read(b []byte) (int, error) {
+	for len(wc.pending) == 0 {
+		msg, err := wc.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		wc.pending = msg
+	}
+	n := copy(b, wc.pending)
+	wc.pending = wc.pending[n:]
+	return n, nil
+}
+
+//Write sends 'b' as a single binary message
+func (wc *WebSocketConn) Write(b []byte) (int, error) {
+	err := wc.WriteMessage(b)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+//Close sends a close frame and closes the underlying net.Conn
+func (wc *WebSocketConn) Close() error {
+	if wc.closeCalled {
+		return fmt.Errorf("Connection already closed in WebSocketConn.Close()")
+	}
+	wc.closeCalled = true
+	if !wc.closeRecvd {
+		wc.writeFrame(closeFrame, closePayload(closeNormal))
+	}
+	return wc.conn.Close()
+}
+
+//SetDeadline delegates to net.Conn.SetDeadline
+func (wc *WebSocketConn) SetDeadline(t time.Time) error {
+	return wc.conn.SetDeadline(t)
+}
+
+//GetAddress returns net.Conn.RemoteAddr().String()
+func (wc *WebSocketConn) GetAddress() string {
+	return wc.conn.RemoteAddr().String()
+}
+
+//GetIP returns the IP address of the remote end of the connection
+func (wc *WebSocketConn) GetIP() net.IP {
+	return (&GatewayNetConnWrapper{conn: wc.conn}).GetIP()
+}
+
+//GetEncoding returns the wire encoding spoken by the remote end
+func (wc *WebSocketConn) GetEncoding() string {
+	return wc.encoding
+}
+
+//SetProtocol records the protocol version and capabilities negotiated with the remote end
+func (wc *WebSocketConn) SetProtocol(version int32, capabilities uint64) {
+	wc.version = version
+	wc.capabilities = capabilities
+}
+
+//GetProtocolVersion returns the negotiated protocol version
+func (wc *WebSocketConn) GetProtocolVersion() int32 {
+	return wc.version
+}
+
+//GetCapabilities returns the negotiated capabilities
+func (wc *WebSocketConn) GetCapabilities() uint64 {
+	return wc.capabilities
+}
+
+//SetMetadata records the metadata declared by the remote end when logging on
+func (wc *WebSocketConn) SetMetadata(m metadata.Metadata) {
+	wc.metadata = m
+}
+
+//GetMetadata returns the metadata declared by the remote end
+func (wc *WebSocketConn) GetMetadata() metadata.Metadata {
+	return wc.metadata
+}
+
+//SetEndpointID records the identity issued to the remote end when it logged on
+func (wc *WebSocketConn) SetEndpointID(id string) {
+	wc.endpointID = id
+}
+
+//GetEndpointID returns the identity issued to the remote end or "" if it never logged on
+func (wc *WebSocketConn) GetEndpointID() string {
+	return wc.endpointID
+}
+
+//IsClosed tests whether or not the connection was closed
+func (wc *WebSocketConn) IsClosed() bool {
+	if wc.closeCalled || wc.closeRecvd {
+		return true
+	}
+
+	wc.conn.SetReadDeadline(time.Now())
+	_, err := wc.reader.Peek(1)
+	wc.conn.SetReadDeadline(time.Time{})
+	if err == io.EOF {
+		wc.closeRecvd = true
+	}
+	return wc.closeRecvd
+}
+
+func (wc *WebSocketConn) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(wc.reader, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		wc.fail(closeProtocolError)
+		return false, 0, nil, fmt.Errorf("Reserved bits set in WebSocket frame")
+	}
+	if !masked {
+		wc.fail(closeProtocolError)
+		return false, 0, nil, fmt.Errorf("Received unmasked WebSocket frame from client")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended uint16
+		err = binary.Read(wc.reader, binary.BigEndian, &extended)
+		length = uint64(extended)
+	case 127:
+		err = binary.Read(wc.reader, binary.BigEndian, &length)
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if opcode >= closeFrame && (length > 125 || !fin) {
+		wc.fail(closeProtocolError)
+		return false, 0, nil, fmt.Errorf("Invalid WebSocket control frame")
+	}
+	if length > uint64(MaxMessageSize) {
+		wc.fail(closeMessageTooBig)
+		return false, 0, nil, fmt.Errorf("WebSocket frame exceeds %d bytes", MaxMessageSize)
+	}
+
+	mask := make([]byte, 4)
+	_, err = io.ReadFull(wc.reader, mask)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(wc.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+func (wc *WebSocketConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+
+	length := len(payload)
+	if length <= 125 {
+		frame = append(frame, byte(length))
+	} else if length <= 0xFFFF {
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	} else {
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	wc.writeMutex.Lock()
+	defer wc.writeMutex.Unlock()
+	_, err := wc.conn.Write(frame)
+	if err != nil {
+		return fmt.Errorf("Failed to write WebSocket frame: %v", err)
+	}
+	return nil
+}
+
+func (wc *WebSocketConn) fail(code uint16) {
+	wc.writeFrame(closeFrame, closePayload(code))
+}
+
+func closePayload(code uint16) []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, code)
+	return payload
+}
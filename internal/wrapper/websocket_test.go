@@ -0,0 +1,220 @@
+package wrapper
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStalledHandshake(t *testing.T) {
+	fmt.Printf("----------------------\nSTALLED HANDSHAKE TEST\n----------------------\n")
+	certificate, err := selfSignedCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsListener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := NewNetListener(tlsListener, "protobuf")
+	defer listener.Close()
+
+	//A client that never handshakes must not hold up the next one
+	stalled, err := net.Dial("tcp", tlsListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+	time.Sleep(time.Millisecond * 20)
+	go func() {
+		client, err := tls.Dial("tcp", tlsListener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			defer client.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := listener.Accept()
+		accepted <- err
+	}()
+	select {
+	case err = <-accepted:
+		fmt.Printf("Accepted handshaken connection behind a stalled one\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Stalled handshake blocked the listener")
+	}
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func TestWebSocketListener(t *testing.T) {
+	fmt.Printf("----------------------\nWEBSOCKET LISTENER TEST\n----------------------\n")
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := NewWebSocketListener(netListener, "/signal", "json")
+	defer listener.Close()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		clientErr <- runWebSocketClient(netListener.Addr().String())
+	}()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsConn := conn.(*WebSocketConn)
+	fmt.Printf("Accepted upgraded connection from %s\n", wsConn.GetAddress())
+	if wsConn.GetEncoding() != "json" {
+		t.Fatalf("Unexpected encoding %s", wsConn.GetEncoding())
+	}
+
+	msg, err := wsConn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Read fragmented message: %s\n", string(msg))
+	if string(msg) != "hello world" {
+		t.Fatalf("Unexpected message %s", string(msg))
+	}
+
+	var debrief int32
+	err = binary.Read(wsConn, binary.BigEndian, &debrief)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Read raw value: %d\n", debrief)
+	if debrief != 42 {
+		t.Fatalf("Unexpected raw value %d", debrief)
+	}
+
+	err = wsConn.WriteMessage([]byte("response"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = wsConn.ReadMessage()
+	if err != io.EOF {
+		t.Fatalf("Expected EOF after close frame, got %v", err)
+	}
+	if !wsConn.IsClosed() {
+		t.Fatalf("Connection should be closed")
+	}
+	if err = <-clientErr; err != nil {
+		t.Fatal(err)
+	}
+
+	//A client that never sends its upgrade request must not hold up the next one
+	stalled, err := net.Dial("tcp", netListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+	go runWebSocketClient(netListener.Addr().String())
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := listener.Accept()
+		accepted <- err
+	}()
+	select {
+	case err = <-accepted:
+		fmt.Printf("Accepted upgraded connection behind a stalled one\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Stalled upgrade blocked the listener")
+	}
+}
+
+func runWebSocketClient(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET /signal HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", addr, key)
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("Upgrade refused with status %d", response.StatusCode)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		return fmt.Errorf("Wrong accept key %s", response.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	writeClientFrame(conn, false, binaryFrame, []byte("hello "))
+	writeClientFrame(conn, true, pingFrame, []byte("ping"))
+	writeClientFrame(conn, true, continuationFrame, []byte("world"))
+	writeClientFrame(conn, true, binaryFrame, []byte{0, 0, 0, 42})
+
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return err
+	}
+	if header[0]&0x0F != pongFrame {
+		return fmt.Errorf("Expected pong, got opcode %d", header[0]&0x0F)
+	}
+	reader.Discard(int(header[1]))
+
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return err
+	}
+	payload := make([]byte, header[1])
+	if _, err = io.ReadFull(reader, payload); err != nil {
+		return err
+	}
+	if string(payload) != "response" {
+		return fmt.Errorf("Unexpected response %s", string(payload))
+	}
+	return writeClientFrame(conn, true, closeFrame, closePayload(closeNormal))
+}
+
+func writeClientFrame(conn net.Conn, fin bool, opcode byte, payload []byte) error {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := conn.Write(frame)
+	return err
+}
@@ -0,0 +1,219 @@
+package jsonmsg
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/arstevens/go-request/handle"
+)
+
+func NewRouteWrapper(routeCode int32, rawRequest []byte) ([]byte, error) {
+	return NewVersionedRouteWrapper(routeCode, 0, 0, rawRequest)
+}
+
+/*NewVersionedRouteWrapper wraps a request along with the highest protocol
+version and the capabilities supported by the client*/
+func NewVersionedRouteWrapper(routeCode int32, version int32, capabilities uint64, rawRequest []byte) ([]byte, error) {
+	request := RouterWrapper{Type: routeCode, Request: rawRequest, Version: version, Capabilities: capabilities}
+	raw, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create RouteWrapper in NewVersionedRouteWrapper(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackRouteWrapper(raw []byte) (handle.Request, error) {
+	var request RouterWrapper
+	err := json.Unmarshal(raw, &request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackRouteWrapper(): %v", err)
+	}
+	return &request, nil
+}
+
+func NewLocalizeRequest(dataspace string) ([]byte, error) {
+	return NewConstrainedLocalizeRequest(dataspace, nil)
+}
+
+/*NewConstrainedLocalizeRequest creates a LocalizeRequest that is only paired
+with endpoints meeting 'requirements'*/
+func NewConstrainedLocalizeRequest(dataspace string, requirements *EndpointRequirements) ([]byte, error) {
+	return NewFanOutLocalizeRequest(dataspace, requirements, 0)
+}
+
+/*NewFanOutLocalizeRequest creates a LocalizeRequest that is paired with up
+to 'peers' distinct endpoints meeting 'requirements'. 'requirements' may be nil*/
+func NewFanOutLocalizeRequest(dataspace string, requirements *EndpointRequirements, peers int32) ([]byte, error) {
+	request := LocalizeRequest{Dataspace: dataspace, Requirements: requirements, Peers: peers}
+	raw, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create LocalizeRequest in NewFanOutLocalizeRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackLocalizeRequest(raw []byte) (interface{}, error) {
+	var request LocalizeRequest
+	err := json.Unmarshal(raw, &request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackLocalizeRequest(): %v", err)
+	}
+	return &JSONLocalizeRequest{request: &request}, nil
+}
+
+func NewRegistrationRequest(isAdd bool, isOrigin bool, datafield string) ([]byte, error) {
+	request := RegistrationRequest{IsAdd: isAdd, IsOrigin: isOrigin, Datafield: datafield}
+	raw, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create RegistrationRequest in NewRegistrationRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+/*NewOwnedDataspaceRequest creates a RegistrationRequest adding 'dataspace'
+owned by 'originID'. Endpoints of the owner may pin themselves to it*/
+func NewOwnedDataspaceRequest(dataspace string, originID string) ([]byte, error) {
+	request := RegistrationRequest{IsAdd: true, IsOrigin: false, Datafield: dataspace, OriginID: originID}
+	raw, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create RegistrationRequest in NewOwnedDataspaceRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackRegistrationRequest(raw []byte) (interface{}, error) {
+	var request RegistrationRequest
+	err := json.Unmarshal(raw, &request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackRegistrationRequest(): %v", err)
+	}
+	return &JSONRegistrationRequest{request: &request}, nil
+}
+
+func NewConnectionRequest(isLogOn bool, swarmID string, originID string) ([]byte, error) {
+	return NewDescribedConnectionRequest(isLogOn, swarmID, originID, nil)
+}
+
+//NewDescribedConnectionRequest creates a ConnectionRequest declaring the metadata of the endpoint
+func NewDescribedConnectionRequest(isLogOn bool, swarmID string, originID string, metadata *EndpointMetadata) ([]byte, error) {
+	request := ConnectionRequest{IsLogOn: isLogOn, SwarmID: swarmID, OriginID: originID, Metadata: metadata}
+	raw, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create ConnectionRequest in NewDescribedConnectionRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+/*NewPinnedConnectionRequest creates a ConnectionRequest logging an endpoint
+on to the swarm of dataspace 'swarmID'. 'originID' must own the dataspace*/
+func NewPinnedConnectionRequest(swarmID string, originID string, metadata *EndpointMetadata) ([]byte, error) {
+	request := ConnectionRequest{IsLogOn: true, SwarmID: swarmID, OriginID: originID, Metadata: metadata, IsPinned: true}
+	raw, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create ConnectionRequest in NewPinnedConnectionRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+/*NewLogOffRequest creates a ConnectionRequest logging off the endpoint
+that was issued 'endpointID' when it logged on*/
+func NewLogOffRequest(swarmID string, originID string, endpointID string) ([]byte, error) {
+	request := ConnectionRequest{IsLogOn: false, SwarmID: swarmID, OriginID: originID, EndpointID: endpointID}
+	raw, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create ConnectionRequest in NewLogOffRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackConnectionRequest(raw []byte) (interface{}, error) {
+	var request ConnectionRequest
+	err := json.Unmarshal(raw, &request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackConnectionRequest(): %v", err)
+	}
+	return &JSONConnectionRequest{request: &request}, nil
+}
+
+func NewNegotiateMessage(accepted bool, data []byte) ([]byte, error) {
+	msg := NegotiateMessage{IsAccepted: accepted, MessageData: data}
+	raw, err := json.Marshal(&msg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create NegotiateMessage in NewNegotiateMessage(): %v", err)
+	}
+	return raw, nil
+}
+
+/*NewSignalMessage creates a typed WebRTC NegotiateMessage. 'sdp' is set
+for offers and answers and 'candidate' for trickled ICE candidates*/
+func NewSignalMessage(signalType int32, sdp string, candidate *IceCandidate) ([]byte, error) {
+	msg := NegotiateMessage{Type: signalType, Sdp: sdp, Candidate: candidate}
+	raw, err := json.Marshal(&msg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create NegotiateMessage in NewSignalMessage(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnmarshalNegotiateMessage(raw []byte) (interface{}, error) {
+	var msg NegotiateMessage
+	err := json.Unmarshal(raw, &msg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnmarshalNegotiateMessage(): %v", err)
+	}
+	return &JSONNegotiateMessage{msg: &msg}, nil
+}
+
+func NewResponse(status int32, category int32, reason string, version int32, capabilities uint64) ([]byte, error) {
+	response := Response{Status: status, Category: category, Reason: reason,
+		Version: version, Capabilities: capabilities}
+	return MarshalResponse(&response)
+}
+
+//MarshalResponse encodes a Response with every field already set
+func MarshalResponse(response *Response) ([]byte, error) {
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Response in MarshalResponse(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackResponse(raw []byte) (interface{}, error) {
+	var response Response
+	err := json.Unmarshal(raw, &response)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackResponse(): %v", err)
+	}
+	return &JSONResponse{response: &response}, nil
+}
+
+func NewNotice(noticeType int32, reason string) ([]byte, error) {
+	return MarshalNotice(&Notice{Type: noticeType, Reason: reason})
+}
+
+//latencyReportType matches NOTICE_LATENCY_REPORT in protomsg/messages.proto
+const latencyReportType int32 = 3
+
+//NewLatencyReport creates a Notice reporting the round trip time in microseconds to 'peer'
+func NewLatencyReport(peer string, rtt int64) ([]byte, error) {
+	return MarshalNotice(&Notice{Type: latencyReportType, Peer: peer, Rtt: rtt})
+}
+
+//MarshalNotice encodes a Notice with every field already set
+func MarshalNotice(notice *Notice) ([]byte, error) {
+	raw, err := json.Marshal(notice)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Notice in MarshalNotice(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackNotice(raw []byte) (interface{}, error) {
+	var notice Notice
+	err := json.Unmarshal(raw, &notice)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackNotice(): %v", err)
+	}
+	return &JSONNotice{notice: &notice}, nil
+}
@@ -0,0 +1,248 @@
+package jsonmsg
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestJsonmsg(t *testing.T) {
+	fmt.Printf("Testing RouterWrapper\n")
+	fmt.Printf("\tcreating new wrapped request...")
+	lRequest, err := NewLocalizeRequest("/dataspace/TEST")
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	wrapped, err := NewVersionedRouteWrapper(0, 2, 1, lRequest)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	fmt.Printf("success: %s\n", string(wrapped))
+
+	fmt.Printf("\tunwrapping request...")
+	request, err := UnpackRouteWrapper(wrapped)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if request.(*RouterWrapper).GetVersion() != 2 || request.(*RouterWrapper).GetCapabilities() != 1 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected protocol in wrapper %s", string(wrapped))
+	}
+	iLocalize, err := UnpackLocalizeRequest(request.GetRequest())
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if iLocalize.(*JSONLocalizeRequest).GetDataspace() != "/dataspace/TEST" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected dataspace %s", iLocalize.(*JSONLocalizeRequest).GetDataspace())
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing RegistrationRequest\n")
+	fmt.Printf("\tcreating and unmarshaling request...")
+	rRequest, err := NewRegistrationRequest(true, true, "DATA")
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iRegistration, err := UnpackRegistrationRequest(rRequest)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	registration := iRegistration.(*JSONRegistrationRequest)
+	if !registration.IsAdd() || !registration.IsOrigin() || registration.GetDataField() != "DATA" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected registration request %s", string(rRequest))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing ConnectionRequest\n")
+	fmt.Printf("\tunmarshaling handwritten request...")
+	iConnection, err := UnpackConnectionRequest([]byte(`{"isLogOn":true,"swarmID":"/swarm/TEST","originID":"/origin/TEST"}`))
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	connection := iConnection.(*JSONConnectionRequest)
+	if !connection.IsLogOn() || connection.GetSwarmID() != "/swarm/TEST" || connection.GetOriginID() != "/origin/TEST" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected connection request")
+	}
+	if connection.GetMetadata() != nil || connection.IsPinned() {
+		fmt.Printf("failed\n")
+		t.Fatalf("Request without metadata should have none and not be pinned")
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling pinned request...")
+	cRequest, err := NewPinnedConnectionRequest("/dataspace/TEST", "/origin/TEST", nil)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iConnection, err = UnpackConnectionRequest(cRequest)
+	if err != nil || !iConnection.(*JSONConnectionRequest).IsPinned() {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected pinned request %s", string(cRequest))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling log off request...")
+	cRequest, err = NewLogOffRequest("", "/origin/TEST", "0a1b")
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iConnection, err = UnpackConnectionRequest(cRequest)
+	if err != nil || iConnection.(*JSONConnectionRequest).IsLogOn() ||
+		iConnection.(*JSONConnectionRequest).GetEndpointID() != "0a1b" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected log off request %s", string(cRequest))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tunmarshaling request with metadata...")
+	iConnection, err = UnpackConnectionRequest([]byte(`{"isLogOn":true,"swarmID":"/swarm/TEST","originID":"/origin/TEST",` +
+		`"metadata":{"transports":["webrtc"],"storageSize":1024,"region":"eu-west"}}`))
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	metadata := iConnection.(*JSONConnectionRequest).GetMetadata().(*EndpointMetadata)
+	if metadata.GetTransports()[0] != "webrtc" || metadata.GetStorageSize() != 1024 || metadata.GetRegion() != "eu-west" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected endpoint metadata %v", metadata)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing NegotiateMessage\n")
+	fmt.Printf("\tcreating and unmarshaling message...")
+	nMessage, err := NewNegotiateMessage(true, []byte("offer"))
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iNegotiate, err := UnmarshalNegotiateMessage(nMessage)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	negotiate := iNegotiate.(*JSONNegotiateMessage)
+	if !negotiate.IsAccepted() || !bytes.Equal(negotiate.GetMessageData(), []byte("offer")) {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected negotiate message %s", string(nMessage))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling candidate...")
+	nMessage, err = NewSignalMessage(3, "", &IceCandidate{Candidate: "candidate:1 1 udp 2122260223 10.0.0.2 54400 typ host", SdpMid: "0"})
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iNegotiate, err = UnmarshalNegotiateMessage(nMessage)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	negotiate = iNegotiate.(*JSONNegotiateMessage)
+	candidate, ok := negotiate.GetCandidate().(*IceCandidate)
+	if negotiate.GetSignalType() != 3 || negotiate.IsAccepted() || !ok || candidate.GetSdpMid() != "0" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected signal message %s", string(nMessage))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing Response\n")
+	fmt.Printf("\tcreating and unmarshaling response...")
+	response, err := NewResponse(1, 2, "No such dataspace", 2, 0)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iResponse, err := UnpackResponse(response)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	unpacked := iResponse.(*JSONResponse)
+	if unpacked.GetStatus() != 1 || unpacked.GetCategory() != 2 || unpacked.GetReason() != "No such dataspace" ||
+		unpacked.GetVersion() != 2 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected response %s", string(response))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tunmarshaling handwritten busy response...")
+	iResponse, err = UnpackResponse([]byte(`{"status":2,"category":11,"retryAfter":250}`))
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if iResponse.(*JSONResponse).GetRetryAfter() != 250 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected retry after %d", iResponse.(*JSONResponse).GetRetryAfter())
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tmarshaling log on response...")
+	loggedOn, err := MarshalResponse(&Response{EndpointID: "0a1b"})
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iResponse, err = UnpackResponse(loggedOn)
+	if err != nil || iResponse.(*JSONResponse).GetEndpointID() != "0a1b" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected log on response %s", string(loggedOn))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing Notice\n")
+	fmt.Printf("\tcreating and unmarshaling notice...")
+	notice, err := NewNotice(0, "Server shutting down")
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iNotice, err := UnpackNotice(notice)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if iNotice.(*JSONNotice).GetType() != 0 || iNotice.(*JSONNotice).GetReason() != "Server shutting down" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected notice %s", string(notice))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling latency report...")
+	report, err := NewLatencyReport("203.0.113.7", 42000)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iNotice, err = UnpackNotice(report)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if iNotice.(*JSONNotice).GetPeer() != "203.0.113.7" || iNotice.(*JSONNotice).GetRtt() != 42000 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected latency report %s", string(report))
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\twrapping malformed request...")
+	_, err = NewRouteWrapper(0, []byte("not json"))
+	if err == nil {
+		fmt.Printf("failed\n")
+		t.Fatalf("Expected error wrapping malformed request")
+	}
+	fmt.Printf("success\n")
+}
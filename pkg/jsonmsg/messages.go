@@ -0,0 +1,130 @@
+package jsonmsg
+
+import "encoding/json"
+
+/*The types below mirror the messages defined in protomsg/messages.proto.
+Field names match the protobuf JSON mapping so clients can share schemas*/
+
+type RouterWrapper struct {
+	Type         int32           `json:"type"`
+	Request      json.RawMessage `json:"request"`
+	Version      int32           `json:"version,omitempty"`
+	Capabilities uint64          `json:"capabilities,omitempty"`
+}
+
+func (rw *RouterWrapper) GetType() int32 {
+	return rw.Type
+}
+
+func (rw *RouterWrapper) GetRequest() []byte {
+	return rw.Request
+}
+
+func (rw *RouterWrapper) GetVersion() int32 {
+	return rw.Version
+}
+
+func (rw *RouterWrapper) GetCapabilities() uint64 {
+	return rw.Capabilities
+}
+
+type LocalizeRequest struct {
+	Dataspace    string                `json:"dataspace"`
+	Requirements *EndpointRequirements `json:"requirements,omitempty"`
+	//Number of endpoints to pair the requester with
+	Peers int32 `json:"peers,omitempty"`
+}
+
+type RegistrationRequest struct {
+	IsAdd     bool   `json:"isAdd"`
+	IsOrigin  bool   `json:"isOrigin"`
+	Datafield string `json:"datafield"`
+	OriginID  string `json:"originID,omitempty"`
+}
+
+type ConnectionRequest struct {
+	IsLogOn  bool              `json:"isLogOn"`
+	SwarmID  string            `json:"swarmID"`
+	OriginID string            `json:"originID"`
+	Metadata *EndpointMetadata `json:"metadata,omitempty"`
+	IsPinned bool              `json:"isPinned,omitempty"`
+	//Identity issued to the endpoint when it logged on
+	EndpointID string `json:"endpointID,omitempty"`
+}
+
+type EndpointMetadata struct {
+	Transports []string `json:"transports,omitempty"`
+	//Kilobits per second
+	MaxUploadBandwidth uint64 `json:"maxUploadBandwidth,omitempty"`
+	//Bytes
+	StorageSize   uint64 `json:"storageSize,omitempty"`
+	ClientVersion string `json:"clientVersion,omitempty"`
+	Region        string `json:"region,omitempty"`
+}
+
+func (em *EndpointMetadata) GetTransports() []string       { return em.Transports }
+func (em *EndpointMetadata) GetMaxUploadBandwidth() uint64 { return em.MaxUploadBandwidth }
+func (em *EndpointMetadata) GetStorageSize() uint64        { return em.StorageSize }
+func (em *EndpointMetadata) GetClientVersion() string      { return em.ClientVersion }
+func (em *EndpointMetadata) GetRegion() string             { return em.Region }
+
+type EndpointRequirements struct {
+	Transports         []string `json:"transports,omitempty"`
+	MinUploadBandwidth uint64   `json:"minUploadBandwidth,omitempty"`
+	MinStorageSize     uint64   `json:"minStorageSize,omitempty"`
+	MinClientVersion   string   `json:"minClientVersion,omitempty"`
+	Regions            []string `json:"regions,omitempty"`
+}
+
+func (er *EndpointRequirements) GetTransports() []string       { return er.Transports }
+func (er *EndpointRequirements) GetMinUploadBandwidth() uint64 { return er.MinUploadBandwidth }
+func (er *EndpointRequirements) GetMinStorageSize() uint64     { return er.MinStorageSize }
+func (er *EndpointRequirements) GetMinClientVersion() string   { return er.MinClientVersion }
+func (er *EndpointRequirements) GetRegions() []string          { return er.Regions }
+
+//NegotiateMessage carries messageData as a base64 string
+type NegotiateMessage struct {
+	IsAccepted  bool   `json:"isAccepted"`
+	MessageData []byte `json:"messageData"`
+	//SignalType of a WebRTC signaling message. Zero keeps the message opaque
+	Type      int32         `json:"type,omitempty"`
+	Sdp       string        `json:"sdp,omitempty"`
+	Candidate *IceCandidate `json:"candidate,omitempty"`
+}
+
+type IceCandidate struct {
+	Candidate        string `json:"candidate"`
+	SdpMid           string `json:"sdpMid,omitempty"`
+	SdpMLineIndex    uint32 `json:"sdpMLineIndex,omitempty"`
+	UsernameFragment string `json:"usernameFragment,omitempty"`
+}
+
+func (ic *IceCandidate) GetCandidate() string        { return ic.Candidate }
+func (ic *IceCandidate) GetSdpMid() string           { return ic.SdpMid }
+func (ic *IceCandidate) GetSdpMLineIndex() uint32    { return ic.SdpMLineIndex }
+func (ic *IceCandidate) GetUsernameFragment() string { return ic.UsernameFragment }
+
+type Response struct {
+	Status       int32  `json:"status"`
+	Category     int32  `json:"category"`
+	Reason       string `json:"reason"`
+	Version      int32  `json:"version"`
+	Capabilities uint64 `json:"capabilities"`
+	//Milliseconds a busy client should wait before retrying
+	RetryAfter int64 `json:"retryAfter,omitempty"`
+	//Number of endpoints a requester was paired with
+	Peers int32 `json:"peers,omitempty"`
+	//Identity issued to an endpoint that logged on
+	EndpointID string `json:"endpointID,omitempty"`
+}
+
+type Notice struct {
+	Type   int32  `json:"type"`
+	Reason string `json:"reason"`
+	//IP address of the peer a latency report was measured to
+	Peer string `json:"peer,omitempty"`
+	//Round trip time to the peer in microseconds
+	Rtt int64 `json:"rtt,omitempty"`
+	//Every dataspace served by the swarm a migrating endpoint is moved to
+	Dataspaces []string `json:"dataspaces,omitempty"`
+}
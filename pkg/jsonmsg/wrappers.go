@@ -0,0 +1,160 @@
+package jsonmsg
+
+type JSONLocalizeRequest struct {
+	request *LocalizeRequest
+}
+
+func (lr *JSONLocalizeRequest) GetDataspace() string {
+	return lr.request.Dataspace
+}
+
+//GetRequirements returns the EndpointRequirements of the request or nil if it has none
+func (lr *JSONLocalizeRequest) GetRequirements() interface{} {
+	if lr.request.Requirements == nil {
+		return nil
+	}
+	return lr.request.Requirements
+}
+
+//GetPeers returns the number of endpoints the requester wants to be paired with
+func (lr *JSONLocalizeRequest) GetPeers() int32 {
+	return lr.request.Peers
+}
+
+type JSONRegistrationRequest struct {
+	request *RegistrationRequest
+}
+
+func (rr *JSONRegistrationRequest) IsAdd() bool {
+	return rr.request.IsAdd
+}
+
+func (rr *JSONRegistrationRequest) IsOrigin() bool {
+	return rr.request.IsOrigin
+}
+
+func (rr *JSONRegistrationRequest) GetDataField() string {
+	return rr.request.Datafield
+}
+
+func (rr *JSONRegistrationRequest) GetOriginID() string {
+	return rr.request.OriginID
+}
+
+type JSONConnectionRequest struct {
+	request *ConnectionRequest
+}
+
+func (cr *JSONConnectionRequest) IsLogOn() bool {
+	return cr.request.IsLogOn
+}
+
+func (cr *JSONConnectionRequest) GetSwarmID() string {
+	return cr.request.SwarmID
+}
+
+func (cr *JSONConnectionRequest) GetOriginID() string {
+	return cr.request.OriginID
+}
+
+//GetEndpointID returns the identity a logging off endpoint was issued when it logged on
+func (cr *JSONConnectionRequest) GetEndpointID() string {
+	return cr.request.EndpointID
+}
+
+func (cr *JSONConnectionRequest) IsPinned() bool {
+	return cr.request.IsPinned
+}
+
+//GetMetadata returns the EndpointMetadata of the request or nil if it has none
+func (cr *JSONConnectionRequest) GetMetadata() interface{} {
+	if cr.request.Metadata == nil {
+		return nil
+	}
+	return cr.request.Metadata
+}
+
+type JSONNegotiateMessage struct {
+	msg *NegotiateMessage
+}
+
+func (nm *JSONNegotiateMessage) IsAccepted() bool {
+	return nm.msg.IsAccepted
+}
+
+func (nm *JSONNegotiateMessage) GetMessageData() []byte {
+	return nm.msg.MessageData
+}
+
+func (nm *JSONNegotiateMessage) GetSignalType() int32 {
+	return nm.msg.Type
+}
+
+func (nm *JSONNegotiateMessage) GetSdp() string {
+	return nm.msg.Sdp
+}
+
+//GetCandidate returns the IceCandidate of the message or nil if it has none
+func (nm *JSONNegotiateMessage) GetCandidate() interface{} {
+	if nm.msg.Candidate == nil {
+		return nil
+	}
+	return nm.msg.Candidate
+}
+
+type JSONResponse struct {
+	response *Response
+}
+
+func (r *JSONResponse) GetStatus() int32 {
+	return r.response.Status
+}
+
+func (r *JSONResponse) GetCategory() int32 {
+	return r.response.Category
+}
+
+func (r *JSONResponse) GetReason() string {
+	return r.response.Reason
+}
+
+func (r *JSONResponse) GetVersion() int32 {
+	return r.response.Version
+}
+
+func (r *JSONResponse) GetCapabilities() uint64 {
+	return r.response.Capabilities
+}
+
+func (r *JSONResponse) GetRetryAfter() int64 {
+	return r.response.RetryAfter
+}
+
+//GetEndpointID returns the identity issued to an endpoint that logged on
+func (r *JSONResponse) GetEndpointID() string {
+	return r.response.EndpointID
+}
+
+type JSONNotice struct {
+	notice *Notice
+}
+
+func (n *JSONNotice) GetType() int32 {
+	return n.notice.Type
+}
+
+func (n *JSONNotice) GetReason() string {
+	return n.notice.Reason
+}
+
+func (n *JSONNotice) GetPeer() string {
+	return n.notice.Peer
+}
+
+func (n *JSONNotice) GetRtt() int64 {
+	return n.notice.Rtt
+}
+
+func (n *JSONNotice) GetDataspaces() []string {
+	return n.notice.Dataspaces
+}
@@ -8,10 +8,16 @@ import (
 )
 
 func NewRouteWrapper(routeCode int32, rawRequest []byte) ([]byte, error) {
-	request := RouterWrapper{Type: routeCode, Request: rawRequest}
+	return NewVersionedRouteWrapper(routeCode, 0, 0, rawRequest)
+}
+
+/*NewVersionedRouteWrapper wraps a request along with the highest protocol
+version and the capabilities supported by the client*/
+func NewVersionedRouteWrapper(routeCode int32, version int32, capabilities uint64, rawRequest []byte) ([]byte, error) {
+	request := RouterWrapper{Type: routeCode, Request: rawRequest, Version: version, Capabilities: capabilities}
 	raw, err := proto.Marshal(&request)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create RouteWrapper in NewRouteWrapper(): %v", err)
+		return nil, fmt.Errorf("Failed to create RouteWrapper in NewVersionedRouteWrapper(): %v", err)
 	}
 	return raw, nil
 }
@@ -26,10 +32,22 @@ func UnpackRouteWrapper(raw []byte) (handle.Request, error) {
 }
 
 func NewLocalizeRequest(dataspace string) ([]byte, error) {
-	request := LocalizeRequest{Dataspace: dataspace}
+	return NewConstrainedLocalizeRequest(dataspace, nil)
+}
+
+/*NewConstrainedLocalizeRequest creates a LocalizeRequest that is only paired
+with endpoints meeting 'requirements'*/
+func NewConstrainedLocalizeRequest(dataspace string, requirements *EndpointRequirements) ([]byte, error) {
+	return NewFanOutLocalizeRequest(dataspace, requirements, 0)
+}
+
+/*NewFanOutLocalizeRequest creates a LocalizeRequest that is paired with up
+to 'peers' distinct endpoints meeting 'requirements'. 'requirements' may be nil*/
+func NewFanOutLocalizeRequest(dataspace string, requirements *EndpointRequirements, peers int32) ([]byte, error) {
+	request := LocalizeRequest{Dataspace: dataspace, Requirements: requirements, Peers: peers}
 	raw, err := proto.Marshal(&request)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create LocalizeRequest in NewLocalizeRequest(): %v", err)
+		return nil, fmt.Errorf("Failed to create LocalizeRequest in NewFanOutLocalizeRequest(): %v", err)
 	}
 	return raw, nil
 }
@@ -52,6 +70,17 @@ func NewRegistrationRequest(isAdd bool, isOrigin bool, datafield string) ([]byte
 	return raw, nil
 }
 
+/*NewOwnedDataspaceRequest creates a RegistrationRequest adding 'dataspace'
+owned by 'originID'. Endpoints of the owner may pin themselves to it*/
+func NewOwnedDataspaceRequest(dataspace string, originID string) ([]byte, error) {
+	request := RegistrationRequest{IsAdd: true, IsOrigin: false, Datafield: dataspace, OriginID: originID}
+	raw, err := proto.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create RegistrationRequest in NewOwnedDataspaceRequest(): %v", err)
+	}
+	return raw, nil
+}
+
 func UnpackRegistrationRequest(raw []byte) (interface{}, error) {
 	var request RegistrationRequest
 	err := proto.Unmarshal(raw, &request)
@@ -62,10 +91,37 @@ func UnpackRegistrationRequest(raw []byte) (interface{}, error) {
 }
 
 func NewConnectionRequest(isLogOn bool, swarmID string, originID string) ([]byte, error) {
-	request := ConnectionRequest{IsLogOn: isLogOn, SwarmID: swarmID, OriginID: originID}
+	return NewDescribedConnectionRequest(isLogOn, swarmID, originID, nil)
+}
+
+//NewDescribedConnectionRequest creates a ConnectionRequest declaring the metadata of the endpoint
+func NewDescribedConnectionRequest(isLogOn bool, swarmID string, originID string, metadata *EndpointMetadata) ([]byte, error) {
+	request := ConnectionRequest{IsLogOn: isLogOn, SwarmID: swarmID, OriginID: originID, Metadata: metadata}
 	raw, err := proto.Marshal(&request)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create ConnectionRequest in NewConnectionRequest(): %v", err)
+		return nil, fmt.Errorf("Failed to create ConnectionRequest in NewDescribedConnectionRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+/*NewPinnedConnectionRequest creates a ConnectionRequest logging an endpoint
+on to the swarm of dataspace 'swarmID'. 'originID' must own the dataspace*/
+func NewPinnedConnectionRequest(swarmID string, originID string, metadata *EndpointMetadata) ([]byte, error) {
+	request := ConnectionRequest{IsLogOn: true, SwarmID: swarmID, OriginID: originID, Metadata: metadata, IsPinned: true}
+	raw, err := proto.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create ConnectionRequest in NewPinnedConnectionRequest(): %v", err)
+	}
+	return raw, nil
+}
+
+/*NewLogOffRequest creates a ConnectionRequest logging off the endpoint
+that was issued 'endpointID' when it logged on*/
+func NewLogOffRequest(swarmID string, originID string, endpointID string) ([]byte, error) {
+	request := ConnectionRequest{IsLogOn: false, SwarmID: swarmID, OriginID: originID, EndpointID: endpointID}
+	raw, err := proto.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create ConnectionRequest in NewLogOffRequest(): %v", err)
 	}
 	return raw, nil
 }
@@ -88,6 +144,17 @@ func NewNegotiateMessage(accepted bool, data []byte) ([]byte, error) {
 	return raw, nil
 }
 
+/*NewSignalMessage creates a typed WebRTC NegotiateMessage. 'sdp' is set
+for offers and answers and 'candidate' for trickled ICE candidates*/
+func NewSignalMessage(signalType int32, sdp string, candidate *IceCandidate) ([]byte, error) {
+	msg := NegotiateMessage{Type: SignalType(signalType), Sdp: sdp, Candidate: candidate}
+	raw, err := proto.Marshal(&msg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create NegotiateMessage in NewSignalMessage(): %v", err)
+	}
+	return raw, nil
+}
+
 func UnmarshalNegotiateMessage(raw []byte) (interface{}, error) {
 	var msg NegotiateMessage
 	err := proto.Unmarshal(raw, &msg)
@@ -96,3 +163,54 @@ func UnmarshalNegotiateMessage(raw []byte) (interface{}, error) {
 	}
 	return &PBNegotiateMessage{msg: &msg}, nil
 }
+
+func NewResponse(status int32, category int32, reason string, version int32, capabilities uint64) ([]byte, error) {
+	response := Response{Status: ResponseStatus(status), Category: ErrorCategory(category), Reason: reason,
+		Version: version, Capabilities: capabilities}
+	return MarshalResponse(&response)
+}
+
+//MarshalResponse encodes a Response with every field already set
+func MarshalResponse(response *Response) ([]byte, error) {
+	raw, err := proto.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Response in MarshalResponse(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackResponse(raw []byte) (interface{}, error) {
+	var response Response
+	err := proto.Unmarshal(raw, &response)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackResponse(): %v", err)
+	}
+	return &PBResponse{response: &response}, nil
+}
+
+func NewNotice(noticeType int32, reason string) ([]byte, error) {
+	return MarshalNotice(&Notice{Type: NoticeType(noticeType), Reason: reason})
+}
+
+//NewLatencyReport creates a Notice reporting the round trip time in microseconds to 'peer'
+func NewLatencyReport(peer string, rtt int64) ([]byte, error) {
+	return MarshalNotice(&Notice{Type: NoticeType_NOTICE_LATENCY_REPORT, Peer: peer, Rtt: rtt})
+}
+
+//MarshalNotice encodes a Notice with every field already set
+func MarshalNotice(notice *Notice) ([]byte, error) {
+	raw, err := proto.Marshal(notice)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Notice in MarshalNotice(): %v", err)
+	}
+	return raw, nil
+}
+
+func UnpackNotice(raw []byte) (interface{}, error) {
+	var notice Notice
+	err := proto.Unmarshal(raw, &notice)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal in UnpackNotice(): %v", err)
+	}
+	return &PBNotice{notice: &notice}, nil
+}
@@ -56,6 +56,211 @@ func TestProtomsg(t *testing.T) {
 		panic(err)
 	}
 	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling pinned request...")
+	cRequest, err = NewPinnedConnectionRequest("/dataspace/TEST", "/origin/TEST", nil)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iConnection, err := UnpackConnectionRequest(cRequest)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if pinned := iConnection.(*PBConnectionRequest); !pinned.IsPinned() || !pinned.IsLogOn() {
+		fmt.Printf("failed\n")
+		t.Fatalf("Expected a pinned log on request")
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling log off request...")
+	cRequest, err = NewLogOffRequest("", "/origin/TEST", "0a1b")
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iConnection, err = UnpackConnectionRequest(cRequest)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if logOff := iConnection.(*PBConnectionRequest); logOff.IsLogOn() || logOff.GetEndpointID() != "0a1b" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected log off request %v", logOff.request)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling owned dataspace request...")
+	rRequest, err = NewOwnedDataspaceRequest("/dataspace/TEST", "/origin/TEST")
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iRegistration, err := UnpackRegistrationRequest(rRequest)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if owned := iRegistration.(*PBRegistrationRequest); owned.GetOriginID() != "/origin/TEST" || owned.IsOrigin() {
+		fmt.Printf("failed\n")
+		t.Fatalf("Expected a dataspace owned by /origin/TEST")
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling constrained localize request...")
+	lRequest, err = NewFanOutLocalizeRequest("/dataspace/TEST",
+		&EndpointRequirements{Transports: []string{"webrtc"}, MinUploadBandwidth: 512}, 3)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iLocalize, err := UnpackLocalizeRequest(lRequest)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	requirements := iLocalize.(*PBLocalizeRequest).GetRequirements().(*EndpointRequirements)
+	if requirements.GetTransports()[0] != "webrtc" || requirements.GetMinUploadBandwidth() != 512 ||
+		iLocalize.(*PBLocalizeRequest).GetPeers() != 3 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected endpoint requirements %v", requirements)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing NegotiateMessage\n")
+	fmt.Printf("\tcreating and unmarshaling answer...")
+	nMessage, err := NewSignalMessage(int32(SignalType_SIGNAL_ANSWER), "v=0", nil)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iNegotiate, err := UnmarshalNegotiateMessage(nMessage)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	negotiate := iNegotiate.(*PBNegotiateMessage)
+	if negotiate.GetSignalType() != int32(SignalType_SIGNAL_ANSWER) || negotiate.GetSdp() != "v=0" ||
+		negotiate.GetCandidate() != nil {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected signal message %v", negotiate.msg)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing Response\n")
+	fmt.Printf("\tcreating new response...")
+	response, err := NewResponse(int32(ResponseStatus_STATUS_ERROR),
+		int32(ErrorCategory_CATEGORY_UNKNOWN_DATASPACE), "No such dataspace", 2, 5)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tunmarshaling response...")
+	iResponse, err := UnpackResponse(response)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	unpacked := iResponse.(*PBResponse)
+	if unpacked.GetCategory() != int32(ErrorCategory_CATEGORY_UNKNOWN_DATASPACE) {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected category %d", unpacked.GetCategory())
+	}
+	if unpacked.GetVersion() != 2 || unpacked.GetCapabilities() != 5 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected protocol %d/%d", unpacked.GetVersion(), unpacked.GetCapabilities())
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tmarshaling busy response...")
+	busy, err := MarshalResponse(&Response{Status: ResponseStatus_STATUS_BUSY,
+		Category: ErrorCategory_CATEGORY_OVERLOADED, RetryAfter: 250})
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iResponse, err = UnpackResponse(busy)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if iResponse.(*PBResponse).GetRetryAfter() != 250 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected retry after %d", iResponse.(*PBResponse).GetRetryAfter())
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tmarshaling log on response...")
+	loggedOn, err := MarshalResponse(&Response{Status: ResponseStatus_STATUS_OK, EndpointID: "0a1b"})
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iResponse, err = UnpackResponse(loggedOn)
+	if err != nil || iResponse.(*PBResponse).GetEndpointID() != "0a1b" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected log on response %v", iResponse)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing Notice\n")
+	fmt.Printf("\tcreating and unmarshaling notice...")
+	notice, err := NewNotice(int32(NoticeType_NOTICE_CLOSING), "Server shutting down")
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iNotice, err := UnpackNotice(notice)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if iNotice.(*PBNotice).GetType() != int32(NoticeType_NOTICE_CLOSING) ||
+		iNotice.(*PBNotice).GetReason() != "Server shutting down" {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected notice %v", iNotice)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("\tcreating and unmarshaling latency report...")
+	report, err := NewLatencyReport("203.0.113.7", 42000)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	iNotice, err = UnpackNotice(report)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	if iNotice.(*PBNotice).GetType() != int32(NoticeType_NOTICE_LATENCY_REPORT) ||
+		iNotice.(*PBNotice).GetPeer() != "203.0.113.7" || iNotice.(*PBNotice).GetRtt() != 42000 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected latency report %v", iNotice)
+	}
+	fmt.Printf("success\n")
+
+	fmt.Printf("Testing RouterWrapper\n")
+	fmt.Printf("\tcreating and unmarshaling versioned wrapper...")
+	wrapped, err := NewVersionedRouteWrapper(1, 2, 3, rRequest)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	request, err := UnpackRouteWrapper(wrapped)
+	if err != nil {
+		fmt.Printf("failed\n")
+		panic(err)
+	}
+	wrapper := request.(*RouterWrapper)
+	if wrapper.GetType() != 1 || wrapper.GetVersion() != 2 || wrapper.GetCapabilities() != 3 {
+		fmt.Printf("failed\n")
+		t.Fatalf("Unexpected wrapper %v", wrapper)
+	}
+	fmt.Printf("success\n")
 }
 
 type FakeConn struct{}
@@ -12,6 +12,19 @@ func (lr *PBLocalizeRequest) GetDataspace() string {
 	return lr.request.GetDataspace()
 }
 
+//GetRequirements returns the EndpointRequirements of the request or nil if it has none
+func (lr *PBLocalizeRequest) GetRequirements() interface{} {
+	if lr.request.GetRequirements() == nil {
+		return nil
+	}
+	return lr.request.GetRequirements()
+}
+
+//GetPeers returns the number of endpoints the requester wants to be paired with
+func (lr *PBLocalizeRequest) GetPeers() int32 {
+	return lr.request.GetPeers()
+}
+
 type PBRegistrationRequest struct {
 	request *RegistrationRequest
 }
@@ -28,6 +41,10 @@ func (rr *PBRegistrationRequest) GetDataField() string {
 	return rr.request.GetDatafield()
 }
 
+func (rr *PBRegistrationRequest) GetOriginID() string {
+	return rr.request.GetOriginID()
+}
+
 type PBConnectionRequest struct {
 	request *ConnectionRequest
 }
@@ -44,6 +61,23 @@ func (cr *PBConnectionRequest) GetOriginID() string {
 	return cr.request.GetOriginID()
 }
 
+//GetEndpointID returns the identity a logging off endpoint was issued when it logged on
+func (cr *PBConnectionRequest) GetEndpointID() string {
+	return cr.request.GetEndpointID()
+}
+
+func (cr *PBConnectionRequest) IsPinned() bool {
+	return cr.request.GetIsPinned()
+}
+
+//GetMetadata returns the EndpointMetadata of the request or nil if it has none
+func (cr *PBConnectionRequest) GetMetadata() interface{} {
+	if cr.request.GetMetadata() == nil {
+		return nil
+	}
+	return cr.request.GetMetadata()
+}
+
 type PBNegotiateMessage struct {
 	msg *NegotiateMessage
 }
@@ -51,3 +85,80 @@ type PBNegotiateMessage struct {
 func (nm *PBNegotiateMessage) IsAccepted() bool {
 	return nm.msg.GetIsAccepted()
 }
+
+func (nm *PBNegotiateMessage) GetMessageData() []byte {
+	return nm.msg.GetMessageData()
+}
+
+func (nm *PBNegotiateMessage) GetSignalType() int32 {
+	return int32(nm.msg.GetType())
+}
+
+func (nm *PBNegotiateMessage) GetSdp() string {
+	return nm.msg.GetSdp()
+}
+
+//GetCandidate returns the IceCandidate of the message or nil if it has none
+func (nm *PBNegotiateMessage) GetCandidate() interface{} {
+	if nm.msg.GetCandidate() == nil {
+		return nil
+	}
+	return nm.msg.GetCandidate()
+}
+
+type PBResponse struct {
+	response *Response
+}
+
+func (r *PBResponse) GetStatus() int32 {
+	return int32(r.response.GetStatus())
+}
+
+func (r *PBResponse) GetCategory() int32 {
+	return int32(r.response.GetCategory())
+}
+
+func (r *PBResponse) GetReason() string {
+	return r.response.GetReason()
+}
+
+func (r *PBResponse) GetVersion() int32 {
+	return r.response.GetVersion()
+}
+
+func (r *PBResponse) GetCapabilities() uint64 {
+	return r.response.GetCapabilities()
+}
+
+func (r *PBResponse) GetRetryAfter() int64 {
+	return r.response.GetRetryAfter()
+}
+
+//GetEndpointID returns the identity issued to an endpoint that logged on
+func (r *PBResponse) GetEndpointID() string {
+	return r.response.GetEndpointID()
+}
+
+type PBNotice struct {
+	notice *Notice
+}
+
+func (n *PBNotice) GetType() int32 {
+	return int32(n.notice.GetType())
+}
+
+func (n *PBNotice) GetReason() string {
+	return n.notice.GetReason()
+}
+
+func (n *PBNotice) GetPeer() string {
+	return n.notice.GetPeer()
+}
+
+func (n *PBNotice) GetRtt() int64 {
+	return n.notice.GetRtt()
+}
+
+func (n *PBNotice) GetDataspaces() []string {
+	return n.notice.GetDataspaces()
+}